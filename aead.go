@@ -3,7 +3,14 @@ package simplecipher
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
 	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
 )
 
 // This file implements AES cipher modes providing authenticated encryption with
@@ -17,15 +24,140 @@ import (
 //  - https://en.wikipedia.org/wiki/Block_cipher_mode_of_operation#Authenticated_encryption_with_additional_data_(AEAD)_modes
 //  - https://pkg.go.dev/crypto/cipher@go1.23.1#AEAD
 
+// gcmMaxPlaintextSize is the maximum plaintext size of GCM
+// defined by NIST SP 800-38D: 2^39 - 256 bits.
+const gcmMaxPlaintextSize = ((1 << 32) - 2) * aes.BlockSize
+
+// MaxPlaintextSize is the maximum plaintext size in bytes accepted by the GCM
+// ciphers. Encrypt returns an error wrapping [ErrPlaintextTooLarge] for larger
+// plaintexts, and Decrypt rejects ciphertexts that would decrypt to one.
+//
+// It defaults to the maximum allowed by the GCM specification.
+// Lower it to limit the memory usage of untrusted inputs:
+//
+//	simplecipher.MaxPlaintextSize = 1 << 20 // 1 MiB
+var MaxPlaintextSize int64 = gcmMaxPlaintextSize
+
+// GCMConfig configures a GCM cipher created by [NewGCMWithConfig].
+//
+// The zero values of the optional fields select the defaults:
+//
+//   - NonceSize: 0 for the standard 12 bytes.
+//   - TagSize: 0 for the standard 16 bytes.
+//   - RandomNonce: false for using the fixed Nonce.
+//   - AADFunc: nil for no additional data.
+//   - Rand: nil for [crypto/rand.Reader].
+type GCMConfig struct {
+	// Key must be 16, 24, or 32 bytes long to select AES-128, AES-192, or AES-256.
+	Key Key
+	// Nonce is the fixed nonce used for every encryption.
+	// It must be NonceSize bytes long, and must be nil if RandomNonce is set.
+	Nonce Key
+	// NonceSize is the nonce size in bytes. Non-standard sizes reduce
+	// interoperability and are only supported with the standard TagSize.
+	NonceSize int
+	// TagSize is the authentication tag size in bytes, between 12 and 16.
+	// Non-standard sizes are only supported with the standard NonceSize.
+	TagSize int
+	// RandomNonce generates a random nonce for each encryption,
+	// and prepends it to the ciphertext.
+	RandomNonce bool
+	// AADFunc, if not nil, is called on every encryption and decryption
+	// to compute the additional authenticated data of the message.
+	// See [NewGCMWithAADFunc].
+	AADFunc func() []byte
+	// Rand, if not nil, is the source of the random nonces of this cipher
+	// instead of [crypto/rand.Reader], e.g., a deterministic reader to
+	// reproduce the ciphertexts in tests. It requires RandomNonce.
+	// The other ciphers read crypto/rand.Reader, see [WithRand].
+	// Never use a predictable Rand in production: it repeats the nonces.
+	Rand io.Reader
+}
+
+// standard GCM sizes, see [cipher.NewGCM].
+const (
+	gcmStandardNonceSize = 12
+	gcmStandardTagSize   = 16
+	gcmMinTagSize        = 12
+)
+
+// validate checks the combination of the configuration fields,
+// and returns the nonce and tag sizes with the defaults applied.
+func (cfg GCMConfig) validate() (nonceSize, tagSize int, err error) {
+	nonceSize, tagSize = cfg.NonceSize, cfg.TagSize
+	if nonceSize == 0 {
+		nonceSize = gcmStandardNonceSize
+	}
+	if tagSize == 0 {
+		tagSize = gcmStandardTagSize
+	}
+
+	switch {
+	case nonceSize < 0:
+		return 0, 0, fmt.Errorf("%w: negative NonceSize %d", ErrInvalidConfig, nonceSize)
+	case tagSize < gcmMinTagSize || tagSize > gcmStandardTagSize:
+		return 0, 0, fmt.Errorf("%w: TagSize %d not in [%d, %d]", ErrInvalidConfig, tagSize, gcmMinTagSize, gcmStandardTagSize)
+	case nonceSize != gcmStandardNonceSize && tagSize != gcmStandardTagSize:
+		return 0, 0, fmt.Errorf("%w: non-standard NonceSize and TagSize at the same time", ErrInvalidConfig)
+	case cfg.Key == nil:
+		return 0, 0, fmt.Errorf("%w: nil Key", ErrInvalidConfig)
+	case cfg.RandomNonce && cfg.Nonce != nil:
+		return 0, 0, fmt.Errorf("%w: Nonce is set with RandomNonce", ErrInvalidConfig)
+	case !cfg.RandomNonce && cfg.Nonce == nil:
+		return 0, 0, fmt.Errorf("%w: nil Nonce without RandomNonce", ErrInvalidConfig)
+	case !cfg.RandomNonce && cfg.Rand != nil:
+		return 0, 0, fmt.Errorf("%w: Rand is set without RandomNonce", ErrInvalidConfig)
+	}
+
+	return nonceSize, tagSize, nil
+}
+
 // gcm is the AES-GCM cipher mode implementation for the [Cipher] interface.
 type gcm struct {
-	key   Key
-	nonce Key
+	cfg GCMConfig
+
+	// the AEAD and the nonce are built lazily on first use and memoized,
+	// to avoid deriving keys and setting up the AEAD on every call.
+	// Only a success is memoized: a failed build (e.g., a transient error
+	// of a KeyFunc) is retried by the next call. A cipher.AEAD is safe for
+	// concurrent use.
+	mu    sync.Mutex
+	built atomic.Pointer[gcmState]
+}
+
+// gcmState is the AEAD and the nonce built from a [GCMConfig].
+type gcmState struct {
+	aesgcm cipher.AEAD
+	// nonce is the fixed nonce, nil with RandomNonce.
+	nonce []byte
+	// keyID is the SHA-256 of the key, for the nonce reuse detection.
+	keyID [32]byte
 }
 
 var _ Cipher = (*gcm)(nil)
 
+// NewGCMWithConfig creates a new GCM cipher with the given configuration.
+//
+// The configuration is validated on first use: Encrypt, Decrypt and
+// Validate return an error wrapping [ErrInvalidConfig] for an invalid
+// combination of fields, or [ErrNonceSize] for a nonce of the wrong size.
+//
+// The key and nonce are read (derived) only once on first successful use,
+// and the underlying AEAD is reused by the following calls. A failure is
+// retried by the next call, and a [KeyFunc] key is fetched on every call.
+//
+// See also: [NewGCM], [NewGCMWithNonceSize], [SimpleGCM] for common configurations.
+func NewGCMWithConfig(cfg GCMConfig) Cipher {
+	cfg.Key = keyOrEmpty(cfg.Key)
+	return &gcm{cfg: cfg}
+}
+
 // NewGCM creates a new GCM cipher with the given key and nonce.
+//
+// The key and nonce are read (derived) only once on first successful use,
+// and the underlying AEAD is reused by the following calls. A failure is
+// retried by the next call, and a [KeyFunc] key is fetched on every call.
+//
 // It's caller's responsibility to ensure the following:
 //
 //   - The key must be 16 or 32 bytes long to select AES-128 or AES-256.
@@ -33,9 +165,42 @@ var _ Cipher = (*gcm)(nil)
 //
 // Use [SimpleGCM] if you are not familiar with these.
 //
-// See also: [cipher.NewGCM] for low-level usage.
+// See also: [cipher.NewGCM] for low-level usage, [NewGCMWithConfig] for more control.
 func NewGCM(key, nonce Key) Cipher {
-	return &gcm{key: key, nonce: nonce}
+	return NewGCMWithConfig(GCMConfig{Key: key, Nonce: nonce})
+}
+
+// NewGCMWithNonceSize creates a new GCM cipher with the given key and a nonce
+// of non-standard length.
+//
+// The nonce must be exactly nonceSize bytes long, otherwise Encrypt and Decrypt
+// return an error wrapping [ErrNonceSize].
+//
+// Only use this function if you require compatibility with an existing
+// cryptosystem that uses non-standard nonce lengths. All other users should
+// use [NewGCM], which is faster and more resistant to misuse.
+// Non-12-byte nonces reduce interoperability with other implementations.
+//
+// See also: [cipher.NewGCMWithNonceSize] for low-level usage.
+func NewGCMWithNonceSize(key, nonce Key, nonceSize int) Cipher {
+	return NewGCMWithConfig(GCMConfig{Key: key, Nonce: nonce, NonceSize: nonceSize})
+}
+
+// NewGCMWithAADFunc creates a new GCM cipher with the given key and a random
+// nonce per message, authenticating the additional data computed by aadFunc
+// at Encrypt and Decrypt time, e.g., from the request metadata:
+//
+//	c := simplecipher.NewGCMWithAADFunc(key, func() []byte {
+//		return []byte(tenantID)
+//	})
+//
+// The AAD is not stored in the ciphertext. aadFunc must return the same
+// value when decrypting as it did when encrypting, otherwise Decrypt fails
+// with an error wrapping [ErrAuthenticationFailed].
+//
+// See also: [GCMConfig].AADFunc to combine it with other options.
+func NewGCMWithAADFunc(key Key, aadFunc func() []byte) Cipher {
+	return NewGCMWithConfig(GCMConfig{Key: key, RandomNonce: true, AADFunc: aadFunc})
 }
 
 // SimpleGCM creates a new AES-256-GCM cipher from the given key and nonce.
@@ -54,54 +219,984 @@ func SimpleGCM(keyPassphrase, noncePassphrase string) Cipher {
 	return NewGCM(NewAesKey(keyPassphrase), NewNonce(noncePassphrase))
 }
 
+// Mode returns [ModeGCM].
+func (g *gcm) Mode() ModeID {
+	return ModeGCM
+}
+
+// Describe describes the cipher, e.g., AES-256-GCM.
+func (g *gcm) Describe() CipherInfo {
+	return CipherInfo{Algorithm: algorithmAES, Mode: ModeGCM, KeyBits: keyBits(g.cfg.Key), Codec: defaultCodecName()}
+}
+
+// Validate checks the configuration, and the lengths of the key and the nonce.
+func (g *gcm) Validate() (err error) {
+	defer recoverFromPanic(&err)
+
+	nonceSize, _, err := g.cfg.validate()
+	if err != nil {
+		return err
+	}
+
+	if err := validateAesKey(g.cfg.Key); err != nil {
+		return err
+	}
+
+	if g.cfg.RandomNonce {
+		return nil
+	}
+	if n := len(g.cfg.Nonce.Bytes()); n != nonceSize {
+		return fmt.Errorf("%w: got %d bytes, want %d", ErrNonceSize, n, nonceSize)
+	}
+
+	return nil
+}
+
 // Encrypt encrypts the given plaintext using GCM.
 // The ciphertext is returned with [DefaultStringCodec] encoding.
+//
+// With RandomNonce configured, the random nonce is prepended to the ciphertext.
 func (g *gcm) Encrypt(plainText string) (cipherText string, err error) {
+	defer wrapOpError(&err, encrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	if int64(len(plainText)) > MaxPlaintextSize {
+		return "", fmt.Errorf("%w: %d bytes > %d", ErrPlaintextTooLarge, len(plainText), MaxPlaintextSize)
+	}
+
+	aesgcm, prefix, nonce, err := g.sealNonce()
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext := aesgcm.Seal(prefix, nonce, []byte(plainText), g.additionalData(nil))
+
+	return DefaultStringCodec.EncodeToString(ciphertext), nil
+}
+
+// Decrypt decrypts the given ciphertext using GCM.
+// The ciphertext must be a [DefaultStringCodec] string.
+//
+// If the ciphertext has been tampered with (or the key/nonce mismatch),
+// an error wrapping [ErrAuthenticationFailed] is returned.
+func (g *gcm) Decrypt(cipherText string) (plainText string, err error) {
+	defer wrapOpError(&err, decrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	ciphertext, err := decodeCipherText(cipherText)
+	if err != nil {
+		return "", err
+	}
+
+	aesgcm, nonce, ciphertext, err := g.openNonce(ciphertext)
+	if err != nil {
+		return "", err
+	}
+
+	if int64(len(ciphertext)) > MaxPlaintextSize+int64(aesgcm.Overhead()) {
+		return "", fmt.Errorf("%w: %d bytes ciphertext", ErrPlaintextTooLarge, len(ciphertext))
+	}
+
+	plaintext, err := aesgcm.Open(nil, nonce, ciphertext, g.additionalData(nil))
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrAuthenticationFailed, err)
+	}
+
+	return string(plaintext), nil
+}
+
+// additionalData returns the additional data to authenticate:
+// the header followed by the output of the AADFunc, if any.
+func (g *gcm) additionalData(header []byte) []byte {
+	if g.cfg.AADFunc == nil {
+		return header
+	}
+	return append(slices.Clip(header), g.cfg.AADFunc()...)
+}
+
+// sealNonce returns the AEAD and the nonce to seal a new message.
+// With RandomNonce, a new random nonce is returned also as the prefix
+// of the ciphertext; otherwise prefix is nil.
+// The nonce is checked by [EnableNonceReuseDetection] if enabled.
+func (g *gcm) sealNonce() (aesgcm cipher.AEAD, prefix, nonce []byte, err error) {
+	state, err := g.state()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	aesgcm, nonce = state.aesgcm, state.nonce
+
+	if g.cfg.RandomNonce {
+		nonce, err = randomBytesFrom(g.cfg.Rand, aesgcm.NonceSize())
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		prefix = nonce
+	}
+
+	if err := trackNonce(state.keyID, nonce); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return aesgcm, prefix, nonce, nil
+}
+
+// openNonce returns the AEAD and the nonce to open the ciphertext.
+// With RandomNonce, the nonce is split from the beginning of the ciphertext.
+func (g *gcm) openNonce(ciphertext []byte) (aesgcm cipher.AEAD, nonce, rest []byte, err error) {
+	aesgcm, nonce, err = g.aead()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if g.cfg.RandomNonce {
+		if len(ciphertext) < aesgcm.NonceSize() {
+			return nil, nil, nil, ErrCipherTextTooShort
+		}
+		nonce, ciphertext = ciphertext[:aesgcm.NonceSize()], ciphertext[aesgcm.NonceSize():]
+	}
+
+	return aesgcm, nonce, ciphertext, nil
+}
+
+//////// Detached tags ////////
+
+// DetachedCipher is a [Cipher] that can store the authentication tag
+// separately from the ciphertext, for protocols that require so.
+//
+// The GCM ciphers created by [NewGCM], [NewGCMWithNonceSize],
+// [NewGCMWithConfig] and [SimpleGCM] implement DetachedCipher:
+//
+//	dc := simplecipher.SimpleGCM("key", "nonce").(simplecipher.DetachedCipher)
+//	ciphertext, tag, err := dc.EncryptDetached("plaintext")
+type DetachedCipher interface {
+	Cipher
+	// EncryptDetached encrypts the plaintext, and returns the ciphertext and
+	// the authentication tag separately, both [DefaultStringCodec] encoded.
+	EncryptDetached(plainText string) (cipherText string, tag string, err error)
+	// DecryptDetached verifies the tag and decrypts the ciphertext,
+	// both [DefaultStringCodec] encoded.
+	DecryptDetached(cipherText string, tag string) (plainText string, err error)
+}
+
+var _ DetachedCipher = (*gcm)(nil)
+
+// EncryptDetached encrypts the given plaintext using GCM,
+// and splits the authentication tag from the end of the ciphertext.
+func (g *gcm) EncryptDetached(plainText string) (cipherText string, tag string, err error) {
+	defer wrapOpError(&err, encrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	if int64(len(plainText)) > MaxPlaintextSize {
+		return "", "", fmt.Errorf("%w: %d bytes > %d", ErrPlaintextTooLarge, len(plainText), MaxPlaintextSize)
+	}
+
+	aesgcm, prefix, nonce, err := g.sealNonce()
+	if err != nil {
+		return "", "", err
+	}
+
+	sealed := aesgcm.Seal(prefix, nonce, []byte(plainText), g.additionalData(nil))
+	ciphertext, rawTag := sealed[:len(sealed)-aesgcm.Overhead()], sealed[len(sealed)-aesgcm.Overhead():]
+
+	return DefaultStringCodec.EncodeToString(ciphertext), DefaultStringCodec.EncodeToString(rawTag), nil
+}
+
+// DecryptDetached reassembles the ciphertext and the authentication tag,
+// and decrypts them using GCM.
+//
+// If the tag does not match the ciphertext,
+// an error wrapping [ErrAuthenticationFailed] is returned.
+func (g *gcm) DecryptDetached(cipherText string, tag string) (plainText string, err error) {
+	defer wrapOpError(&err, decrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	ciphertext, err := decodeCipherText(cipherText)
+	if err != nil {
+		return "", err
+	}
+
+	rawTag, err := decodeCipherText(tag)
+	if err != nil {
+		return "", err
+	}
+
+	aesgcm, nonce, ciphertext, err := g.openNonce(ciphertext)
+	if err != nil {
+		return "", err
+	}
+
+	if int64(len(ciphertext)) > MaxPlaintextSize {
+		return "", fmt.Errorf("%w: %d bytes ciphertext", ErrPlaintextTooLarge, len(ciphertext))
+	}
+
+	if len(rawTag) != aesgcm.Overhead() {
+		return "", fmt.Errorf("%w: tag must be %d bytes, got %d", ErrAuthenticationFailed, aesgcm.Overhead(), len(rawTag))
+	}
+
+	plaintext, err := aesgcm.Open(nil, nonce, append(ciphertext, rawTag...), g.additionalData(nil))
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrAuthenticationFailed, err)
+	}
+
+	return string(plaintext), nil
+}
+
+//////// Cleartext headers ////////
+
+// MaxHeaderSize is the maximum size in bytes of a cleartext header
+// accepted by [HeaderCipher].
+const MaxHeaderSize = 255
+
+// HeaderCipher is a [Cipher] that can prefix the ciphertext with a small
+// cleartext header (e.g., routing information), authenticated as the
+// additional data of the AEAD.
+//
+// The GCM ciphers created by [NewGCM], [NewGCMWithNonceSize],
+// [NewGCMWithConfig] and [SimpleGCM] implement HeaderCipher.
+// The output is laid out as:
+//
+//	len(header) (1 byte) | header | nonce | ciphertext | tag
+//
+// The nonce is always included, so it is not needed to decrypt.
+type HeaderCipher interface {
+	Cipher
+	// EncryptWithHeader encrypts the plaintext, and prefixes the
+	// ciphertext with the cleartext header, at most [MaxHeaderSize] bytes.
+	// The result is returned with [DefaultStringCodec] encoding.
+	EncryptWithHeader(header []byte, plainText string) (cipherText string, err error)
+	// DecryptWithHeader verifies and decrypts the ciphertext,
+	// and returns the cleartext header along with the plaintext.
+	DecryptWithHeader(cipherText string) (header []byte, plainText string, err error)
+}
+
+var _ HeaderCipher = (*gcm)(nil)
+
+// EncryptWithHeader encrypts the given plaintext using GCM,
+// authenticating the cleartext header as the additional data.
+func (g *gcm) EncryptWithHeader(header []byte, plainText string) (cipherText string, err error) {
+	defer wrapOpError(&err, encrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	if len(header) > MaxHeaderSize {
+		return "", fmt.Errorf("%w: header of %d bytes > %d", ErrInvalidConfig, len(header), MaxHeaderSize)
+	}
+
+	if int64(len(plainText)) > MaxPlaintextSize {
+		return "", fmt.Errorf("%w: %d bytes > %d", ErrPlaintextTooLarge, len(plainText), MaxPlaintextSize)
+	}
+
+	aesgcm, _, nonce, err := g.sealNonce()
+	if err != nil {
+		return "", err
+	}
+
+	out := make([]byte, 0, 1+len(header)+len(nonce)+len(plainText)+aesgcm.Overhead())
+	out = append(out, byte(len(header)))
+	out = append(out, header...)
+	out = append(out, nonce...)
+	out = aesgcm.Seal(out, nonce, []byte(plainText), g.additionalData(header))
+
+	return DefaultStringCodec.EncodeToString(out), nil
+}
+
+// DecryptWithHeader splits the cleartext header and the nonce from the
+// ciphertext, and decrypts the rest using GCM.
+//
+// If the header or the ciphertext has been tampered with,
+// an error wrapping [ErrAuthenticationFailed] is returned.
+func (g *gcm) DecryptWithHeader(cipherText string) (header []byte, plainText string, err error) {
+	defer wrapOpError(&err, decrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	data, err := decodeCipherText(cipherText)
+	if err != nil {
+		return nil, "", err
+	}
+
+	aesgcm, _, err := g.aead()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(data) < 1 || len(data) < 1+int(data[0])+aesgcm.NonceSize()+aesgcm.Overhead() {
+		return nil, "", ErrCipherTextTooShort
+	}
+
+	header, data = data[1:1+int(data[0])], data[1+int(data[0]):]
+	nonce, ciphertext := data[:aesgcm.NonceSize()], data[aesgcm.NonceSize():]
+
+	if int64(len(ciphertext)) > MaxPlaintextSize+int64(aesgcm.Overhead()) {
+		return nil, "", fmt.Errorf("%w: %d bytes ciphertext", ErrPlaintextTooLarge, len(ciphertext))
+	}
+
+	plaintext, err := aesgcm.Open(nil, nonce, ciphertext, g.additionalData(header))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %w", ErrAuthenticationFailed, err)
+	}
+
+	return header, string(plaintext), nil
+}
+
+//////// Appending ////////
+
+// AppendCipher is a [Cipher] that can encrypt and decrypt raw bytes into
+// caller-provided buffers, like the built-in append, to reduce allocations
+// in hot paths:
+//
+//	buf = buf[:0]
+//	buf, err = c.EncryptAppend(buf, plaintext)
+//
+// The ciphertexts are the raw bytes of the ones of Encrypt and Decrypt,
+// without the [DefaultStringCodec] encoding.
+//
+// The GCM ciphers created by [NewGCM], [NewGCMWithNonceSize],
+// [NewGCMWithConfig] and [SimpleGCM], and the CBC ciphers created by
+// [NewCBC], [NewCBCNoIVPrepend] and [SimpleCBC] implement AppendCipher.
+type AppendCipher interface {
+	Cipher
+	// EncryptAppend appends the ciphertext of the plaintext to dst,
+	// and returns the updated slice.
+	EncryptAppend(dst, plainText []byte) ([]byte, error)
+	// DecryptAppend appends the plaintext of the ciphertext to dst,
+	// and returns the updated slice.
+	// On error, dst is returned unchanged (its spare capacity may be overwritten).
+	DecryptAppend(dst, cipherText []byte) ([]byte, error)
+}
+
+var _ AppendCipher = (*gcm)(nil)
+
+// EncryptAppend encrypts the given plaintext using GCM, appending the
+// ciphertext to dst. The plaintext and dst must not overlap.
+//
+// With RandomNonce configured, the random nonce is appended before the ciphertext.
+func (g *gcm) EncryptAppend(dst, plainText []byte) (_ []byte, err error) {
+	defer wrapOpError(&err, encrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	if int64(len(plainText)) > MaxPlaintextSize {
+		return dst, fmt.Errorf("%w: %d bytes > %d", ErrPlaintextTooLarge, len(plainText), MaxPlaintextSize)
+	}
+
+	state, err := g.state()
+	if err != nil {
+		return dst, err
+	}
+	aesgcm, nonce := state.aesgcm, state.nonce
+
+	out := dst
+	if g.cfg.RandomNonce {
+		n := aesgcm.NonceSize()
+		out = slices.Grow(out, n+len(plainText)+aesgcm.Overhead())
+		out = out[:len(out)+n]
+		nonce = out[len(out)-n:]
+		if err := readRandom(g.cfg.Rand, nonce); err != nil {
+			return dst, err
+		}
+	}
+
+	if err := trackNonce(state.keyID, nonce); err != nil {
+		return dst, err
+	}
+
+	return aesgcm.Seal(out, nonce, plainText, g.additionalData(nil)), nil
+}
+
+// DecryptAppend decrypts the given ciphertext using GCM, appending the
+// plaintext to dst. The ciphertext and dst must not overlap.
+//
+// If the ciphertext has been tampered with (or the key/nonce mismatch),
+// an error wrapping [ErrAuthenticationFailed] is returned.
+func (g *gcm) DecryptAppend(dst, cipherText []byte) (_ []byte, err error) {
+	defer wrapOpError(&err, decrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	aesgcm, nonce, ciphertext, err := g.openNonce(cipherText)
+	if err != nil {
+		return dst, err
+	}
+
+	if int64(len(ciphertext)) > MaxPlaintextSize+int64(aesgcm.Overhead()) {
+		return dst, fmt.Errorf("%w: %d bytes ciphertext", ErrPlaintextTooLarge, len(ciphertext))
+	}
+
+	out, err := aesgcm.Open(dst, nonce, ciphertext, g.additionalData(nil))
+	if err != nil {
+		return dst, fmt.Errorf("%w: %w", ErrAuthenticationFailed, err)
+	}
+
+	return out, nil
+}
+
+var _ RawCipher = (*gcm)(nil)
+
+// EncryptRaw encrypts the given plaintext using GCM like Encrypt,
+// and returns the raw ciphertext bytes without encoding.
+func (g *gcm) EncryptRaw(plainText string) ([]byte, error) {
+	return g.EncryptAppend(nil, []byte(plainText))
+}
+
+// DecryptRaw decrypts the given raw ciphertext bytes using GCM like
+// Decrypt, without decoding.
+func (g *gcm) DecryptRaw(cipherText []byte) (string, error) {
+	plaintext, err := g.DecryptAppend(nil, cipherText)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+//////// Verification ////////
+
+// Verifier is a [Cipher] that can check the integrity of a ciphertext
+// without returning the plaintext, e.g., for a health check of stored
+// ciphertexts by a process that should not see the data.
+//
+// The GCM ciphers created by [NewGCM], [NewGCMWithNonceSize],
+// [NewGCMWithConfig] and [SimpleGCM] implement Verifier.
+type Verifier interface {
+	Cipher
+	// Verify returns nil if the ciphertext is authentic,
+	// or an error wrapping [ErrAuthenticationFailed] otherwise.
+	Verify(cipherText string) error
+}
+
+var _ Verifier = (*gcm)(nil)
+
+// Verify authenticates the given ciphertext using GCM like Decrypt,
+// and returns only the result. The ciphertext must be a
+// [DefaultStringCodec] string.
+//
+// GCM can not authenticate without decrypting: the plaintext is decrypted
+// into a scratch buffer, which is zeroed before returning.
+func (g *gcm) Verify(cipherText string) (err error) {
+	defer wrapOpError(&err, decrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	ciphertext, err := decodeCipherText(cipherText)
+	if err != nil {
+		return err
+	}
+
+	aesgcm, nonce, ciphertext, err := g.openNonce(ciphertext)
+	if err != nil {
+		return err
+	}
+
+	if int64(len(ciphertext)) > MaxPlaintextSize+int64(aesgcm.Overhead()) {
+		return fmt.Errorf("%w: %d bytes ciphertext", ErrPlaintextTooLarge, len(ciphertext))
+	}
+
+	// decrypt in place: the decoded ciphertext is the scratch buffer
+	_, err = aesgcm.Open(ciphertext[:0], nonce, ciphertext, g.additionalData(nil))
+	clear(ciphertext)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrAuthenticationFailed, err)
+	}
+
+	return nil
+}
+
+//////// Streamed additional data ////////
+
+// AADReaderCipher is a [Cipher] that authenticates additional data read
+// from an [io.Reader], e.g., a large file header that should not be held
+// in memory, or not be copied into the ciphertext.
+//
+// The GCM ciphers created by [NewGCM], [NewGCMWithNonceSize],
+// [NewGCMWithConfig] and [SimpleGCM] implement AADReaderCipher:
+//
+//	ac := simplecipher.SimpleGCM("key", "nonce").(simplecipher.AADReaderCipher)
+//	ciphertext, err := ac.EncryptWithAADReader(headerFile, "plaintext")
+//
+// As GCM of [crypto/cipher] takes the additional data as a whole, the
+// reader is hashed with SHA-256 on the fly, and the 32 bytes digest is
+// authenticated as the additional data instead. This is as secure as
+// authenticating the data itself, as long as SHA-256 is collision
+// resistant.
+//
+// The additional data is not included in the ciphertext: the same data
+// must be read again to decrypt. The ciphertexts are laid out like the ones
+// of Encrypt, but are NOT interchangeable, since the additional data differs.
+type AADReaderCipher interface {
+	Cipher
+	// EncryptWithAADReader encrypts the plaintext, authenticating the
+	// additional data read from aad till EOF.
+	// The ciphertext is returned with [DefaultStringCodec] encoding.
+	EncryptWithAADReader(aad io.Reader, plainText string) (cipherText string, err error)
+	// DecryptWithAADReader verifies the ciphertext against the additional
+	// data read from aad till EOF, and decrypts it.
+	DecryptWithAADReader(aad io.Reader, cipherText string) (plainText string, err error)
+}
+
+var _ AADReaderCipher = (*gcm)(nil)
+
+// EncryptWithAADReader encrypts the given plaintext using GCM,
+// authenticating the SHA-256 digest of the additional data.
+func (g *gcm) EncryptWithAADReader(aad io.Reader, plainText string) (cipherText string, err error) {
+	defer wrapOpError(&err, encrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	if int64(len(plainText)) > MaxPlaintextSize {
+		return "", fmt.Errorf("%w: %d bytes > %d", ErrPlaintextTooLarge, len(plainText), MaxPlaintextSize)
+	}
+
+	digest, err := digestAAD(aad)
+	if err != nil {
+		return "", err
+	}
+
+	aesgcm, prefix, nonce, err := g.sealNonce()
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext := aesgcm.Seal(prefix, nonce, []byte(plainText), g.additionalData(digest))
+
+	return DefaultStringCodec.EncodeToString(ciphertext), nil
+}
+
+// DecryptWithAADReader decrypts the given ciphertext using GCM,
+// verifying the SHA-256 digest of the additional data.
+//
+// If the ciphertext or the additional data has been tampered with,
+// an error wrapping [ErrAuthenticationFailed] is returned.
+func (g *gcm) DecryptWithAADReader(aad io.Reader, cipherText string) (plainText string, err error) {
+	defer wrapOpError(&err, decrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	ciphertext, err := decodeCipherText(cipherText)
+	if err != nil {
+		return "", err
+	}
+
+	digest, err := digestAAD(aad)
+	if err != nil {
+		return "", err
+	}
+
+	aesgcm, nonce, ciphertext, err := g.openNonce(ciphertext)
+	if err != nil {
+		return "", err
+	}
+
+	if int64(len(ciphertext)) > MaxPlaintextSize+int64(aesgcm.Overhead()) {
+		return "", fmt.Errorf("%w: %d bytes ciphertext", ErrPlaintextTooLarge, len(ciphertext))
+	}
+
+	plaintext, err := aesgcm.Open(nil, nonce, ciphertext, g.additionalData(digest))
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrAuthenticationFailed, err)
+	}
+
+	return string(plaintext), nil
+}
+
+// digestAAD reads the additional data till EOF, and returns its SHA-256 digest.
+func digestAAD(aad io.Reader) ([]byte, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, aad); err != nil {
+		return nil, fmt.Errorf("%w: additional data: %w", ErrCopy, err)
+	}
+	return h.Sum(nil), nil
+}
+
+//////// Versioned ////////
+
+// gcmVersion1 is the current format version of [NewGCMVersioned]:
+// a random 12-byte nonce, and a 16-byte tag.
+const gcmVersion1 byte = 1
+
+// gcmVersioned is the AES-GCM cipher prefixing the ciphertext with a
+// format version, implementing the [Cipher] interface.
+type gcmVersioned struct {
+	gcm *gcm
+}
+
+var _ ModeCipher = (*gcmVersioned)(nil)
+
+// NewGCMVersioned creates a new GCM cipher with the given key and a random
+// nonce per message, prefixing the ciphertext with a format version byte,
+// so that the format can evolve (e.g., switching the nonce size) without
+// ambiguity:
+//
+//	version (1 byte) | nonce (12) | ciphertext | tag (16)
+//
+// Encrypt writes version 1. Decrypt returns an error wrapping
+// [ErrUnsupportedVersion] for the versions it does not know.
+func NewGCMVersioned(key Key) Cipher {
+	return &gcmVersioned{gcm: &gcm{cfg: GCMConfig{Key: keyOrEmpty(key), RandomNonce: true}}}
+}
+
+// Mode returns [ModeGCM].
+func (g *gcmVersioned) Mode() ModeID {
+	return ModeGCM
+}
+
+// Describe describes the cipher, e.g., AES-256-GCM.
+func (g *gcmVersioned) Describe() CipherInfo {
+	return g.gcm.Describe()
+}
+
+// Validate checks the length of the key.
+func (g *gcmVersioned) Validate() error {
+	return g.gcm.Validate()
+}
+
+// Encrypt encrypts the given plaintext using GCM, in the current version.
+// The ciphertext is returned with [DefaultStringCodec] encoding.
+func (g *gcmVersioned) Encrypt(plainText string) (cipherText string, err error) {
+	defer wrapOpError(&err, encrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	ciphertext, err := g.gcm.EncryptAppend([]byte{gcmVersion1}, []byte(plainText))
+	if err != nil {
+		return "", err
+	}
+
+	return DefaultStringCodec.EncodeToString(ciphertext), nil
+}
+
+// Decrypt decrypts the given ciphertext using GCM, in the format of its version.
+// The ciphertext must be a [DefaultStringCodec] string.
+func (g *gcmVersioned) Decrypt(cipherText string) (plainText string, err error) {
+	defer wrapOpError(&err, decrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	ciphertext, err := decodeCipherText(cipherText)
+	if err != nil {
+		return "", err
+	}
+
+	if len(ciphertext) < 1 {
+		return "", ErrCipherTextTooShort
+	}
+
+	if version := ciphertext[0]; version != gcmVersion1 {
+		return "", fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+	}
+
+	plaintext, err := g.gcm.DecryptAppend(nil, ciphertext[1:])
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+//////// Synthetic nonce ////////
+
+// gcmSynthNonce is the AES-GCM cipher with a nonce synthesized
+// from the plaintext, implementing the [Cipher] interface.
+type gcmSynthNonce struct {
+	key Key
+	// macKey is the HMAC key to synthesize nonces, derived from key.
+	macKey Key
+}
+
+var _ ModeCipher = (*gcmSynthNonce)(nil)
+
+// hkdfInfoSynthNonce is the HKDF info label of the nonce synthesis key.
+const hkdfInfoSynthNonce = "simplecipher gcm synthetic nonce"
+
+// NewGCMSynthNonce creates a new deterministic GCM cipher with the given key.
+//
+// The nonce is synthesized as HMAC-SHA256(macKey, plaintext)[:12], where
+// macKey is derived from the key via HKDF. The nonce is prepended to the
+// ciphertext, and verified against the plaintext on decryption.
+// So the same plaintext encrypted with the same key always produces the
+// same ciphertext, while different plaintexts (almost) never share a nonce.
+//
+// Caveat: deterministic encryption leaks whether two ciphertexts have the
+// same plaintext. This construction is SIV-style but weaker than a real
+// AES-SIV (RFC 5297). Use [NewGCM] with unique nonces unless you need
+// determinism (e.g., for equality lookups on encrypted columns).
+//
+// The key must be 16, 24, or 32 bytes long to select AES-128, AES-192, or AES-256.
+func NewGCMSynthNonce(key Key) Cipher {
+	key = keyOrEmpty(key)
+	return &gcmSynthNonce{
+		key:    key,
+		macKey: &hkdfKey{Secret: key, Info: hkdfInfoSynthNonce, Len: Aes256},
+	}
+}
+
+// Mode returns [ModeGCM].
+func (g *gcmSynthNonce) Mode() ModeID {
+	return ModeGCM
+}
+
+// Describe describes the cipher, e.g., AES-256-GCM.
+func (g *gcmSynthNonce) Describe() CipherInfo {
+	return CipherInfo{Algorithm: algorithmAES, Mode: ModeGCM, KeyBits: keyBits(g.key), Codec: defaultCodecName()}
+}
+
+// synthNonce returns the nonce synthesized from the plaintext.
+func (g *gcmSynthNonce) synthNonce(plaintext []byte) []byte {
+	mac := hmac.New(sha256.New, g.macKey.Bytes())
+	mac.Write(plaintext)
+	return mac.Sum(nil)[:NonceSize]
+}
+
+// Encrypt encrypts the given plaintext using GCM with a synthetic nonce.
+// The ciphertext (with the nonce prepended) is returned with [DefaultStringCodec] encoding.
+func (g *gcmSynthNonce) Encrypt(plainText string) (cipherText string, err error) {
+	defer wrapOpError(&err, encrypt, ModeGCM)
 	defer recoverFromPanic(&err)
 
 	plaintext := []byte(plainText)
-	key := g.key.Bytes()
-	nonce := g.nonce.Bytes()
+	nonce := g.synthNonce(plaintext)
 
-	block, err := aes.NewCipher(key)
+	ciphertext, err := sealGCM(g.key.Bytes(), nonce, plaintext)
 	if err != nil {
 		return "", err
 	}
 
-	aesgcm, err := cipher.NewGCM(block)
+	return DefaultStringCodec.EncodeToString(append(nonce, ciphertext...)), nil
+}
+
+// Decrypt decrypts the given ciphertext using GCM with the prepended nonce,
+// and verifies that the nonce is synthesized from the plaintext.
+// The ciphertext must be a [DefaultStringCodec] string.
+func (g *gcmSynthNonce) Decrypt(cipherText string) (plainText string, err error) {
+	defer wrapOpError(&err, decrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	ciphertext, err := decodeCipherText(cipherText)
 	if err != nil {
 		return "", err
 	}
 
-	ciphertext := aesgcm.Seal(nil, nonce, plaintext, nil)
+	if len(ciphertext) < int(NonceSize)+gcmTagSize {
+		return "", ErrCipherTextTooShort
+	}
+
+	nonce, ciphertext := ciphertext[:NonceSize], ciphertext[NonceSize:]
+
+	plaintext, err := openGCM(g.key.Bytes(), nonce, ciphertext)
+	if err != nil {
+		return "", err
+	}
+
+	if !hmac.Equal(nonce, g.synthNonce(plaintext)) {
+		return "", fmt.Errorf("%w: synthetic nonce mismatch", ErrAuthenticationFailed)
+	}
+
+	return string(plaintext), nil
+}
+
+//////// Counter nonce ////////
+
+// gcmCounterSize is the size of the counter at the start of the nonce.
+const gcmCounterSize = 8
+
+// gcmCounter is the AES-GCM cipher with the nonce taken from a counter,
+// implementing the [Cipher] interface.
+type gcmCounter struct {
+	gcm *gcm
+
+	mu        sync.Mutex
+	next      uint64 // counter of the next encrypted message
+	exhausted bool   // the counter has wrapped around
+}
+
+var _ ModeCipher = (*gcmCounter)(nil)
+
+// NewGCMCounter creates a new GCM cipher with the given key and a nonce
+// taken from an internal counter, starting from startCounter.
+//
+// Each Encrypt uses the counter (then increments it) as the nonce, and
+// prepends the nonce to the ciphertext:
+//
+//	counter (8 bytes, big-endian) | zeros (4) | ciphertext | tag (16)
+//
+// Unlike random nonces, the nonces of one instance never collide, so the
+// key can encrypt more than the 2^32 messages recommended with random
+// nonces. The counter does not wrap around: once the message of counter
+// 2^64-1 is encrypted, Encrypt returns an error wrapping [ErrNonceReused].
+//
+// This is only safe with a single writer per key: the state is per
+// instance, in memory, and safe for concurrent use. Two instances (e.g.,
+// two processes, or one after a restart) with the same key and an
+// overlapping range of counters reuse nonces, which breaks GCM entirely.
+// Persist the counter (the first 8 bytes of the last ciphertext) and
+// start after it, or use [NewGCMWithAADFunc] and other random nonce
+// ciphers if you can not.
+//
+// Decrypt reads the nonce prepended to the ciphertext, so it does not
+// depend on the counter. The key must be 16, 24, or 32 bytes long to
+// select AES-128, AES-192, or AES-256.
+func NewGCMCounter(key Key, startCounter uint64) Cipher {
+	return &gcmCounter{gcm: &gcm{cfg: GCMConfig{Key: keyOrEmpty(key), RandomNonce: true}}, next: startCounter}
+}
+
+// Mode returns [ModeGCM].
+func (g *gcmCounter) Mode() ModeID {
+	return ModeGCM
+}
+
+// Describe describes the cipher, e.g., AES-256-GCM.
+func (g *gcmCounter) Describe() CipherInfo {
+	return g.gcm.Describe()
+}
+
+// Validate checks the length of the key.
+func (g *gcmCounter) Validate() error {
+	return g.gcm.Validate()
+}
+
+// nextNonce returns the nonce of the next counter, and increments it.
+func (g *gcmCounter) nextNonce() ([]byte, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.exhausted {
+		return nil, fmt.Errorf("%w: nonce counter exhausted", ErrNonceReused)
+	}
+
+	nonce := make([]byte, NonceSize)
+	binary.BigEndian.PutUint64(nonce[:gcmCounterSize], g.next)
+
+	g.next++
+	g.exhausted = g.next == 0
+
+	return nonce, nil
+}
+
+// Encrypt encrypts the given plaintext using GCM with the next counter
+// as the nonce. The ciphertext (with the nonce prepended) is returned with
+// [DefaultStringCodec] encoding.
+func (g *gcmCounter) Encrypt(plainText string) (cipherText string, err error) {
+	defer wrapOpError(&err, encrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	if int64(len(plainText)) > MaxPlaintextSize {
+		return "", fmt.Errorf("%w: %d bytes > %d", ErrPlaintextTooLarge, len(plainText), MaxPlaintextSize)
+	}
+
+	state, err := g.gcm.state()
+	if err != nil {
+		return "", err
+	}
+
+	nonce, err := g.nextNonce()
+	if err != nil {
+		return "", err
+	}
+
+	if err := trackNonce(state.keyID, nonce); err != nil {
+		return "", err
+	}
+
+	ciphertext := state.aesgcm.Seal(nonce, nonce, []byte(plainText), nil)
 
 	return DefaultStringCodec.EncodeToString(ciphertext), nil
 }
 
-// Decrypt decrypts the given ciphertext using GCM.
+// Decrypt decrypts the given ciphertext using GCM with the prepended nonce.
 // The ciphertext must be a [DefaultStringCodec] string.
-func (g *gcm) Decrypt(cipherText string) (plainText string, err error) {
+func (g *gcmCounter) Decrypt(cipherText string) (plainText string, err error) {
+	defer wrapOpError(&err, decrypt, ModeGCM)
 	defer recoverFromPanic(&err)
 
-	ciphertext, err := DefaultStringCodec.DecodeString(cipherText)
+	ciphertext, err := decodeCipherText(cipherText)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := g.gcm.DecryptAppend(nil, ciphertext)
 	if err != nil {
 		return "", err
 	}
 
-	key := g.key.Bytes()
-	nonce := g.nonce.Bytes()
+	return string(plaintext), nil
+}
+
+//////// Auto salt ////////
+
+// autoSaltSize is the size of the random salt prepended by [SimpleGCMAutoSalt].
+const autoSaltSize = 16
+
+// gcmAutoSalt is the AES-GCM cipher deriving the key with a random salt
+// per encryption, implementing the [Cipher] interface.
+type gcmAutoSalt struct {
+	passphrase string
+}
+
+var _ ModeCipher = (*gcmAutoSalt)(nil)
 
-	block, err := aes.NewCipher(key)
+// SimpleGCMAutoSalt creates a new AES-256-GCM cipher from the passphrase,
+// with a random salt per ciphertext, instead of [DefaultSalt].
+//
+// Each Encrypt generates a random 16-byte salt, derives the key from the
+// passphrase with it, and prepends the salt to the ciphertext:
+//
+//	salt (16) | ciphertext | tag (16)
+//
+// Decrypt re-derives the key with the embedded salt.
+// As every key is unique, the nonce is fixed (zeros).
+// So there are no salts or nonces to manage.
+//
+// Caveat: the key is derived with scrypt on every Encrypt and Decrypt,
+// which is slow by design. Use [SimpleGCM] or [NewGCM] with a
+// [MaterializeKey] in hot paths.
+func SimpleGCMAutoSalt(passphrase string) Cipher {
+	return &gcmAutoSalt{passphrase: passphrase}
+}
+
+// Mode returns [ModeGCM].
+func (g *gcmAutoSalt) Mode() ModeID {
+	return ModeGCM
+}
+
+// Describe describes the cipher, i.e., AES-256-GCM.
+func (g *gcmAutoSalt) Describe() CipherInfo {
+	return CipherInfo{Algorithm: algorithmAES, Mode: ModeGCM, KeyBits: int(Aes256) * 8, Codec: defaultCodecName()}
+}
+
+// key derives the key from the passphrase with the salt.
+func (g *gcmAutoSalt) key(salt []byte) []byte {
+	return NewAesKey(g.passphrase, WithSalt(string(salt))).Bytes()
+}
+
+// Encrypt encrypts the given plaintext using GCM with a key derived with a
+// random salt. The ciphertext (with the salt prepended) is returned with
+// [DefaultStringCodec] encoding.
+func (g *gcmAutoSalt) Encrypt(plainText string) (cipherText string, err error) {
+	defer wrapOpError(&err, encrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	salt, err := randomBytes(autoSaltSize)
 	if err != nil {
 		return "", err
 	}
 
-	aesgcm, err := cipher.NewGCM(block)
+	ciphertext, err := sealGCM(g.key(salt), make([]byte, NonceSize), []byte(plainText))
 	if err != nil {
 		return "", err
 	}
 
-	plaintext, err := aesgcm.Open(nil, nonce, ciphertext, nil)
+	return DefaultStringCodec.EncodeToString(append(salt, ciphertext...)), nil
+}
+
+// Decrypt decrypts the given ciphertext using GCM with a key derived with
+// the prepended salt.
+// The ciphertext must be a [DefaultStringCodec] string.
+func (g *gcmAutoSalt) Decrypt(cipherText string) (plainText string, err error) {
+	defer wrapOpError(&err, decrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	ciphertext, err := decodeCipherText(cipherText)
+	if err != nil {
+		return "", err
+	}
+
+	if len(ciphertext) < autoSaltSize+gcmTagSize {
+		return "", ErrCipherTextTooShort
+	}
+
+	salt, ciphertext := ciphertext[:autoSaltSize], ciphertext[autoSaltSize:]
+
+	plaintext, err := openGCM(g.key(salt), make([]byte, NonceSize), ciphertext)
 	if err != nil {
 		return "", err
 	}
@@ -109,6 +1204,87 @@ func (g *gcm) Decrypt(cipherText string) (plainText string, err error) {
 	return string(plaintext), nil
 }
 
+// aead returns the [cipher.AEAD] and the fixed nonce (nil with RandomNonce).
+func (g *gcm) aead() (cipher.AEAD, []byte, error) {
+	state, err := g.state()
+	if err != nil {
+		return nil, nil, err
+	}
+	return state.aesgcm, state.nonce, nil
+}
+
+// state returns the memoized [gcmState], building it on first use.
+//
+// A failed build is not memoized, so the next call retries it.
+// Keys fetched per operation ([KeyFunc]) are never memoized, but fetched
+// again by every call.
+func (g *gcm) state() (*gcmState, error) {
+	if state := g.built.Load(); state != nil {
+		return state, nil
+	}
+
+	if isKeyFunc(g.cfg.Key) || isKeyFunc(g.cfg.Nonce) {
+		return g.newState()
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if state := g.built.Load(); state != nil {
+		return state, nil
+	}
+
+	state, err := g.newState()
+	if err != nil {
+		return nil, err
+	}
+	g.built.Store(state)
+
+	return state, nil
+}
+
+// newState creates the underlying [cipher.AEAD] from the configuration,
+// and checks the nonce against the configured nonce size.
+func (g *gcm) newState() (state *gcmState, err error) {
+	defer recoverFromPanic(&err)
+
+	nonceSize, tagSize, err := g.cfg.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	var nonce []byte
+	if !g.cfg.RandomNonce {
+		nonce = g.cfg.Nonce.Bytes()
+		if len(nonce) != nonceSize {
+			return nil, fmt.Errorf("%w: got %d bytes, want %d", ErrNonceSize, len(nonce), nonceSize)
+		}
+	}
+
+	key := g.cfg.Key.Bytes()
+
+	block, err := newAesBlock(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var aesgcm cipher.AEAD
+	switch {
+	case nonceSize != gcmStandardNonceSize:
+		aesgcm, err = cipher.NewGCMWithNonceSize(block, nonceSize)
+	case tagSize != gcmStandardTagSize:
+		aesgcm, err = cipher.NewGCMWithTagSize(block, tagSize)
+	default:
+		aesgcm, err = cipher.NewGCM(block)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &gcmState{aesgcm: aesgcm, nonce: nonce, keyID: sha256.Sum256(key)}, nil
+}
+
 // recoverFromPanic recovers from a panic and sets the error to the given pointer.
 func recoverFromPanic(err *error) {
 	if r := recover(); r != nil {
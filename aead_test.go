@@ -1,8 +1,16 @@
 package simplecipher
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"strings"
+	"sync"
 	"testing"
+	"testing/iotest"
 	"time"
 )
 
@@ -42,6 +50,701 @@ func FuzzSimpleGCM(f *testing.F) {
 	})
 }
 
+func TestGCM_Decrypt_tampered(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	cipher := SimpleGCM("key", "nonce")
+
+	ciphertext, err := cipher.Encrypt("plaintext")
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+
+	raw, err := DefaultStringCodec.DecodeString(ciphertext)
+	if err != nil {
+		t.Fatalf("DecodeString error: %v", err)
+	}
+	raw[0] ^= 0x01
+	tampered := DefaultStringCodec.EncodeToString(raw)
+
+	_, err = cipher.Decrypt(tampered)
+	if !errors.Is(err, ErrAuthenticationFailed) {
+		t.Fatalf("Decrypt(tampered) error = %v, want %v", err, ErrAuthenticationFailed)
+	}
+}
+
+func TestNewGCMWithNonceSize(t *testing.T) {
+	key := []byte("key0key1key2key3key4key5key6key7")
+
+	tests := []struct {
+		name      string
+		nonce     []byte
+		nonceSize int
+		wantErr   bool
+	}{
+		{
+			name:      "8-byte",
+			nonce:     []byte("nonce-08"),
+			nonceSize: 8,
+		},
+		{
+			name:      "16-byte",
+			nonce:     []byte("nonce-0016-bytes"),
+			nonceSize: 16,
+		},
+		{
+			name:      "mismatch",
+			nonce:     []byte("nonce0nonce1"),
+			nonceSize: 16,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			createGCM := func() Cipher {
+				return NewGCMWithNonceSize(Bytes(key), Bytes(tt.nonce), tt.nonceSize)
+			}
+
+			if !tt.wantErr {
+				testCipher(tt.name, t, createGCM, "plaintext")
+				return
+			}
+
+			_, err := createGCM().Encrypt("plaintext")
+			if !errors.Is(err, ErrNonceSize) {
+				t.Errorf("Encrypt error = %v, want %v", err, ErrNonceSize)
+			}
+		})
+	}
+}
+
+func TestGCM_MaxPlaintextSize(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	MaxPlaintextSize = 64
+	defer func() { MaxPlaintextSize = gcmMaxPlaintextSize }()
+
+	cipher := SimpleGCM("key", "nonce")
+
+	testCipher("atLimit", t, func() Cipher { return cipher }, strings.Repeat("a", 64))
+
+	_, err := cipher.Encrypt(strings.Repeat("a", 65))
+	if !errors.Is(err, ErrPlaintextTooLarge) {
+		t.Errorf("Encrypt(above limit) error = %v, want %v", err, ErrPlaintextTooLarge)
+	}
+
+	MaxPlaintextSize = gcmMaxPlaintextSize
+	ciphertext, err := cipher.Encrypt(strings.Repeat("a", 65))
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+
+	MaxPlaintextSize = 64
+	_, err = cipher.Decrypt(ciphertext)
+	if !errors.Is(err, ErrPlaintextTooLarge) {
+		t.Errorf("Decrypt(above limit) error = %v, want %v", err, ErrPlaintextTooLarge)
+	}
+}
+
+func TestNewGCMWithConfig(t *testing.T) {
+	key := String("key0key1key2key3key4key5key6key7")
+
+	tests := []struct {
+		name    string
+		cfg     GCMConfig
+		wantErr error
+	}{
+		{
+			name: "default",
+			cfg:  GCMConfig{Key: key, Nonce: String("nonce0nonce1")},
+		},
+		{
+			name: "nonce16",
+			cfg:  GCMConfig{Key: key, Nonce: String("nonce-0016-bytes"), NonceSize: 16},
+		},
+		{
+			name: "nonce8",
+			cfg:  GCMConfig{Key: key, Nonce: String("nonce-08"), NonceSize: 8},
+		},
+		{
+			name: "tag12",
+			cfg:  GCMConfig{Key: key, Nonce: String("nonce0nonce1"), TagSize: 12},
+		},
+		{
+			name: "randomNonce",
+			cfg:  GCMConfig{Key: key, RandomNonce: true},
+		},
+		{
+			name: "randomNonce16_tag16",
+			cfg:  GCMConfig{Key: key, RandomNonce: true, NonceSize: 16, TagSize: 16},
+		},
+		{
+			name:    "nonceAndTagSize",
+			cfg:     GCMConfig{Key: key, Nonce: String("nonce-08"), NonceSize: 8, TagSize: 12},
+			wantErr: ErrInvalidConfig,
+		},
+		{
+			name:    "tagTooShort",
+			cfg:     GCMConfig{Key: key, Nonce: String("nonce0nonce1"), TagSize: 8},
+			wantErr: ErrInvalidConfig,
+		},
+		{
+			name:    "nonceWithRandomNonce",
+			cfg:     GCMConfig{Key: key, Nonce: String("nonce0nonce1"), RandomNonce: true},
+			wantErr: ErrInvalidConfig,
+		},
+		{
+			name:    "noNonce",
+			cfg:     GCMConfig{Key: key},
+			wantErr: ErrInvalidConfig,
+		},
+		{
+			name:    "nonceSizeMismatch",
+			cfg:     GCMConfig{Key: key, Nonce: String("nonce0nonce1"), NonceSize: 16},
+			wantErr: ErrNonceSize,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			createGCM := func() Cipher {
+				return NewGCMWithConfig(tt.cfg)
+			}
+
+			if tt.wantErr == nil {
+				if err := ValidateCipher(createGCM()); err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				testCipher(tt.name, t, createGCM, "plaintext")
+				return
+			}
+
+			if err := ValidateCipher(createGCM()); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Validate() = %v, want %v", err, tt.wantErr)
+			}
+			if _, err := createGCM().Encrypt("plaintext"); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Encrypt error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGCM_Detached(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	cipher := SimpleGCM("key", "nonce").(DetachedCipher)
+
+	ciphertext1, tag1, err := cipher.EncryptDetached("plaintext 1")
+	if err != nil {
+		t.Fatalf("EncryptDetached error: %v", err)
+	}
+	ciphertext2, tag2, err := cipher.EncryptDetached("plaintext 2")
+	if err != nil {
+		t.Fatalf("EncryptDetached error: %v", err)
+	}
+
+	rawTag, err := DefaultStringCodec.DecodeString(tag1)
+	if err != nil {
+		t.Fatalf("DecodeString(tag) error: %v", err)
+	}
+	if len(rawTag) != 16 {
+		t.Errorf("len(tag) = %v, want 16", len(rawTag))
+	}
+
+	decrypted, err := cipher.DecryptDetached(ciphertext1, tag1)
+	if err != nil {
+		t.Fatalf("DecryptDetached error: %v", err)
+	}
+	if decrypted != "plaintext 1" {
+		t.Errorf("decrypted (%s) != plaintext (%s)", decrypted, "plaintext 1")
+	}
+
+	// attached & detached are interchangeable
+	decrypted, err = cipher.Decrypt(ciphertext2 + tag2)
+	if err != nil || decrypted != "plaintext 2" {
+		t.Errorf("Decrypt(ciphertext + tag) = (%s, %v), want (%s, nil)", decrypted, err, "plaintext 2")
+	}
+
+	_, err = cipher.DecryptDetached(ciphertext1, tag2)
+	if !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("DecryptDetached(swapped tag) error = %v, want %v", err, ErrAuthenticationFailed)
+	}
+}
+
+func TestGCM_WithHeader(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	cipher := NewGCMWithConfig(GCMConfig{Key: NewAesKey("key"), RandomNonce: true}).(HeaderCipher)
+	header := []byte("route=eu-west-1")
+
+	ciphertext, err := cipher.EncryptWithHeader(header, "plaintext")
+	if err != nil {
+		t.Fatalf("EncryptWithHeader error: %v", err)
+	}
+
+	raw, _ := DefaultStringCodec.DecodeString(ciphertext)
+	if !bytes.Equal(raw[1:1+len(header)], header) {
+		t.Errorf("cleartext header = %q, want %q", raw[1:1+len(header)], header)
+	}
+
+	gotHeader, decrypted, err := cipher.DecryptWithHeader(ciphertext)
+	if err != nil {
+		t.Fatalf("DecryptWithHeader error: %v", err)
+	}
+	if !bytes.Equal(gotHeader, header) || decrypted != "plaintext" {
+		t.Errorf("DecryptWithHeader = (%q, %s), want (%q, %s)", gotHeader, decrypted, header, "plaintext")
+	}
+
+	for i := 0; i <= len(header); i++ {
+		tampered := bytes.Clone(raw)
+		tampered[i] ^= 1
+		_, _, err = cipher.DecryptWithHeader(DefaultStringCodec.EncodeToString(tampered))
+		if !errors.Is(err, ErrAuthenticationFailed) && !errors.Is(err, ErrCipherTextTooShort) {
+			t.Errorf("DecryptWithHeader(flipped byte %d) error = %v, want %v", i, err, ErrAuthenticationFailed)
+		}
+	}
+
+	_, err = cipher.EncryptWithHeader(make([]byte, MaxHeaderSize+1), "plaintext")
+	if err == nil {
+		t.Errorf("EncryptWithHeader(oversized header) error = nil, want non-nil")
+	}
+}
+
+func TestGCM_Verify(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	ciphers := map[string]Cipher{
+		"SimpleGCM":   SimpleGCM("key", "nonce"),
+		"RandomNonce": NewGCMWithConfig(GCMConfig{Key: NewAesKey("key"), RandomNonce: true}),
+	}
+	for name, c := range ciphers {
+		t.Run(name, func(t *testing.T) {
+			v := c.(Verifier)
+
+			ciphertext, err := c.Encrypt("plaintext")
+			if err != nil {
+				t.Fatalf("Encrypt error: %v", err)
+			}
+			if err := v.Verify(ciphertext); err != nil {
+				t.Errorf("Verify() error = %v, want nil", err)
+			}
+
+			raw, _ := DefaultStringCodec.DecodeString(ciphertext)
+			raw[len(raw)-1] ^= 1
+			if err := v.Verify(DefaultStringCodec.EncodeToString(raw)); !errors.Is(err, ErrAuthenticationFailed) {
+				t.Errorf("Verify(tampered) error = %v, want %v", err, ErrAuthenticationFailed)
+			}
+
+			if err := v.Verify("not hex"); !errors.Is(err, ErrMalformedCiphertext) {
+				t.Errorf("Verify(malformed) error = %v, want %v", err, ErrMalformedCiphertext)
+			}
+		})
+	}
+}
+
+func TestGCM_AADReader(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	cipher := NewGCMWithConfig(GCMConfig{Key: NewAesKey("key"), RandomNonce: true}).(AADReaderCipher)
+	aad := bytes.Repeat([]byte("header"), 1<<20) // 6 MiB
+
+	ciphertext, err := cipher.EncryptWithAADReader(bytes.NewReader(aad), "plaintext")
+	if err != nil {
+		t.Fatalf("EncryptWithAADReader error: %v", err)
+	}
+
+	decrypted, err := cipher.DecryptWithAADReader(bytes.NewReader(aad), ciphertext)
+	if err != nil || decrypted != "plaintext" {
+		t.Errorf("DecryptWithAADReader() = %q, %v, want %q", decrypted, err, "plaintext")
+	}
+
+	tampered := bytes.Clone(aad)
+	tampered[len(tampered)/2] ^= 1
+	for name, aad := range map[string][]byte{"tampered": tampered, "truncated": aad[:len(aad)-1], "empty": nil} {
+		_, err := cipher.DecryptWithAADReader(bytes.NewReader(aad), ciphertext)
+		if !errors.Is(err, ErrAuthenticationFailed) {
+			t.Errorf("DecryptWithAADReader(%s aad) error = %v, want %v", name, err, ErrAuthenticationFailed)
+		}
+	}
+
+	if _, err := cipher.Decrypt(ciphertext); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("Decrypt() error = %v, want %v without the aad", err, ErrAuthenticationFailed)
+	}
+
+	_, err = cipher.EncryptWithAADReader(iotest.ErrReader(errors.New("read error")), "plaintext")
+	if !errors.Is(err, ErrCopy) {
+		t.Errorf("EncryptWithAADReader(failing reader) error = %v, want %v", err, ErrCopy)
+	}
+}
+
+func TestGCM_Append(t *testing.T) {
+	key := Bytes([]byte("key0key1key2key3"))
+
+	ciphers := map[string]Cipher{
+		"fixedNonce":  NewGCM(key, Bytes([]byte("nonce0nonce1"))),
+		"randomNonce": NewGCMWithConfig(GCMConfig{Key: key, RandomNonce: true}),
+	}
+	for name, c := range ciphers {
+		t.Run(name, func(t *testing.T) {
+			cipher := c.(AppendCipher)
+
+			prefix := []byte("prefix:")
+			ciphertext, err := cipher.EncryptAppend(bytes.Clone(prefix), []byte("plaintext"))
+			if err != nil {
+				t.Fatalf("EncryptAppend() error = %v", err)
+			}
+			if !bytes.HasPrefix(ciphertext, prefix) {
+				t.Fatalf("EncryptAppend() = %q, want prefix %q", ciphertext, prefix)
+			}
+			ciphertext = ciphertext[len(prefix):]
+
+			plaintext, err := cipher.DecryptAppend(bytes.Clone(prefix), ciphertext)
+			if err != nil || string(plaintext) != "prefix:plaintext" {
+				t.Errorf("DecryptAppend() = %q, %v, want %q", plaintext, err, "prefix:plaintext")
+			}
+
+			// interoperable with the string API
+			decrypted, err := cipher.Decrypt(DefaultStringCodec.EncodeToString(ciphertext))
+			if err != nil || decrypted != "plaintext" {
+				t.Errorf("Decrypt(EncryptAppend()) = %q, %v, want %q", decrypted, err, "plaintext")
+			}
+			encrypted, _ := cipher.Encrypt("plaintext")
+			raw, _ := DefaultStringCodec.DecodeString(encrypted)
+			plaintext, err = cipher.DecryptAppend(nil, raw)
+			if err != nil || string(plaintext) != "plaintext" {
+				t.Errorf("DecryptAppend(Encrypt()) = %q, %v, want %q", plaintext, err, "plaintext")
+			}
+
+			// buffers are reused
+			buf := make([]byte, 0, 1024)
+			out, _ := cipher.EncryptAppend(buf, []byte("plaintext"))
+			if &out[0] != &buf[:1][0] {
+				t.Errorf("EncryptAppend() did not reuse the buffer")
+			}
+
+			ciphertext[len(ciphertext)-1] ^= 1
+			out, err = cipher.DecryptAppend(prefix, ciphertext)
+			if !errors.Is(err, ErrAuthenticationFailed) {
+				t.Errorf("DecryptAppend(tampered) error = %v, want %v", err, ErrAuthenticationFailed)
+			}
+			if !bytes.Equal(out, prefix) {
+				t.Errorf("DecryptAppend(tampered) = %q, want dst %q", out, prefix)
+			}
+		})
+	}
+}
+
+func TestNewGCMWithAADFunc(t *testing.T) {
+	key := Bytes([]byte("key0key1key2key3"))
+
+	t.Run("fixed", func(t *testing.T) {
+		createGCM := func() Cipher {
+			return NewGCMWithAADFunc(key, func() []byte { return []byte("tenant-1") })
+		}
+		testCipher("", t, createGCM, "plaintext")
+
+		ciphertext, _ := createGCM().Encrypt("plaintext")
+		_, err := NewGCMWithAADFunc(key, func() []byte { return []byte("tenant-2") }).Decrypt(ciphertext)
+		if !errors.Is(err, ErrAuthenticationFailed) {
+			t.Errorf("Decrypt(other AAD) error = %v, want %v", err, ErrAuthenticationFailed)
+		}
+		_, err = NewGCMWithConfig(GCMConfig{Key: key, RandomNonce: true}).Decrypt(ciphertext)
+		if !errors.Is(err, ErrAuthenticationFailed) {
+			t.Errorf("Decrypt(no AAD) error = %v, want %v", err, ErrAuthenticationFailed)
+		}
+	})
+
+	// the AAD must be stable between Encrypt and Decrypt:
+	// a function returning a new value on every call breaks decryption.
+	t.Run("changing", func(t *testing.T) {
+		calls := 0
+		cipher := NewGCMWithAADFunc(key, func() []byte {
+			calls++
+			return []byte(fmt.Sprint("request-", calls))
+		})
+
+		ciphertext, err := cipher.Encrypt("plaintext")
+		if err != nil {
+			t.Fatalf("Encrypt() error = %v", err)
+		}
+		_, err = cipher.Decrypt(ciphertext)
+		if !errors.Is(err, ErrAuthenticationFailed) {
+			t.Errorf("Decrypt(changed AAD) error = %v, want %v", err, ErrAuthenticationFailed)
+		}
+		if calls != 2 {
+			t.Errorf("AADFunc called %d times, want once per Encrypt and Decrypt", calls)
+		}
+	})
+
+	t.Run("header", func(t *testing.T) {
+		cipher := NewGCMWithAADFunc(key, func() []byte { return []byte("tenant-1") }).(HeaderCipher)
+
+		ciphertext, _ := cipher.EncryptWithHeader([]byte("header"), "plaintext")
+		header, plaintext, err := cipher.DecryptWithHeader(ciphertext)
+		if err != nil || string(header) != "header" || plaintext != "plaintext" {
+			t.Errorf("DecryptWithHeader() = %q, %q, %v, want %q, %q", header, plaintext, err, "header", "plaintext")
+		}
+	})
+}
+
+func TestNewGCMVersioned(t *testing.T) {
+	key := Bytes([]byte("key0key1key2key3"))
+	createGCM := func() Cipher {
+		return NewGCMVersioned(key)
+	}
+
+	testCipher("", t, createGCM, "plaintext")
+
+	ciphertext, _ := createGCM().Encrypt("plaintext")
+	raw, _ := DefaultStringCodec.DecodeString(ciphertext)
+	if raw[0] != 1 {
+		t.Errorf("Encrypt() version = %d, want 1", raw[0])
+	}
+
+	raw[0]++
+	_, err := createGCM().Decrypt(DefaultStringCodec.EncodeToString(raw))
+	if !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Decrypt(version 2) error = %v, want %v", err, ErrUnsupportedVersion)
+	}
+
+	_, err = createGCM().Decrypt("")
+	if !errors.Is(err, ErrCipherTextTooShort) {
+		t.Errorf("Decrypt(empty) error = %v, want %v", err, ErrCipherTextTooShort)
+	}
+}
+
+func TestNewGCMSynthNonce(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	createGCM := func() Cipher {
+		return NewGCMSynthNonce(NewAesKey("key"))
+	}
+
+	testCipher("", t, createGCM, "plaintext")
+
+	cipher := createGCM()
+
+	ciphertext1, _ := cipher.Encrypt("plaintext")
+	ciphertext2, _ := createGCM().Encrypt("plaintext")
+	if ciphertext1 != ciphertext2 {
+		t.Errorf("Encrypt(same plaintext) = %s and %s, want identical", ciphertext1, ciphertext2)
+	}
+
+	ciphertext3, _ := cipher.Encrypt("another plaintext")
+	if ciphertext1[:2*NonceSize] == ciphertext3[:2*NonceSize] {
+		t.Errorf("Encrypt(different plaintext) reused nonce %s", ciphertext1[:2*NonceSize])
+	}
+
+	_, err := NewGCMSynthNonce(NewAesKey("another key")).Decrypt(ciphertext1)
+	if !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("Decrypt(wrong key) error = %v, want %v", err, ErrAuthenticationFailed)
+	}
+}
+
+func TestNewGCMCounter(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	key := NewAesKey("key")
+
+	testCipher("", t, func() Cipher { return NewGCMCounter(key, 0) }, "plaintext")
+
+	c := NewGCMCounter(key, 41)
+
+	for want := uint64(41); want < 45; want++ {
+		ciphertext, err := c.Encrypt("plaintext")
+		if err != nil {
+			t.Fatalf("Encrypt error: %v", err)
+		}
+
+		raw, _ := DefaultStringCodec.DecodeString(ciphertext)
+		if counter := binary.BigEndian.Uint64(raw); counter != want {
+			t.Errorf("nonce counter = %d, want %d", counter, want)
+		}
+		if tail := raw[gcmCounterSize:NonceSize]; !bytes.Equal(tail, make([]byte, NonceSize-gcmCounterSize)) {
+			t.Errorf("nonce tail = %x, want zeros", tail)
+		}
+
+		// decrypted by another instance, regardless of its counter
+		decrypted, err := NewGCMCounter(key, 0).Decrypt(ciphertext)
+		if err != nil || decrypted != "plaintext" {
+			t.Errorf("Decrypt() = %s, %v, want %s", decrypted, err, "plaintext")
+		}
+	}
+
+	// the counter does not wrap around
+	last := NewGCMCounter(key, math.MaxUint64)
+	if _, err := last.Encrypt("plaintext"); err != nil {
+		t.Fatalf("Encrypt(last counter) error: %v", err)
+	}
+	if _, err := last.Encrypt("plaintext"); !errors.Is(err, ErrNonceReused) {
+		t.Errorf("Encrypt(exhausted) error = %v, want %v", err, ErrNonceReused)
+	}
+}
+
+func TestSimpleGCMAutoSalt(t *testing.T) {
+	createGCM := func() Cipher {
+		return SimpleGCMAutoSalt("passphrase")
+	}
+
+	testCipher("", t, createGCM, "plaintext")
+
+	cipher := createGCM()
+
+	ciphertext1, _ := cipher.Encrypt("plaintext")
+	ciphertext2, _ := cipher.Encrypt("plaintext")
+	if ciphertext1[:2*autoSaltSize] == ciphertext2[:2*autoSaltSize] {
+		t.Errorf("Encrypt() reused salt %s", ciphertext1[:2*autoSaltSize])
+	}
+
+	for _, ciphertext := range []string{ciphertext1, ciphertext2} {
+		// a new cipher has no state but the passphrase
+		plaintext, err := createGCM().Decrypt(ciphertext)
+		if err != nil || plaintext != "plaintext" {
+			t.Errorf("Decrypt(%s) = %q, %v, want %q", ciphertext, plaintext, err, "plaintext")
+		}
+	}
+
+	_, err := SimpleGCMAutoSalt("another passphrase").Decrypt(ciphertext1)
+	if !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("Decrypt(wrong passphrase) error = %v, want %v", err, ErrAuthenticationFailed)
+	}
+
+	_, err = cipher.Decrypt(ciphertext1[:2*autoSaltSize])
+	if !errors.Is(err, ErrCipherTextTooShort) {
+		t.Errorf("Decrypt(salt only) error = %v, want %v", err, ErrCipherTextTooShort)
+	}
+}
+
+func FuzzNewGCMSynthNonce(f *testing.F) {
+	// key: bytes, plaintext: string
+	f.Add([]byte("key0key1key2key3"), "plain-text-plain-text000")
+	f.Add([]byte("badkey"), "plain-text")
+
+	f.Fuzz(func(t *testing.T, key []byte, plaintext string) {
+		createGCM := func() Cipher {
+			return NewGCMSynthNonce(Bytes(key))
+		}
+
+		if len(key) != 16 && len(key) != 24 && len(key) != 32 {
+			testErrorCipher("badKeyLen", t, createGCM, plaintext)
+			return
+		}
+
+		testCipher("", t, createGCM, plaintext)
+	})
+}
+
+func TestGCM_concurrent(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	cipher := SimpleGCM("key", "nonce")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			plaintext := fmt.Sprint("plaintext-", i)
+
+			ciphertext, err := cipher.Encrypt(plaintext)
+			if err != nil {
+				t.Errorf("Encrypt error: %v", err)
+				return
+			}
+
+			decrypted, err := cipher.Decrypt(ciphertext)
+			if err != nil {
+				t.Errorf("Decrypt error: %v", err)
+				return
+			}
+			if decrypted != plaintext {
+				t.Errorf("decrypted (%s) != plaintext (%s)", decrypted, plaintext)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// flakyKey is a Key failing (returning nil) for its first fails calls.
+type flakyKey struct {
+	key   []byte
+	fails int
+	calls int
+}
+
+func (k *flakyKey) Bytes() []byte {
+	k.calls++
+	if k.calls <= k.fails {
+		return nil
+	}
+	return k.key
+}
+
+func TestGCM_retryAfterError(t *testing.T) {
+	key := &flakyKey{key: []byte("key0key1key2key3"), fails: 1}
+	cipher := NewGCMWithConfig(GCMConfig{Key: key, RandomNonce: true})
+
+	if _, err := cipher.Encrypt("plaintext"); !errors.Is(err, ErrEmptyKey) {
+		t.Fatalf("Encrypt() with a failing key error = %v, want %v", err, ErrEmptyKey)
+	}
+
+	// the failure is not memoized: the next call fetches the key again
+	ciphertext, err := cipher.Encrypt("plaintext")
+	if err != nil {
+		t.Fatalf("Encrypt() after the failure error = %v, want nil", err)
+	}
+	if plaintext, err := cipher.Decrypt(ciphertext); err != nil || plaintext != "plaintext" {
+		t.Errorf("Decrypt() = %q, %v, want %q", plaintext, err, "plaintext")
+	}
+
+	// the success is memoized
+	if key.calls != 2 {
+		t.Errorf("key fetched %d times, want 2", key.calls)
+	}
+}
+
+// BenchmarkSimpleGCM_Encrypt measures the cost of repeated encryption
+// with a reused cipher. Run with -benchmem to see the allocations.
+func BenchmarkSimpleGCM_Encrypt(b *testing.B) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	cipher := SimpleGCM("key", "nonce")
+	plaintext := strings.Repeat("plaintext", 100)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := cipher.Encrypt(plaintext); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkGCM_EncryptAppend measures encryption reusing the buffers.
+// Run with -benchmem to see that it does not allocate.
+func BenchmarkGCM_EncryptAppend(b *testing.B) {
+	cipher := NewGCM(Bytes([]byte("key0key1key2key3")), Bytes([]byte("nonce0nonce1"))).(AppendCipher)
+	plaintext := []byte(strings.Repeat("plaintext", 100))
+
+	ciphertext := make([]byte, 0, 2*len(plaintext))
+	decrypted := make([]byte, 0, 2*len(plaintext))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var err error
+		ciphertext, err = cipher.EncryptAppend(ciphertext[:0], plaintext)
+		if err != nil {
+			b.Fatal(err)
+		}
+		decrypted, err = cipher.DecryptAppend(decrypted[:0], ciphertext)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func ExampleSimpleGCM() {
 	DefaultSalt = func() string { return "NaCl" }
 
@@ -60,3 +763,138 @@ func ExampleSimpleGCM() {
 
 	// Output: Hello, World!
 }
+
+// errTest is the error returned by errCipher.
+var errTest = errors.New("test error")
+
+// errCipher is a [Cipher] failing with err, without the operation context.
+type errCipher struct{ err error }
+
+func (c errCipher) Encrypt(string) (string, error) { return "", c.err }
+func (c errCipher) Decrypt(string) (string, error) { return "", c.err }
+
+func TestErrorOperationContext(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	key := NewAesKey("key")
+	badKey := Bytes([]byte("short"))
+
+	gcm := SimpleGCM("key", "nonce")
+	ciphertext, err := gcm.Encrypt("hello")
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+	tampered := ciphertext[:len(ciphertext)-2] + "00"
+	if tampered == ciphertext {
+		tampered = ciphertext[:len(ciphertext)-2] + "11"
+	}
+
+	tests := []struct {
+		name    string
+		do      func() error
+		msg     string
+		wantErr error
+	}{
+		{"GCM decrypt", func() error {
+			_, err := gcm.Decrypt(tampered)
+			return err
+		}, "simplecipher decrypt (GCM): ", ErrAuthenticationFailed},
+		{"GCM encrypt", func() error {
+			_, err := NewGCM(badKey, NewNonce("nonce")).Encrypt("hello")
+			return err
+		}, "simplecipher encrypt (GCM): ", nil},
+		{"CBC encrypt", func() error {
+			_, err := NewCBC(key, NewIv("iv")).Encrypt("not a block")
+			return err
+		}, "simplecipher encrypt (CBC): ", ErrPlaintextBlockSize},
+		{"CTR stream decrypt", func() error {
+			return NewCTRStream(key, NewIv("iv")).DecryptStream(strings.NewReader("short"), new(bytes.Buffer))
+		}, "simplecipher decrypt (CTR): ", ErrCopy},
+		{"decorator", func() error {
+			_, err := WithUTF8Validation(gcm).Decrypt(tampered)
+			return err
+		}, "simplecipher decrypt (GCM): ", ErrAuthenticationFailed},
+		{"decorator own error", func() error {
+			ct, err := gcm.Encrypt("\xff")
+			if err != nil {
+				return err
+			}
+			_, err = WithUTF8Validation(gcm).Decrypt(ct)
+			return err
+		}, "simplecipher decrypt (GCM): ", ErrInvalidUTF8},
+		{"max length passthrough", func() error {
+			_, err := WithMaxCiphertextLen(errCipher{errTest}, 100).Encrypt("hello")
+			return err
+		}, "simplecipher encrypt: ", errTest},
+		{"UTF-8 passthrough", func() error {
+			_, err := WithUTF8Validation(errCipher{errTest}).Encrypt("hello")
+			return err
+		}, "simplecipher encrypt: ", errTest},
+		{"rate limit passthrough", func() error {
+			_, err := WithDecryptRateLimit(errCipher{errTest}, 1).Encrypt("hello")
+			return err
+		}, "simplecipher encrypt: ", errTest},
+		{"keyring", func() error {
+			_, err := NewKeyring(key).Decrypt("not hex")
+			return err
+		}, "simplecipher decrypt (GCM): ", ErrMalformedCiphertext},
+		{"block from stream", func() error {
+			_, err := BlockFromStream(NewCTRStream(key, NewIv("iv"))).Decrypt("not hex")
+			return err
+		}, "simplecipher decrypt (CTR): ", ErrMalformedCiphertext},
+		{"stream from block", func() error {
+			return StreamFromBlock(gcm).DecryptStream(iotest.ErrReader(errTest), new(bytes.Buffer))
+		}, "simplecipher decrypt (GCM): ", errTest},
+		{"EncryptMap", func() error {
+			_, err := EncryptMap(errCipher{errTest}, map[string]string{"a": "1", "b": "2"})
+			return err
+		}, "simplecipher encrypt: ", errTest},
+		{"DecryptMap", func() error {
+			_, err := DecryptMap(gcm, map[string]string{"a": tampered, "b": tampered})
+			return err
+		}, "simplecipher decrypt (GCM): ", ErrAuthenticationFailed},
+		{"KeyFromEnv", func() error {
+			t.Setenv("SIMPLECIPHER_TEST_MISSING_KEY", "")
+			_, err := KeyFromEnv("SIMPLECIPHER_TEST_MISSING_KEY", Aes256)
+			return err
+		}, "simplecipher load key: ", ErrEnvVarMissing},
+		{"CTR read seeker Read", func() error {
+			src := struct {
+				io.Reader
+				io.Seeker
+			}{iotest.ErrReader(errTest), strings.NewReader("")}
+			r, err := NewCTRReadSeeker(key, NewIv("iv"), src)
+			if err != nil {
+				return err
+			}
+			_, err = r.Read(make([]byte, 8))
+			return err
+		}, "simplecipher decrypt (CTR): ", errTest},
+		{"CTR read seeker Seek", func() error {
+			r, err := NewCTRReadSeeker(key, NewIv("iv"), strings.NewReader(""))
+			if err != nil {
+				return err
+			}
+			_, err = r.Seek(-1, io.SeekStart)
+			return err
+		}, "simplecipher decrypt (CTR): ", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.do()
+			if err == nil {
+				t.Fatalf("error = nil, want an error")
+			}
+			if !strings.HasPrefix(err.Error(), tt.msg) {
+				t.Errorf("error = %q, want prefix %q", err, tt.msg)
+			}
+			if strings.Count(err.Error(), "simplecipher ") != 1 {
+				t.Errorf("error = %q, want the context once", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("errors.Is(%v, %v) = false, want true", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package simplecipher
+
+// This file provides helpers to decrypt batches of ciphertexts, e.g., the
+// encrypted column of a table.
+
+// DecryptAllPartial decrypts each ciphertext with the cipher, carrying on
+// after failures, so that one corrupt record does not abort the whole batch.
+//
+// The results and the errs are both as long as the ciphertexts, and indexed
+// alike: for each i, either errs[i] is nil and results[i] is the plaintext,
+// or errs[i] is the error of the decryption and results[i] is empty.
+//
+//	results, errs := simplecipher.DecryptAllPartial(cipher, ciphertexts)
+//	for i, err := range errs {
+//		if err != nil {
+//			log.Printf("record %d: %v", i, err)
+//			continue
+//		}
+//		use(results[i])
+//	}
+func DecryptAllPartial(c Cipher, ciphertexts []string) (results []string, errs []error) {
+	results = make([]string, len(ciphertexts))
+	errs = make([]error, len(ciphertexts))
+
+	for i, cipherText := range ciphertexts {
+		results[i], errs[i] = c.Decrypt(cipherText)
+		if errs[i] != nil {
+			results[i] = ""
+		}
+	}
+
+	return results, errs
+}
@@ -0,0 +1,59 @@
+package simplecipher
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDecryptAllPartial(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	c := NewGCMWithConfig(GCMConfig{Key: NewAesKey("passphrase"), RandomNonce: true})
+
+	valid := func(plaintext string) string {
+		cipherText, err := c.Encrypt(plaintext)
+		if err != nil {
+			t.Fatalf("Encrypt error: %v", err)
+		}
+		return cipherText
+	}
+	tampered := []byte(valid("tampered"))
+	if tampered[len(tampered)-1] == '0' {
+		tampered[len(tampered)-1] = '1'
+	} else {
+		tampered[len(tampered)-1] = '0'
+	}
+
+	ciphertexts := []string{
+		valid("a"),
+		"not hex",
+		valid("b"),
+		string(tampered),
+		valid(""),
+		"",
+	}
+	wantResults := []string{"a", "", "b", "", "", ""}
+	wantErrs := []error{nil, ErrMalformedCiphertext, nil, ErrAuthenticationFailed, nil, ErrCipherTextTooShort}
+
+	results, errs := DecryptAllPartial(c, ciphertexts)
+	if len(results) != len(ciphertexts) || len(errs) != len(ciphertexts) {
+		t.Fatalf("DecryptAllPartial() = %d results, %d errs, want %d", len(results), len(errs), len(ciphertexts))
+	}
+
+	for i := range ciphertexts {
+		if results[i] != wantResults[i] {
+			t.Errorf("results[%d] = %q, want %q", i, results[i], wantResults[i])
+		}
+		if wantErrs[i] == nil && errs[i] != nil {
+			t.Errorf("errs[%d] = %v, want nil", i, errs[i])
+		}
+		if wantErrs[i] != nil && !errors.Is(errs[i], wantErrs[i]) {
+			t.Errorf("errs[%d] = %v, want %v", i, errs[i], wantErrs[i])
+		}
+	}
+
+	results, errs = DecryptAllPartial(c, nil)
+	if len(results) != 0 || len(errs) != 0 {
+		t.Errorf("DecryptAllPartial(nil) = %v, %v, want empty", results, errs)
+	}
+}
@@ -0,0 +1,95 @@
+package simplecipher
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// benchmarkCiphers are the ciphers compared by BenchmarkEncrypt and
+// BenchmarkDecrypt, with raw keys so that the key derivation is not measured
+// (see BenchmarkKeyDerivation for that).
+var benchmarkCiphers = []struct {
+	mode      ModeID
+	newCipher func() Cipher
+}{
+	{ModeCBC, func() Cipher { return NewCBC(benchmarkKey, benchmarkIV) }},
+	{ModeCFB, func() Cipher { return NewCFB(benchmarkKey, benchmarkIV) }},
+	{ModeOFB, func() Cipher { return NewOFB(benchmarkKey, benchmarkIV) }},
+	{ModeCTR, func() Cipher { return NewCTR(benchmarkKey, benchmarkIV) }},
+	{ModeGCM, func() Cipher { return NewGCM(benchmarkKey, Bytes([]byte("nonce0nonce1"))) }},
+}
+
+var (
+	benchmarkKey = Bytes([]byte("key0key1key2key3key4key5key6key7"))
+	benchmarkIV  = Bytes([]byte("iv00iv01iv02iv03"))
+)
+
+// benchmarkSizes are the plaintext sizes, multiples of the block size for CBC.
+var benchmarkSizes = []int{64, 1 << 10, 1 << 20}
+
+// BenchmarkEncrypt measures the encryption throughput (MB/s) and the
+// allocations of each mode, for several plaintext sizes:
+//
+//	go test -run '^$' -bench 'Encrypt|Decrypt' -benchmem
+func BenchmarkEncrypt(b *testing.B) {
+	for _, bc := range benchmarkCiphers {
+		for _, size := range benchmarkSizes {
+			b.Run(fmt.Sprintf("%s/%d", bc.mode, size), func(b *testing.B) {
+				cipher := bc.newCipher()
+				plaintext := strings.Repeat("p", size)
+
+				b.SetBytes(int64(size))
+				b.ReportAllocs()
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					if _, err := cipher.Encrypt(plaintext); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkDecrypt measures the decryption throughput (MB/s) and the
+// allocations of each mode, for several plaintext sizes.
+func BenchmarkDecrypt(b *testing.B) {
+	for _, bc := range benchmarkCiphers {
+		for _, size := range benchmarkSizes {
+			b.Run(fmt.Sprintf("%s/%d", bc.mode, size), func(b *testing.B) {
+				cipher := bc.newCipher()
+				ciphertext, err := cipher.Encrypt(strings.Repeat("p", size))
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				b.SetBytes(int64(size))
+				b.ReportAllocs()
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					if _, err := cipher.Decrypt(ciphertext); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkKeyDerivation measures the cost of deriving a key from a
+// passphrase with scrypt, which the Simple* ciphers pay on every
+// operation unless the key is materialized (see [MaterializeKey]).
+func BenchmarkKeyDerivation(b *testing.B) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if key := NewAesKey("passphrase").Bytes(); len(key) != int(Aes256) {
+			b.Fatalf("len(key) = %d, want %d", len(key), Aes256)
+		}
+	}
+}
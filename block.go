@@ -4,7 +4,10 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"fmt"
 	"github.com/cdfmlr/simplecipher/pkcs7"
+	"io"
+	"slices"
 )
 
 // This file implements AES block cipher modes.
@@ -20,13 +23,49 @@ import (
 //  - https://en.wikipedia.org/wiki/Block_cipher_mode_of_operation#Confidentiality_only_modes
 //  - https://pkg.go.dev/crypto/cipher@go1.23.1#Block
 
+// blockBuilder creates a [cipher.Block] from the key, e.g., [aes.NewCipher].
+type blockBuilder func(key []byte) (cipher.Block, error)
+
+// newAesBlock is the default blockBuilder.
+// An empty key fails with [ErrEmptyKey].
+func newAesBlock(key []byte) (cipher.Block, error) {
+	if len(key) == 0 {
+		return nil, ErrEmptyKey
+	}
+	return aes.NewCipher(key)
+}
+
 // cbc is the AES-CBC cipher mode implementation for the [Cipher] interface.
 type cbc struct {
 	key Key
 	iv  Key
+	// noIVPrepend disables prepending the iv to the ciphertext,
+	// the iv field is used for decryption instead.
+	noIVPrepend bool
+	// newBlock creates the underlying block cipher, nil for AES.
+	newBlock blockBuilder
+	// algorithm is the name of the block cipher, "" for AES.
+	algorithm string
 }
 
-var _ Cipher = (*cbc)(nil)
+// block creates the underlying block cipher from the key.
+func (c *cbc) block(key []byte) (cipher.Block, error) {
+	if len(key) == 0 {
+		return nil, ErrEmptyKey
+	}
+	if c.newBlock == nil {
+		return newAesBlock(key)
+	}
+	return c.newBlock(key)
+}
+
+var (
+	_ Cipher       = (*cbc)(nil)
+	_ RawCipher    = (*cbc)(nil)
+	_ RawCipher    = (*simpleCBC)(nil)
+	_ AppendCipher = (*cbc)(nil)
+	_ AppendCipher = (*simpleCBC)(nil)
+)
 
 // NewCBC creates a new CBC cipher with the given key and iv.
 //
@@ -43,84 +82,245 @@ var _ Cipher = (*cbc)(nil)
 //
 // See also: [cipher.NewCBCDecrypter], [cipher.NewCBCEncrypter] for low-level usage.
 func NewCBC(key, iv Key) Cipher {
-	return &cbc{key: key, iv: iv}
+	return &cbc{key: keyOrEmpty(key), iv: iv}
+}
+
+// NewCBCNoIVPrepend creates a new CBC cipher with the given key and iv,
+// whose ciphertext has exactly the same length as the plaintext.
+//
+// Unlike [NewCBC], the iv is NOT prepended to the ciphertext during
+// encryption, and the given iv is used during decryption.
+// So the caller must store (or be able to reproduce) the iv to decrypt
+// the ciphertext. Never reuse an iv with the same key for different
+// plaintexts.
+//
+// The same requirements on the key, the iv and the plaintext as
+// [NewCBC] apply.
+func NewCBCNoIVPrepend(key, iv Key) Cipher {
+	return &cbc{key: keyOrEmpty(key), iv: iv, noIVPrepend: true}
 }
 
 // Encrypt encrypts the given plaintext using CBC.
 // The ciphertext is returned with [DefaultStringCodec] encoding.
 //
-// The IV will be prepended to the ciphertext as the first block.
+// The IV will be prepended to the ciphertext as the first block,
+// unless created by [NewCBCNoIVPrepend].
 func (c *cbc) Encrypt(plainText string) (cipherText string, err error) {
+	defer wrapOpError(&err, encrypt, ModeCBC)
+	defer recoverFromPanic(&err)
+
+	return encodeCipherText(c.encrypt([]byte(plainText), nil))
+}
+
+// EncryptRaw encrypts the given plaintext using CBC like Encrypt,
+// and returns the raw ciphertext bytes without encoding.
+func (c *cbc) EncryptRaw(plainText string) (cipherText []byte, err error) {
+	defer wrapOpError(&err, encrypt, ModeCBC)
+	defer recoverFromPanic(&err)
+
+	return c.encrypt([]byte(plainText), nil)
+}
+
+// EncryptAppend encrypts the given plaintext using CBC like EncryptRaw,
+// appending the ciphertext to dst.
+func (c *cbc) EncryptAppend(dst, plainText []byte) (_ []byte, err error) {
+	defer wrapOpError(&err, encrypt, ModeCBC)
 	defer recoverFromPanic(&err)
 
-	plaintext := []byte(plainText)
+	ciphertext, err := c.encrypt(plainText, nil)
+	if err != nil {
+		return dst, err
+	}
+	return append(dst, ciphertext...), nil
+}
 
+// encrypt encrypts the plaintext, padding it with pad first if not nil.
+// The padding takes the block size of the underlying block cipher, and is
+// done on a copy of the plaintext, which is zeroed before returning.
+func (c *cbc) encrypt(plaintext []byte, pad func(blockSize int, buf []byte) []byte) ([]byte, error) {
 	key := c.key.Bytes()
 	iv := c.iv.Bytes()
 
+	block, err := c.block(key)
+	if err != nil {
+		return nil, err
+	}
+	blockSize := block.BlockSize()
+
+	if pad != nil {
+		// clipped, so that pad appends to a copy rather than to the
+		// spare capacity of the caller's slice
+		plaintext = pad(blockSize, slices.Clip(plaintext))
+		defer clear(plaintext)
+	}
+
 	// CBC mode works on blocks so plaintexts may need to be padded to the
 	// next whole block. For an example of such padding, see
 	// https://tools.ietf.org/html/rfc5246#section-6.2.3.2. Here we'll
 	// assume that the plaintext is already of the correct length.
-	if len(plaintext)%aes.BlockSize != 0 {
-		return "", ErrPlaintextBlockSize
+	if len(plaintext)%blockSize != 0 {
+		return nil, ErrPlaintextBlockSize
 	}
 
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return "", err
+	// cipher.NewCBCEncrypter panics on a bad iv length
+	if len(iv) != blockSize {
+		return nil, fmt.Errorf("%w: got %d bytes, want %d", ErrIvSize, len(iv), blockSize)
+	}
+
+	if c.noIVPrepend {
+		ciphertext := make([]byte, len(plaintext))
+
+		mode := cipher.NewCBCEncrypter(block, iv)
+		mode.CryptBlocks(ciphertext, plaintext)
+
+		return ciphertext, nil
 	}
 
 	var ciphertext []byte
 
-	ciphertext = make([]byte, aes.BlockSize+len(plaintext))
-	copy(ciphertext[:aes.BlockSize], iv)
+	ciphertext = make([]byte, blockSize+len(plaintext))
+	copy(ciphertext[:blockSize], iv)
 
 	mode := cipher.NewCBCEncrypter(block, iv)
-	mode.CryptBlocks(ciphertext[aes.BlockSize:], plaintext)
+	mode.CryptBlocks(ciphertext[blockSize:], plaintext)
 
-	return DefaultStringCodec.EncodeToString(ciphertext), nil
+	return ciphertext, nil
+}
+
+// Validate checks the lengths of the key and the iv.
+func (c *cbc) Validate() error {
+	if c.newBlock != nil {
+		return validateBlockKeyIv(c.newBlock, c.key, c.iv)
+	}
+	if err := validateAesKey(c.key); err != nil {
+		return err
+	}
+	return validateIv(c.iv)
+}
+
+// Mode returns [ModeCBC].
+func (c *cbc) Mode() ModeID {
+	return ModeCBC
+}
+
+// Describe describes the cipher, e.g., AES-256-CBC.
+func (c *cbc) Describe() CipherInfo {
+	algorithm := c.algorithm
+	if algorithm == "" {
+		algorithm = algorithmAES
+	}
+	return CipherInfo{Algorithm: algorithm, Mode: ModeCBC, KeyBits: keyBits(c.key), Codec: defaultCodecName()}
 }
 
 // Decrypt decrypts the given ciphertext using CBC.
 // The ciphertext must be a [DefaultStringCodec] string.
 //
 // The iv prepended to the ciphertext (the first block) will be used.
-// And the iv field of the cbc will be ignored.
+// And the iv field of the cbc will be ignored,
+// unless created by [NewCBCNoIVPrepend].
 func (c *cbc) Decrypt(cipherText string) (plainText string, err error) {
+	defer wrapOpError(&err, decrypt, ModeCBC)
 	defer recoverFromPanic(&err)
 
-	ciphertext, err := DefaultStringCodec.DecodeString(cipherText)
+	ciphertext, err := decodeCipherText(cipherText)
 	if err != nil {
 		return "", err
 	}
 
+	return c.decrypt(ciphertext, nil)
+}
+
+// DecryptRaw decrypts the given raw ciphertext bytes using CBC like
+// Decrypt, without decoding. The cipherText is not modified.
+func (c *cbc) DecryptRaw(cipherText []byte) (plainText string, err error) {
+	defer wrapOpError(&err, decrypt, ModeCBC)
+	defer recoverFromPanic(&err)
+
+	return c.decrypt(bytes.Clone(cipherText), nil)
+}
+
+// DecryptAppend decrypts the given raw ciphertext bytes using CBC like
+// DecryptRaw, appending the plaintext to dst. The cipherText is not
+// modified, and the intermediate buffer is zeroed before returning.
+func (c *cbc) DecryptAppend(dst, cipherText []byte) (_ []byte, err error) {
+	defer wrapOpError(&err, decrypt, ModeCBC)
+	defer recoverFromPanic(&err)
+
+	buf := bytes.Clone(cipherText)
+	defer clear(buf)
+
+	plaintext, err := c.decryptBytes(buf, nil)
+	if err != nil {
+		return dst, err
+	}
+	return append(dst, plaintext...), nil
+}
+
+// decrypt decrypts the ciphertext in place like decryptBytes, and returns
+// the plaintext as a string.
+func (c *cbc) decrypt(ciphertext []byte, unpad func(blockSize int, buf []byte) ([]byte, error)) (plainText string, err error) {
+	plaintext, err := c.decryptBytes(ciphertext, unpad)
+	return string(plaintext), err
+}
+
+// decryptBytes decrypts the ciphertext in place, and unpads the result with
+// unpad if not nil. The unpadding takes the block size of the underlying
+// block cipher. The plaintext returned is a sub-slice of the ciphertext.
+func (c *cbc) decryptBytes(ciphertext []byte, unpad func(blockSize int, buf []byte) ([]byte, error)) ([]byte, error) {
 	key := c.key.Bytes()
 
-	block, err := aes.NewCipher(key)
+	block, err := c.block(key)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	blockSize := block.BlockSize()
+
+	// All the invalid lengths must be rejected here: CryptBlocks panics on
+	// a ciphertext not a multiple of the block size, and
+	// cipher.NewCBCDecrypter panics on a bad iv length.
+	if c.noIVPrepend {
+		if len(ciphertext)%blockSize != 0 {
+			return nil, ErrCipherTextBlockSize
+		}
+
+		iv := c.iv.Bytes()
+		if len(iv) != blockSize {
+			return nil, fmt.Errorf("%w: got %d bytes, want %d", ErrIvSize, len(iv), blockSize)
+		}
 
-	if len(ciphertext) < aes.BlockSize {
-		return "", ErrCipherTextTooShort
+		mode := cipher.NewCBCDecrypter(block, iv)
+		mode.CryptBlocks(ciphertext, ciphertext)
+
+		return cbcUnpad(blockSize, ciphertext, unpad)
+	}
+
+	if len(ciphertext) < blockSize {
+		return nil, ErrCipherTextTooShort
 	}
 
-	if len(ciphertext)%aes.BlockSize != 0 {
-		return "", ErrCipherTextBlockSize
+	if len(ciphertext)%blockSize != 0 {
+		return nil, ErrCipherTextBlockSize
 	}
 
 	var iv []byte
 
-	iv = ciphertext[:aes.BlockSize]
-	ciphertext = ciphertext[aes.BlockSize:]
+	iv = ciphertext[:blockSize]
+	ciphertext = ciphertext[blockSize:]
 
 	mode := cipher.NewCBCDecrypter(block, iv)
 
 	// CryptBlocks can work in-place if the two arguments are the same.
 	mode.CryptBlocks(ciphertext, ciphertext)
 
-	return string(ciphertext), nil
+	return cbcUnpad(blockSize, ciphertext, unpad)
+}
+
+// cbcUnpad unpads the plaintext with unpad if not nil.
+func cbcUnpad(blockSize int, plaintext []byte, unpad func(blockSize int, buf []byte) ([]byte, error)) ([]byte, error) {
+	if unpad == nil {
+		return plaintext, nil
+	}
+	return unpad(blockSize, plaintext)
 }
 
 // simpleCBC = cbc + random iv + PKCS7 padding plaintext
@@ -145,21 +345,61 @@ func SimpleCBC(keyPassphrase string) Cipher {
 }
 
 func (c *simpleCBC) Encrypt(plainText string) (cipherText string, err error) {
+	defer wrapOpError(&err, encrypt, ModeCBC)
 	defer recoverFromPanic(&err)
 
-	paddedText := string(pkcs7.Pad(aes.BlockSize, []byte(plainText)))
-	return c.cbc.Encrypt(paddedText)
+	return encodeCipherText(c.cbc.encrypt([]byte(plainText), pkcs7.Pad))
+}
+
+func (c *simpleCBC) EncryptRaw(plainText string) (cipherText []byte, err error) {
+	defer wrapOpError(&err, encrypt, ModeCBC)
+	defer recoverFromPanic(&err)
+
+	return c.cbc.encrypt([]byte(plainText), pkcs7.Pad)
 }
 
 func (c *simpleCBC) Decrypt(cipherText string) (plainText string, err error) {
+	defer wrapOpError(&err, decrypt, ModeCBC)
 	defer recoverFromPanic(&err)
 
-	paddedText, err := c.cbc.Decrypt(cipherText)
+	ciphertext, err := decodeCipherText(cipherText)
 	if err != nil {
 		return "", err
 	}
-	plaintext, err := pkcs7.Unpad(aes.BlockSize, []byte(paddedText))
-	return string(plaintext), err
+
+	return c.cbc.decrypt(ciphertext, pkcs7.Unpad)
+}
+
+func (c *simpleCBC) DecryptRaw(cipherText []byte) (plainText string, err error) {
+	defer wrapOpError(&err, decrypt, ModeCBC)
+	defer recoverFromPanic(&err)
+
+	return c.cbc.decrypt(bytes.Clone(cipherText), pkcs7.Unpad)
+}
+
+func (c *simpleCBC) EncryptAppend(dst, plainText []byte) (_ []byte, err error) {
+	defer wrapOpError(&err, encrypt, ModeCBC)
+	defer recoverFromPanic(&err)
+
+	ciphertext, err := c.cbc.encrypt(plainText, pkcs7.Pad)
+	if err != nil {
+		return dst, err
+	}
+	return append(dst, ciphertext...), nil
+}
+
+func (c *simpleCBC) DecryptAppend(dst, cipherText []byte) (_ []byte, err error) {
+	defer wrapOpError(&err, decrypt, ModeCBC)
+	defer recoverFromPanic(&err)
+
+	buf := bytes.Clone(cipherText)
+	defer clear(buf)
+
+	plaintext, err := c.cbc.decryptBytes(buf, pkcs7.Unpad)
+	if err != nil {
+		return dst, err
+	}
+	return append(dst, plaintext...), nil
 }
 
 //////// Wrap stream.go cipher to block cipher ////////
@@ -178,11 +418,46 @@ type streamToBlock struct {
 
 var _ Cipher = (*streamToBlock)(nil)
 
-func newStreamToBlock(sc Stream) Cipher {
+// BlockFromStream wraps the [Stream] into a [Cipher], so that a custom
+// Stream gains the string API of this package:
+//
+//	c := simplecipher.BlockFromStream(myStream)
+//	ciphertext, err := c.Encrypt("plaintext")
+//
+// Encrypt buffers the whole output of EncryptStream, and encodes it with
+// [DefaultStringCodec]. Decrypt decodes the ciphertext, and buffers the
+// whole output of DecryptStream. The bytes are passed through as is:
+// whatever the Stream writes ahead of the ciphertext (e.g., the IV
+// prepended by the streams of this package) is part of the ciphertext.
+//
+// The CFB, OFB and CTR ciphers of this package are built this way,
+// e.g., [NewCTR] is BlockFromStream([NewCTRStream]).
+func BlockFromStream(sc Stream) Cipher {
 	return &streamToBlock{Stream: sc}
 }
 
+// Mode returns the cipher mode of the underlying [Stream],
+// or "" if the Stream does not report its mode.
+func (s *streamToBlock) Mode() ModeID {
+	return modeOf(s.Stream)
+}
+
+// Validate validates the underlying [Stream].
+func (s *streamToBlock) Validate() error {
+	return ValidateStream(s.Stream)
+}
+
+// Describe describes the underlying [Stream], with the codec of the cipher.
+func (s *streamToBlock) Describe() CipherInfo {
+	info := DescribeStream(s.Stream)
+	info.Codec = defaultCodecName()
+	return info
+}
+
+// Encrypt encrypts the plaintext with the underlying [Stream].
+// The ciphertext is returned with [DefaultStringCodec] encoding.
 func (s *streamToBlock) Encrypt(plainText string) (cipherText string, err error) {
+	defer wrapOpError(&err, encrypt, s.Mode())
 	defer recoverFromPanic(&err)
 
 	plainTextReader := bytes.NewReader([]byte(plainText))
@@ -199,10 +474,13 @@ func (s *streamToBlock) Encrypt(plainText string) (cipherText string, err error)
 	return encodedCipherText, nil
 }
 
+// Decrypt decrypts the [DefaultStringCodec] encoded ciphertext
+// with the underlying [Stream].
 func (s *streamToBlock) Decrypt(cipherText string) (plainText string, err error) {
+	defer wrapOpError(&err, decrypt, s.Mode())
 	defer recoverFromPanic(&err)
 
-	cipherTextBytes, err := DefaultStringCodec.DecodeString(cipherText)
+	cipherTextBytes, err := decodeCipherText(cipherText)
 	if err != nil {
 		return "", err
 	}
@@ -219,6 +497,97 @@ func (s *streamToBlock) Decrypt(cipherText string) (plainText string, err error)
 	return string(plainTextBytes), nil
 }
 
+// blockToStream is a wrapper to convert a Block [Cipher] to a [Stream].
+type blockToStream struct {
+	Cipher
+}
+
+var _ Stream = (*blockToStream)(nil)
+
+// StreamFromBlock wraps the [Cipher] into a [Stream], so that a block-only
+// cipher (e.g., CBC or GCM) can be used where a Stream is expected:
+//
+//	s := simplecipher.StreamFromBlock(simplecipher.SimpleGCM("key", "nonce"))
+//	err := s.EncryptStream(file, out)
+//
+// Attention: it is not streaming. EncryptStream reads the whole plaintext
+// into memory and encrypts it at once, and DecryptStream likewise, so the
+// memory usage grows with the size of the input. Use a real Stream
+// (e.g., [NewRecordStream]) for large inputs.
+//
+// As the output of a Stream, the ciphertext is the raw bytes, i.e., the
+// output of c.Encrypt decoded with [DefaultStringCodec].
+func StreamFromBlock(c Cipher) Stream {
+	return &blockToStream{Cipher: c}
+}
+
+// Mode returns the cipher mode of the underlying [Cipher],
+// or "" if the Cipher does not report its mode.
+func (s *blockToStream) Mode() ModeID {
+	return modeOf(s.Cipher)
+}
+
+// Validate validates the underlying [Cipher].
+func (s *blockToStream) Validate() error {
+	return ValidateCipher(s.Cipher)
+}
+
+// Describe describes the underlying [Cipher], without a codec.
+func (s *blockToStream) Describe() CipherInfo {
+	info := DescribeCipher(s.Cipher)
+	info.Codec = ""
+	return info
+}
+
+// EncryptStream reads the whole plaintext, encrypts it with the underlying
+// [Cipher], and writes the decoded ciphertext.
+func (s *blockToStream) EncryptStream(plainText io.Reader, cipherText io.Writer) (err error) {
+	defer wrapOpError(&err, encrypt, s.Mode())
+	defer recoverFromPanic(&err)
+
+	plaintext, err := io.ReadAll(plainText)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCopy, err)
+	}
+
+	encoded, err := s.Encrypt(string(plaintext))
+	if err != nil {
+		return err
+	}
+
+	ciphertext, err := DefaultStringCodec.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrMalformedCiphertext, err)
+	}
+
+	if _, err := cipherText.Write(ciphertext); err != nil {
+		return fmt.Errorf("%w: %w", ErrCopy, err)
+	}
+	return nil
+}
+
+// DecryptStream reads the whole ciphertext, decrypts it with the
+// underlying [Cipher], and writes the plaintext.
+func (s *blockToStream) DecryptStream(cipherText io.Reader, plainText io.Writer) (err error) {
+	defer wrapOpError(&err, decrypt, s.Mode())
+	defer recoverFromPanic(&err)
+
+	ciphertext, err := io.ReadAll(cipherText)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCopy, err)
+	}
+
+	plaintext, err := s.Decrypt(DefaultStringCodec.EncodeToString(ciphertext))
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(plainText, plaintext); err != nil {
+		return fmt.Errorf("%w: %w", ErrCopy, err)
+	}
+	return nil
+}
+
 // NewCFB creates a new CFB cipher with the given key and iv.
 //
 // The key must be 16, 24, or 32 bytes long to select AES-128, AES-192, or AES-256.
@@ -231,7 +600,7 @@ func (s *streamToBlock) Decrypt(cipherText string) (plainText string, err error)
 //
 // See also: [cipher.NewCFBDecrypter], [cipher.NewCFBEncrypter] for low-level usage.
 func NewCFB(key, iv Key) Cipher {
-	return newStreamToBlock(NewCFBStream(key, iv))
+	return BlockFromStream(NewCFBStream(key, iv))
 }
 
 // SimpleCFB creates a new AES-256-CFB cipher with a key derived from
@@ -239,7 +608,7 @@ func NewCFB(key, iv Key) Cipher {
 //
 // See also: [NewCFB] for more control.
 func SimpleCFB(keyPassphrase string) Cipher {
-	return newStreamToBlock(SimpleCFBStream(keyPassphrase))
+	return BlockFromStream(SimpleCFBStream(keyPassphrase))
 }
 
 // NewOFB creates a new OFB cipher with the given key and iv.
@@ -254,7 +623,7 @@ func SimpleCFB(keyPassphrase string) Cipher {
 //
 // See also: [cipher.NewOFB] for low-level usage.
 func NewOFB(key, iv Key) Cipher {
-	return newStreamToBlock(NewOFBStream(key, iv))
+	return BlockFromStream(NewOFBStream(key, iv))
 }
 
 // SimpleOFB creates a new AES-256-OFB cipher with a key derived from
@@ -262,7 +631,7 @@ func NewOFB(key, iv Key) Cipher {
 //
 // See also: [NewOFB] for more control.
 func SimpleOFB(keyPassphrase string) Cipher {
-	return newStreamToBlock(SimpleOFBStream(keyPassphrase))
+	return BlockFromStream(SimpleOFBStream(keyPassphrase))
 }
 
 // NewCTR creates a new CTR cipher with the given key and iv.
@@ -277,7 +646,7 @@ func SimpleOFB(keyPassphrase string) Cipher {
 //
 // See also: [cipher.NewCTR] for low-level usage.
 func NewCTR(key, iv Key) Cipher {
-	return newStreamToBlock(NewCTRStream(key, iv))
+	return BlockFromStream(NewCTRStream(key, iv))
 }
 
 // SimpleCTR creates a new AES-256-CTR cipher with a key derived from
@@ -285,5 +654,5 @@ func NewCTR(key, iv Key) Cipher {
 //
 // See also: [NewCTR] for more control.
 func SimpleCTR(keyPassphrase string) Cipher {
-	return newStreamToBlock(SimpleCTRStream(keyPassphrase))
+	return BlockFromStream(SimpleCTRStream(keyPassphrase))
 }
@@ -1,9 +1,13 @@
 package simplecipher
 
 import (
+	"bytes"
 	"crypto/aes"
+	"crypto/cipher"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"os/exec"
 	"testing"
 )
@@ -128,6 +132,284 @@ func FuzzNewCBC(f *testing.F) {
 	})
 }
 
+func FuzzCBC_Decrypt_invalidLength(f *testing.F) {
+	// ciphertext: bytes (before encoding)
+	f.Add(make([]byte, 17))
+	f.Add(make([]byte, 15))
+	f.Add(make([]byte, 0))
+	f.Add(make([]byte, 33))
+
+	key := String("key0key1key2key3")
+	iv := String("iv00iv01iv02iv03")
+
+	ciphers := map[string]Cipher{
+		"NewCBC":            NewCBC(key, iv),
+		"NewCBCNoIVPrepend": NewCBCNoIVPrepend(key, iv),
+		"SimpleCBC":         SimpleCBC("key"),
+		"badIv":             NewCBCNoIVPrepend(key, String("badiv")),
+	}
+
+	f.Fuzz(func(t *testing.T, ciphertext []byte) {
+		for name, cipher := range ciphers {
+			_, err := cipher.Decrypt(DefaultStringCodec.EncodeToString(ciphertext))
+			if errors.Is(err, ErrPanic) {
+				t.Errorf("%s: Decrypt(%d bytes) recovered from panic: %v", name, len(ciphertext), err)
+			}
+
+			switch {
+			case name == "badIv" && len(ciphertext)%aes.BlockSize == 0:
+				if !errors.Is(err, ErrIvSize) {
+					t.Errorf("%s: Decrypt(%d bytes) error = %v, want %v", name, len(ciphertext), err, ErrIvSize)
+				}
+			case len(ciphertext)%aes.BlockSize != 0:
+				if !errors.Is(err, ErrCipherTextBlockSize) && !errors.Is(err, ErrCipherTextTooShort) {
+					t.Errorf("%s: Decrypt(%d bytes) error = %v, want %v", name, len(ciphertext), err, ErrCipherTextBlockSize)
+				}
+			case len(ciphertext) == 0 && name == "NewCBC":
+				if !errors.Is(err, ErrCipherTextTooShort) {
+					t.Errorf("%s: Decrypt(0 bytes) error = %v, want %v", name, err, ErrCipherTextTooShort)
+				}
+			}
+		}
+	})
+}
+
+func TestCBC_Encrypt_badIv(t *testing.T) {
+	for _, c := range []Cipher{
+		NewCBC(String("key0key1key2key3"), String("badiv")),
+		NewCBCNoIVPrepend(String("key0key1key2key3"), String("badiv")),
+	} {
+		_, err := c.Encrypt("plain-text-plain")
+		if !errors.Is(err, ErrIvSize) || errors.Is(err, ErrPanic) {
+			t.Errorf("Encrypt(bad iv) error = %v, want %v", err, ErrIvSize)
+		}
+	}
+}
+
+func TestNewCBCNoIVPrepend(t *testing.T) {
+	key := String("key0key1key2key3key4key5key6key7")
+	iv := String("iv00iv01iv02iv03")
+
+	createCBC := func() Cipher {
+		return NewCBCNoIVPrepend(key, iv)
+	}
+
+	for _, plaintext := range []string{"", "plain-text-plain", "plain-text-plain-text000plain-te"} {
+		testCipher("", t, createCBC, plaintext)
+
+		ciphertext, err := createCBC().Encrypt(plaintext)
+		if err != nil {
+			t.Fatalf("Encrypt error: %v", err)
+		}
+		raw, _ := DefaultStringCodec.DecodeString(ciphertext)
+		if len(raw) != len(plaintext) {
+			t.Errorf("len(ciphertext) = %v, want %v", len(raw), len(plaintext))
+		}
+
+		// the same as NewCBC without the iv prefix
+		prepended, _ := NewCBC(key, iv).Encrypt(plaintext)
+		if prepended[2*aes.BlockSize:] != ciphertext {
+			t.Errorf("Encrypt() = %s, want %s", ciphertext, prepended[2*aes.BlockSize:])
+		}
+	}
+}
+
+func TestCBC_Append(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	key := String("key0key1key2key3key4key5key6key7")
+
+	ciphers := map[string]Cipher{
+		"NewCBC":    NewCBC(key, String("iv00iv01iv02iv03")),
+		"SimpleCBC": SimpleCBC("key"),
+	}
+	for name, c := range ciphers {
+		t.Run(name, func(t *testing.T) {
+			cipher := c.(AppendCipher)
+
+			// a multiple of the block size, with spare capacity
+			plaintext := append(make([]byte, 0, 64), "plain-text-plain-text000plain-te"...)
+			spare := plaintext[:cap(plaintext)]
+
+			prefix := []byte("prefix:")
+			ciphertext, err := cipher.EncryptAppend(bytes.Clone(prefix), plaintext)
+			if err != nil {
+				t.Fatalf("EncryptAppend() error = %v", err)
+			}
+			if !bytes.HasPrefix(ciphertext, prefix) {
+				t.Fatalf("EncryptAppend() = %q, want prefix %q", ciphertext, prefix)
+			}
+			ciphertext = ciphertext[len(prefix):]
+			// not padded into the spare capacity of the plaintext
+			if !bytes.Equal(spare[len(plaintext):], make([]byte, cap(plaintext)-len(plaintext))) {
+				t.Errorf("EncryptAppend() wrote to the spare capacity of the plaintext")
+			}
+
+			decrypted, err := cipher.DecryptAppend(bytes.Clone(prefix), ciphertext)
+			if err != nil || string(decrypted) != "prefix:"+string(plaintext) {
+				t.Errorf("DecryptAppend() = %q, %v, want %q", decrypted, err, "prefix:"+string(plaintext))
+			}
+
+			// interoperable with the string API
+			s, err := cipher.Decrypt(DefaultStringCodec.EncodeToString(ciphertext))
+			if err != nil || s != string(plaintext) {
+				t.Errorf("Decrypt(EncryptAppend()) = %q, %v, want %q", s, err, plaintext)
+			}
+
+			out, err := cipher.DecryptAppend(prefix, ciphertext[:len(ciphertext)-1])
+			if !errors.Is(err, ErrCipherTextBlockSize) {
+				t.Errorf("DecryptAppend(truncated) error = %v, want %v", err, ErrCipherTextBlockSize)
+			}
+			if !bytes.Equal(out, prefix) {
+				t.Errorf("DecryptAppend(truncated) = %q, want dst %q", out, prefix)
+			}
+		})
+	}
+}
+
+func TestRawCipher(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	key := String("key0key1key2key3key4key5key6key7")
+	iv := String("iv00iv01iv02iv03")
+	nonce := String("nonce0nonce1")
+
+	// deterministic ciphers: the raw ciphertext is the decoded one of Encrypt
+	deterministic := map[string]Cipher{
+		"NewCBC":             NewCBC(key, iv),
+		"NewCBCNoIVPrepend":  NewCBCNoIVPrepend(key, iv),
+		"NewGCM":             NewGCM(key, nonce),
+		"NewGCMWithNonceLen": NewGCMWithNonceSize(key, String("nonce0nonce1nonce2"), 18),
+	}
+	for name, c := range deterministic {
+		t.Run(name, func(t *testing.T) {
+			plaintext := "plain-text-plain-text000plain-te"
+
+			cipherText, err := c.Encrypt(plaintext)
+			if err != nil {
+				t.Fatalf("Encrypt error: %v", err)
+			}
+			want, err := DefaultStringCodec.DecodeString(cipherText)
+			if err != nil {
+				t.Fatalf("DecodeString error: %v", err)
+			}
+
+			raw, err := c.(RawCipher).EncryptRaw(plaintext)
+			if err != nil {
+				t.Fatalf("EncryptRaw error: %v", err)
+			}
+			if !bytes.Equal(raw, want) {
+				t.Errorf("EncryptRaw() = %x, want %x", raw, want)
+			}
+
+			decrypted, err := c.(RawCipher).DecryptRaw(raw)
+			if err != nil {
+				t.Fatalf("DecryptRaw error: %v", err)
+			}
+			if decrypted != plaintext {
+				t.Errorf("DecryptRaw() = %s, want %s", decrypted, plaintext)
+			}
+			// the input is not decrypted in place
+			if !bytes.Equal(raw, want) {
+				t.Errorf("DecryptRaw() modified the ciphertext")
+			}
+		})
+	}
+
+	// random ivs and nonces: the raw ciphertexts interoperate with Encrypt and Decrypt
+	random := map[string]Cipher{
+		"SimpleCBC":   SimpleCBC("key"),
+		"RandomNonce": NewGCMWithConfig(GCMConfig{Key: key, RandomNonce: true}),
+	}
+	for name, c := range random {
+		t.Run(name, func(t *testing.T) {
+			plaintext := "plaintext"
+
+			raw, err := c.(RawCipher).EncryptRaw(plaintext)
+			if err != nil {
+				t.Fatalf("EncryptRaw error: %v", err)
+			}
+			decrypted, err := c.Decrypt(DefaultStringCodec.EncodeToString(raw))
+			if err != nil || decrypted != plaintext {
+				t.Errorf("Decrypt(EncryptRaw()) = %s, %v, want %s", decrypted, err, plaintext)
+			}
+
+			cipherText, err := c.Encrypt(plaintext)
+			if err != nil {
+				t.Fatalf("Encrypt error: %v", err)
+			}
+			raw, _ = DefaultStringCodec.DecodeString(cipherText)
+			decrypted, err = c.(RawCipher).DecryptRaw(raw)
+			if err != nil || decrypted != plaintext {
+				t.Errorf("DecryptRaw(Encrypt()) = %s, %v, want %s", decrypted, err, plaintext)
+			}
+		})
+	}
+
+	_, err := NewCBC(key, iv).(RawCipher).DecryptRaw([]byte("short"))
+	if !errors.Is(err, ErrCipherTextTooShort) {
+		t.Errorf("DecryptRaw(short) error = %v, want %v", err, ErrCipherTextTooShort)
+	}
+}
+
+// mockBlock is a toy 8-byte block cipher (XOR with the key) for testing
+// the block size plumbing. It is NOT secure.
+type mockBlock [8]byte
+
+const mockBlockSize = 8
+
+func newMockBlock(key []byte) (cipher.Block, error) {
+	if len(key) != mockBlockSize {
+		return nil, fmt.Errorf("mock key must be %d bytes", mockBlockSize)
+	}
+	var b mockBlock
+	copy(b[:], key)
+	return &b, nil
+}
+
+func (b *mockBlock) BlockSize() int { return mockBlockSize }
+
+func (b *mockBlock) Encrypt(dst, src []byte) {
+	for i := 0; i < mockBlockSize; i++ {
+		dst[i] = src[i] ^ b[i]
+	}
+}
+
+func (b *mockBlock) Decrypt(dst, src []byte) { b.Encrypt(dst, src) }
+
+func TestCBC_blockSize(t *testing.T) {
+	key := String("mockkey0")
+	iv := String("iv00iv01")
+
+	createCBC := func() Cipher {
+		return &cbc{key: key, iv: iv, newBlock: newMockBlock}
+	}
+	createSimpleCBC := func() Cipher {
+		return &simpleCBC{cbc: cbc{key: key, iv: iv, newBlock: newMockBlock}}
+	}
+
+	testCipher("cbc", t, createCBC, "plain-text-plain-text000")
+	testCipher("simpleCBC", t, createSimpleCBC, "plain")
+
+	// 8 bytes iv + "plain" padded to 8 bytes
+	ciphertext, err := createSimpleCBC().Encrypt("plain")
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+	if raw, _ := DefaultStringCodec.DecodeString(ciphertext); len(raw) != 2*mockBlockSize {
+		t.Errorf("len(ciphertext) = %v, want %v", len(raw), 2*mockBlockSize)
+	}
+
+	// a multiple of 8 bytes, but not of aes.BlockSize
+	if _, err := createCBC().Encrypt("plain-te"); err != nil {
+		t.Errorf("Encrypt(8 bytes) error = %v, want nil", err)
+	}
+
+	if err := ValidateCipher(createCBC()); err != nil {
+		t.Errorf("Validate() error = %v, want nil", err)
+	}
+}
+
 func FuzzSimpleCBC(f *testing.F) {
 	// key: string, plaintext: string
 	f.Add("key", "plain-text-plain-text000")
@@ -251,3 +533,60 @@ func ExampleNewCTR() {
 	// decrypted by simplecipher: Hello, World!
 	// decrypted by openssl: Hello, World!
 }
+
+// xorStream is a toy user-provided Stream: a header byte,
+// and the plaintext XORed with the key byte.
+type xorStream struct {
+	key byte
+}
+
+func (s xorStream) EncryptStream(plainText io.Reader, cipherText io.Writer) error {
+	b, err := io.ReadAll(plainText)
+	if err != nil {
+		return err
+	}
+	for i := range b {
+		b[i] ^= s.key
+	}
+	_, err = cipherText.Write(append([]byte{'X'}, b...))
+	return err
+}
+
+func (s xorStream) DecryptStream(cipherText io.Reader, plainText io.Writer) error {
+	b, err := io.ReadAll(cipherText)
+	if err != nil {
+		return err
+	}
+	if len(b) < 1 || b[0] != 'X' {
+		return ErrMalformedCiphertext
+	}
+	b = b[1:]
+	for i := range b {
+		b[i] ^= s.key
+	}
+	_, err = plainText.Write(b)
+	return err
+}
+
+func TestBlockFromStream(t *testing.T) {
+	c := BlockFromStream(xorStream{key: 0x42})
+
+	testCipher("", t, func() Cipher { return c }, "plaintext")
+
+	ciphertext, err := c.Encrypt("plaintext")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	raw, err := DefaultStringCodec.DecodeString(ciphertext)
+	if err != nil || raw[0] != 'X' || len(raw) != 1+len("plaintext") {
+		t.Errorf("Encrypt() = %q, want the %s encoded output of the Stream", ciphertext, CodecName(DefaultStringCodec))
+	}
+
+	if _, err := c.Decrypt(DefaultStringCodec.EncodeToString([]byte("Y"))); !errors.Is(err, ErrMalformedCiphertext) {
+		t.Errorf("Decrypt(bad header) error = %v, want the error of the Stream", err)
+	}
+
+	if mode := c.(ModeCipher).Mode(); mode != "" {
+		t.Errorf("Mode() = %q, want empty for a Stream without a mode", mode)
+	}
+}
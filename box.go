@@ -0,0 +1,144 @@
+package simplecipher
+
+import (
+	"crypto/cipher"
+	"crypto/sha256"
+	"fmt"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+	"io"
+)
+
+// This file implements a sealed box: anonymous hybrid encryption to an
+// X25519 public key, in the style of NaCl (libsodium) crypto_box_seal.
+//
+// An ephemeral X25519 keypair is generated for each message, and the key
+// and the nonce of XChaCha20-Poly1305 are derived from the shared secret
+// of the ephemeral private key and the recipient public key:
+//
+//	key | nonce = HKDF-SHA256(secret: X25519(ephemeral priv, recipient pub),
+//	                          salt: ephemeral pub | recipient pub,
+//	                          info: "simplecipher sealed box")
+//
+// The sealed box is laid out as follows, and then encoded with
+// [DefaultStringCodec]:
+//
+//	ephemeral public key (32 bytes)
+//	XChaCha20-Poly1305 ciphertext (with 16 bytes tag)
+//
+// Notice that it is not compatible with libsodium, which uses XSalsa20
+// and BLAKE2b.
+
+// BoxKeySize is the size of the X25519 keys of the sealed boxes.
+const BoxKeySize = curve25519.PointSize
+
+// hkdfInfoBox is the HKDF info label of the key and the nonce of the
+// sealed boxes.
+const hkdfInfoBox = "simplecipher sealed box"
+
+// GenerateBoxKeypair generates a random X25519 keypair for [SealBox] and
+// [OpenBox]. Share the public key with the senders, and keep the private
+// key secret.
+func GenerateBoxKeypair() (pub, priv [BoxKeySize]byte, err error) {
+	if err := readRandom(nil, priv[:]); err != nil {
+		return pub, priv, err
+	}
+
+	p, err := curve25519.X25519(priv[:], curve25519.Basepoint)
+	if err != nil {
+		return pub, priv, err
+	}
+	copy(pub[:], p)
+
+	return pub, priv, nil
+}
+
+// SealBox encrypts the plaintext anonymously, so that only the holder of the
+// private key of recipientPub can decrypt it via [OpenBox]:
+//
+//	pub, priv, err := simplecipher.GenerateBoxKeypair()
+//	sealed, err := simplecipher.SealBox(pub, "secret")
+//	plain, err := simplecipher.OpenBox(priv, pub, sealed)
+//
+// The sender is not authenticated: anyone knowing the public key can seal a
+// box, and the sender can not open it afterwards.
+//
+// The sealed box is returned with [DefaultStringCodec] encoding.
+func SealBox(recipientPub [BoxKeySize]byte, plain string) (sealed string, err error) {
+	defer wrapOpError(&err, encrypt, "")
+	defer recoverFromPanic(&err)
+
+	ephemeralPub, ephemeralPriv, err := GenerateBoxKeypair()
+	if err != nil {
+		return "", err
+	}
+	defer clear(ephemeralPriv[:])
+
+	aead, nonce, err := boxAEAD(ephemeralPriv[:], recipientPub[:], ephemeralPub[:], recipientPub[:])
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+
+	out := aead.Seal(ephemeralPub[:], nonce, []byte(plain), nil)
+
+	return DefaultStringCodec.EncodeToString(out), nil
+}
+
+// OpenBox decrypts a box sealed by [SealBox] with the recipient keypair.
+//
+// If the box was not sealed to recipientPub, or has been tampered with,
+// an error wrapping [ErrAuthenticationFailed] is returned.
+func OpenBox(recipientPriv, recipientPub [BoxKeySize]byte, sealed string) (plain string, err error) {
+	defer wrapOpError(&err, decrypt, "")
+	defer recoverFromPanic(&err)
+
+	ciphertext, err := decodeCipherText(sealed)
+	if err != nil {
+		return "", err
+	}
+
+	if len(ciphertext) < BoxKeySize+chacha20poly1305.Overhead {
+		return "", ErrCipherTextTooShort
+	}
+	ephemeralPub, ciphertext := ciphertext[:BoxKeySize], ciphertext[BoxKeySize:]
+
+	aead, nonce, err := boxAEAD(recipientPriv[:], ephemeralPub, ephemeralPub, recipientPub[:])
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrMalformedCiphertext, err)
+	}
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrAuthenticationFailed, err)
+	}
+
+	return string(plaintext), nil
+}
+
+// boxAEAD derives the XChaCha20-Poly1305 AEAD and the nonce of a sealed box
+// from the X25519 shared secret of priv and pub.
+func boxAEAD(priv, pub, ephemeralPub, recipientPub []byte) (aead cipher.AEAD, nonce []byte, err error) {
+	// fails with a low order pub, i.e., an all-zero shared secret
+	shared, err := curve25519.X25519(priv, pub)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer clear(shared)
+
+	salt := append(append([]byte{}, ephemeralPub...), recipientPub...)
+	kdf := hkdf.New(sha256.New, shared, salt, []byte(hkdfInfoBox))
+
+	keyAndNonce := make([]byte, chacha20poly1305.KeySize+chacha20poly1305.NonceSizeX)
+	if _, err := io.ReadFull(kdf, keyAndNonce); err != nil {
+		return nil, nil, err
+	}
+	key, nonce := keyAndNonce[:chacha20poly1305.KeySize], keyAndNonce[chacha20poly1305.KeySize:]
+
+	aead, err = chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return aead, nonce, nil
+}
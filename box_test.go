@@ -0,0 +1,66 @@
+package simplecipher
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSealBox(t *testing.T) {
+	pub, priv, err := GenerateBoxKeypair()
+	if err != nil {
+		t.Fatalf("GenerateBoxKeypair error: %v", err)
+	}
+	otherPub, otherPriv, err := GenerateBoxKeypair()
+	if err != nil {
+		t.Fatalf("GenerateBoxKeypair error: %v", err)
+	}
+	if pub == otherPub || priv == otherPriv {
+		t.Fatalf("GenerateBoxKeypair is deterministic")
+	}
+
+	for _, plaintext := range []string{"", "secret", strings.Repeat("a large payload ", 1024)} {
+		sealed, err := SealBox(pub, plaintext)
+		if err != nil {
+			t.Fatalf("SealBox error: %v", err)
+		}
+
+		got, err := OpenBox(priv, pub, sealed)
+		if err != nil || got != plaintext {
+			t.Errorf("OpenBox(SealBox(%.16q)) = %.16q, %v", plaintext, got, err)
+		}
+
+		// only the holder of the private key can open it
+		if _, err := OpenBox(otherPriv, otherPub, sealed); !errors.Is(err, ErrAuthenticationFailed) {
+			t.Errorf("OpenBox(other keypair) error = %v, want %v", err, ErrAuthenticationFailed)
+		}
+		if _, err := OpenBox(otherPriv, pub, sealed); !errors.Is(err, ErrAuthenticationFailed) {
+			t.Errorf("OpenBox(other private key) error = %v, want %v", err, ErrAuthenticationFailed)
+		}
+	}
+
+	// randomized by the ephemeral keypair
+	sealed1, _ := SealBox(pub, "secret")
+	sealed2, _ := SealBox(pub, "secret")
+	if sealed1 == sealed2 {
+		t.Errorf("SealBox is deterministic")
+	}
+
+	ciphertext, _ := decodeCipherText(sealed1)
+
+	tampered := append([]byte{}, ciphertext...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := OpenBox(priv, pub, DefaultStringCodec.EncodeToString(tampered)); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("OpenBox(tampered) error = %v, want %v", err, ErrAuthenticationFailed)
+	}
+
+	// a low order (all-zero) ephemeral public key
+	lowOrder := append(make([]byte, BoxKeySize), ciphertext[BoxKeySize:]...)
+	if _, err := OpenBox(priv, pub, DefaultStringCodec.EncodeToString(lowOrder)); !errors.Is(err, ErrMalformedCiphertext) {
+		t.Errorf("OpenBox(low order key) error = %v, want %v", err, ErrMalformedCiphertext)
+	}
+
+	if _, err := OpenBox(priv, pub, DefaultStringCodec.EncodeToString(ciphertext[:BoxKeySize])); !errors.Is(err, ErrCipherTextTooShort) {
+		t.Errorf("OpenBox(truncated) error = %v, want %v", err, ErrCipherTextTooShort)
+	}
+}
@@ -0,0 +1,187 @@
+package simplecipher
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+)
+
+// This file provides an opaque ciphertext type that is easy to store,
+// e.g., embedded in JSON APIs.
+
+// Ciphertext is a self-describing ciphertext, produced by [EncryptToCiphertext].
+//
+// It records the cipher mode and the codec used to encode the ciphertext,
+// so that it can be decoded and decrypted correctly later,
+// even if [DefaultStringCodec] has been changed.
+//
+// It is marshaled to JSON as:
+//
+//	{"mode": "GCM", "codec": "hex", "data": "<encoded ciphertext>"}
+//
+// And to binary (e.g., for gob or protobuf bytes fields), without the
+// codec overhead, as:
+//
+//	version (1 byte) | len(mode) (1 byte) | mode | len(codec) (1 byte) | codec | raw ciphertext
+type Ciphertext struct {
+	// Mode is the cipher mode that produced the ciphertext.
+	Mode ModeID
+	// Codec is the name of the [StringCodec] used to encode Data in JSON.
+	// See [CodecName] for available names.
+	Codec string
+	// Data is the raw ciphertext bytes.
+	Data []byte
+}
+
+// ciphertextJSON is the JSON representation of [Ciphertext].
+type ciphertextJSON struct {
+	Mode  ModeID `json:"mode"`
+	Codec string `json:"codec"`
+	Data  string `json:"data"`
+}
+
+var (
+	_ json.Marshaler   = (*Ciphertext)(nil)
+	_ json.Unmarshaler = (*Ciphertext)(nil)
+)
+
+// MarshalJSON encodes the Data with the Codec and marshals the Ciphertext.
+func (c Ciphertext) MarshalJSON() ([]byte, error) {
+	codec := CodecByName(c.Codec)
+	if codec == nil {
+		return nil, fmt.Errorf("unknown codec: %q", c.Codec)
+	}
+
+	return json.Marshal(ciphertextJSON{
+		Mode:  c.Mode,
+		Codec: c.Codec,
+		Data:  codec.EncodeToString(c.Data),
+	})
+}
+
+// UnmarshalJSON unmarshals the Ciphertext and decodes the Data with the Codec.
+func (c *Ciphertext) UnmarshalJSON(b []byte) error {
+	var j ciphertextJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+
+	codec := CodecByName(j.Codec)
+	if codec == nil {
+		return fmt.Errorf("unknown codec: %q", j.Codec)
+	}
+
+	if err := checkCipherTextLen(j.Data, MaxCiphertextLen); err != nil {
+		return err
+	}
+
+	data, err := codec.DecodeString(j.Data)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrMalformedCiphertext, err)
+	}
+
+	*c = Ciphertext{Mode: j.Mode, Codec: j.Codec, Data: data}
+	return nil
+}
+
+// ciphertextBinaryVersion is the version of the binary form of [Ciphertext].
+const ciphertextBinaryVersion byte = 1
+
+var (
+	_ encoding.BinaryMarshaler   = (*Ciphertext)(nil)
+	_ encoding.BinaryUnmarshaler = (*Ciphertext)(nil)
+)
+
+// MarshalBinary encodes the Ciphertext with the raw Data.
+func (c Ciphertext) MarshalBinary() ([]byte, error) {
+	if len(c.Mode) > 255 || len(c.Codec) > 255 {
+		return nil, fmt.Errorf("%w: mode or codec name longer than 255 bytes", ErrInvalidConfig)
+	}
+
+	b := make([]byte, 0, 3+len(c.Mode)+len(c.Codec)+len(c.Data))
+	b = append(b, ciphertextBinaryVersion)
+	b = append(b, byte(len(c.Mode)))
+	b = append(b, c.Mode...)
+	b = append(b, byte(len(c.Codec)))
+	b = append(b, c.Codec...)
+	b = append(b, c.Data...)
+	return b, nil
+}
+
+// UnmarshalBinary decodes the Ciphertext from its binary form.
+// The Data is copied.
+func (c *Ciphertext) UnmarshalBinary(b []byte) error {
+	if len(b) < 1 {
+		return ErrCipherTextTooShort
+	}
+	if b[0] != ciphertextBinaryVersion {
+		return fmt.Errorf("%w: %d", ErrUnsupportedVersion, b[0])
+	}
+	b = b[1:]
+
+	mode, b, ok := cutLengthPrefixed(b)
+	if !ok {
+		return fmt.Errorf("%w: truncated mode", ErrMalformedCiphertext)
+	}
+	codec, b, ok := cutLengthPrefixed(b)
+	if !ok {
+		return fmt.Errorf("%w: truncated codec", ErrMalformedCiphertext)
+	}
+
+	if MaxCiphertextLen > 0 && int64(len(b)) > MaxCiphertextLen {
+		return fmt.Errorf("%w: %d bytes > %d", ErrCiphertextTooLarge, len(b), MaxCiphertextLen)
+	}
+
+	*c = Ciphertext{Mode: ModeID(mode), Codec: string(codec), Data: append([]byte{}, b...)}
+	return nil
+}
+
+// cutLengthPrefixed splits a 1-byte length prefixed field from b.
+func cutLengthPrefixed(b []byte) (field, rest []byte, ok bool) {
+	if len(b) < 1 || len(b) < 1+int(b[0]) {
+		return nil, nil, false
+	}
+	return b[1 : 1+int(b[0])], b[1+int(b[0]):], true
+}
+
+// EncryptToCiphertext encrypts the plaintext with the cipher
+// and returns the result as a [Ciphertext].
+//
+// The Mode is taken from the cipher if it is a [ModeCipher].
+// The Codec is the name of [DefaultStringCodec], or "hex" if
+// [DefaultStringCodec] is not one of the codecs provided by this package.
+func EncryptToCiphertext(c Cipher, plainText string) (Ciphertext, error) {
+	cipherText, err := c.Encrypt(plainText)
+	if err != nil {
+		return Ciphertext{}, err
+	}
+
+	data, err := decodeCipherText(cipherText)
+	if err != nil {
+		return Ciphertext{}, err
+	}
+
+	var mode ModeID
+	if mc, ok := c.(ModeCipher); ok {
+		mode = mc.Mode()
+	}
+
+	codec := CodecName(DefaultStringCodec)
+	if codec == "" {
+		codec = CodecName(HexCodec)
+	}
+
+	return Ciphertext{Mode: mode, Codec: codec, Data: data}, nil
+}
+
+// DecryptCiphertext decrypts the [Ciphertext] with the cipher.
+//
+// If both the cipher (as a [ModeCipher]) and the Ciphertext report a mode,
+// they must match.
+func DecryptCiphertext(c Cipher, cipherText Ciphertext) (string, error) {
+	if mc, ok := c.(ModeCipher); ok && cipherText.Mode != "" && mc.Mode() != cipherText.Mode {
+		return "", fmt.Errorf("mode mismatch: cipher is %s, ciphertext is %s", mc.Mode(), cipherText.Mode)
+	}
+
+	return c.Decrypt(DefaultStringCodec.EncodeToString(cipherText.Data))
+}
@@ -0,0 +1,147 @@
+package simplecipher
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCiphertext_JSON(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	tests := []struct {
+		name   string
+		cipher Cipher
+		codec  StringCodec
+		want   ModeID
+	}{
+		{
+			name:   "gcm_hex",
+			cipher: SimpleGCM("key", "nonce"),
+			codec:  HexCodec,
+			want:   ModeGCM,
+		},
+		{
+			name:   "ctr_base64",
+			cipher: SimpleCTR("key"),
+			codec:  Base64URLCodec,
+			want:   ModeCTR,
+		},
+		{
+			name:   "cbc_base32",
+			cipher: SimpleCBC("key"),
+			codec:  Base32StdCodec,
+			want:   ModeCBC,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			DefaultStringCodec = tt.codec
+			defer func() { DefaultStringCodec = HexCodec }()
+
+			ciphertext, err := EncryptToCiphertext(tt.cipher, "plaintext")
+			if err != nil {
+				t.Fatalf("EncryptToCiphertext error: %v", err)
+			}
+			if ciphertext.Mode != tt.want {
+				t.Errorf("Mode = %v, want %v", ciphertext.Mode, tt.want)
+			}
+
+			b, err := json.Marshal(ciphertext)
+			if err != nil {
+				t.Fatalf("json.Marshal error: %v", err)
+			}
+
+			// the codec used to decrypt is independent of the one to encrypt
+			DefaultStringCodec = HexCodec
+
+			var unmarshaled Ciphertext
+			if err := json.Unmarshal(b, &unmarshaled); err != nil {
+				t.Fatalf("json.Unmarshal(%s) error: %v", b, err)
+			}
+			if !reflect.DeepEqual(unmarshaled, ciphertext) {
+				t.Errorf("json.Unmarshal(%s) = %v, want %v", b, unmarshaled, ciphertext)
+			}
+
+			decrypted, err := DecryptCiphertext(tt.cipher, unmarshaled)
+			if err != nil {
+				t.Fatalf("DecryptCiphertext error: %v", err)
+			}
+			if decrypted != "plaintext" {
+				t.Errorf("decrypted (%s) != plaintext", decrypted)
+			}
+		})
+	}
+}
+
+func TestCiphertext_UnmarshalJSON_error(t *testing.T) {
+	tests := map[string]string{
+		"unknownCodec": `{"mode":"GCM","codec":"rot13","data":"abcd"}`,
+		"badData":      `{"mode":"GCM","codec":"hex","data":"xyz"}`,
+	}
+	for name, in := range tests {
+		t.Run(name, func(t *testing.T) {
+			var c Ciphertext
+			if err := json.Unmarshal([]byte(in), &c); err == nil {
+				t.Errorf("json.Unmarshal(%s) expected error, got none", in)
+			}
+		})
+	}
+}
+
+func TestCiphertext_Binary(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	cipher := SimpleGCM("key", "nonce")
+	ciphertext, err := EncryptToCiphertext(cipher, "plaintext")
+	if err != nil {
+		t.Fatalf("EncryptToCiphertext error: %v", err)
+	}
+
+	// through gob, which uses encoding.BinaryMarshaler
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(ciphertext); err != nil {
+		t.Fatalf("gob Encode error: %v", err)
+	}
+	var decoded Ciphertext
+	if err := gob.NewDecoder(&buf).Decode(&decoded); err != nil {
+		t.Fatalf("gob Decode error: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, ciphertext) {
+		t.Errorf("gob round trip = %v, want %v", decoded, ciphertext)
+	}
+
+	decrypted, err := DecryptCiphertext(cipher, decoded)
+	if err != nil || decrypted != "plaintext" {
+		t.Errorf("DecryptCiphertext() = %q, %v, want %q", decrypted, err, "plaintext")
+	}
+
+	b, _ := ciphertext.MarshalBinary()
+	s, _ := cipher.Encrypt("plaintext")
+	if len(b) >= len(s) {
+		t.Errorf("len(MarshalBinary()) = %d, want < %d of the hex string", len(b), len(s))
+	}
+}
+
+func TestCiphertext_UnmarshalBinary_error(t *testing.T) {
+	tests := map[string]struct {
+		in   []byte
+		want error
+	}{
+		"empty":      {nil, ErrCipherTextTooShort},
+		"version":    {[]byte{2, 0, 0}, ErrUnsupportedVersion},
+		"truncMode":  {[]byte{1, 3, 'G'}, ErrMalformedCiphertext},
+		"truncCodec": {[]byte{1, 3, 'G', 'C', 'M'}, ErrMalformedCiphertext},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var c Ciphertext
+			if err := c.UnmarshalBinary(tt.in); !errors.Is(err, tt.want) {
+				t.Errorf("UnmarshalBinary(%v) error = %v, want %v", tt.in, err, tt.want)
+			}
+		})
+	}
+}
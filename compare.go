@@ -0,0 +1,16 @@
+package simplecipher
+
+import "crypto/subtle"
+
+// SecureCompare reports whether a and b are equal, in constant time
+// with respect to their contents.
+//
+// Use it instead of == to compare a decrypted secret (e.g., a token)
+// to the expected value, so that the comparison does not leak through
+// timing how many leading bytes match.
+//
+// Notice that the lengths are not hidden: strings of different lengths
+// are unequal immediately.
+func SecureCompare(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
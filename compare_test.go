@@ -0,0 +1,24 @@
+package simplecipher
+
+import "testing"
+
+func TestSecureCompare(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want bool
+	}{
+		{"equal", "secret-token", "secret-token", true},
+		{"empty", "", "", true},
+		{"unequalSameLength", "secret-token", "secret-tokeN", false},
+		{"differentLength", "secret-token", "secret", false},
+		{"oneEmpty", "", "secret", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SecureCompare(tt.a, tt.b); got != tt.want {
+				t.Errorf("SecureCompare(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
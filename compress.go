@@ -0,0 +1,98 @@
+package simplecipher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// This file implements a Cipher decorator that compresses the plaintext
+// before encryption.
+
+// compressCipher is a [Cipher] decorator that gzip-compresses the plaintext
+// before passing it to the inner Cipher.
+type compressCipher struct {
+	inner Cipher
+	level int
+}
+
+var _ ModeCipher = (*compressCipher)(nil)
+
+// WithCompression wraps the inner [Cipher] to gzip-compress the plaintext
+// before encryption, and decompress it after decryption.
+// This reduces the ciphertext size of compressible plaintexts.
+//
+// The level is one of the [gzip] compression levels,
+// e.g., [gzip.DefaultCompression] or [gzip.BestCompression].
+//
+// WARNING: compression leaks information about the plaintext through the
+// ciphertext length. If an attacker can influence a part of the plaintext
+// that is encrypted together with a secret, they can recover the secret by
+// observing the ciphertext lengths (see the CRIME and BREACH attacks).
+// Do NOT use compression for plaintexts mixing attacker-controlled data
+// and secrets.
+//
+// Decrypt stops decompressing at [MaxPlaintextSize] bytes, and fails with
+// an error wrapping [ErrPlaintextTooLarge], so that a small ciphertext of
+// a decompression bomb does not exhaust the memory.
+func WithCompression(inner Cipher, level int) Cipher {
+	return &compressCipher{inner: inner, level: level}
+}
+
+// Describe describes the inner Cipher.
+func (c *compressCipher) Describe() CipherInfo {
+	return DescribeCipher(c.inner)
+}
+
+// Mode returns the mode of the inner Cipher, or "" if unknown.
+func (c *compressCipher) Mode() ModeID {
+	return modeOf(c.inner)
+}
+
+// Encrypt compresses the plaintext and encrypts it with the inner Cipher.
+func (c *compressCipher) Encrypt(plainText string) (cipherText string, err error) {
+	defer wrapOpError(&err, encrypt, modeOf(c.inner))
+	defer recoverFromPanic(&err)
+
+	compressed := new(bytes.Buffer)
+
+	writer, err := gzip.NewWriterLevel(compressed, c.level)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.WriteString(writer, plainText); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrCopy, err)
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrCopy, err)
+	}
+
+	return c.inner.Encrypt(compressed.String())
+}
+
+// Decrypt decrypts the ciphertext with the inner Cipher and decompresses it.
+func (c *compressCipher) Decrypt(cipherText string) (plainText string, err error) {
+	defer wrapOpError(&err, decrypt, modeOf(c.inner))
+	defer recoverFromPanic(&err)
+
+	compressed, err := c.inner.Decrypt(cipherText)
+	if err != nil {
+		return "", err
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader([]byte(compressed)))
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrMalformedCiphertext, err)
+	}
+
+	plaintext, err := io.ReadAll(io.LimitReader(reader, MaxPlaintextSize+1))
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrMalformedCiphertext, err)
+	}
+	if int64(len(plaintext)) > MaxPlaintextSize {
+		return "", fmt.Errorf("%w: more than %d bytes decompressed", ErrPlaintextTooLarge, MaxPlaintextSize)
+	}
+
+	return string(plaintext), nil
+}
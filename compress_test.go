@@ -0,0 +1,60 @@
+package simplecipher
+
+import (
+	"compress/gzip"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWithCompression(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	plaintexts := map[string]string{
+		"empty":        "",
+		"short":        "plaintext",
+		"compressible": strings.Repeat("plaintext", 1000),
+	}
+
+	for name, plaintext := range plaintexts {
+		createCipher := func() Cipher {
+			return WithCompression(SimpleCTR("key"), gzip.BestCompression)
+		}
+
+		testCipher(name, t, createCipher, plaintext)
+	}
+
+	plaintext := plaintexts["compressible"]
+
+	compressed, err := WithCompression(SimpleCTR("key"), gzip.DefaultCompression).Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+	uncompressed, err := SimpleCTR("key").Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+
+	if len(compressed) >= len(uncompressed) {
+		t.Errorf("len(compressed) = %v, want < %v", len(compressed), len(uncompressed))
+	}
+}
+
+func TestWithCompression_bomb(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	cipher := WithCompression(SimpleCTR("key"), gzip.BestCompression)
+	ciphertext, err := cipher.Encrypt(strings.Repeat("a", 1<<20))
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+
+	MaxPlaintextSize = 1 << 10
+	defer func() { MaxPlaintextSize = gcmMaxPlaintextSize }()
+
+	if _, err := cipher.Decrypt(ciphertext); !errors.Is(err, ErrPlaintextTooLarge) {
+		t.Errorf("Decrypt(above limit) error = %v, want %v", err, ErrPlaintextTooLarge)
+	}
+
+	testCipher("atLimit", t, func() Cipher { return cipher }, strings.Repeat("a", 1<<10))
+}
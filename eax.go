@@ -0,0 +1,43 @@
+package simplecipher
+
+import (
+	"crypto/cipher"
+	"github.com/ProtonMail/go-crypto/eax"
+	"math"
+)
+
+// This file implements the AES-EAX mode, a two-pass AEAD (CTR encryption
+// and OMAC authentication) built on the block cipher only, over the EAX
+// implementation of github.com/ProtonMail/go-crypto (used by OpenPGP).
+//
+// See also:
+//  - https://web.cs.ucdavis.edu/~rogaway/papers/eax.pdf
+
+// NewEAX creates a new AES-EAX cipher with the given key and nonce.
+//
+// EAX is slower than GCM and OCB, but it only needs the block cipher,
+// and accepts a nonce of any length. The ciphertext is followed by a
+// 16-byte tag, and the additional data is supported via [AADCipher].
+//
+// It's caller's responsibility to ensure the following:
+//
+//   - The key must be 16, 24, or 32 bytes long to select AES-128,
+//     AES-192, or AES-256.
+//   - The nonce must not be empty (16 bytes recommended),
+//     otherwise an error wrapping [ErrNonceSize] is returned.
+//   - The nonce must never be reused with the same key for different
+//     plaintexts.
+//
+// See also: [NewGCM], [NewOCB].
+func NewEAX(key, nonce Key) Cipher {
+	return &aeadCipher{
+		key:          keyOrEmpty(key),
+		nonce:        keyOrEmpty(nonce),
+		mode:         ModeEAX,
+		minNonceSize: 1,
+		maxNonceSize: math.MaxInt,
+		newAEAD: func(block cipher.Block, nonceSize int) (cipher.AEAD, error) {
+			return eax.NewEAXWithNonceAndTagSize(block, nonceSize, aeadTagSize)
+		},
+	}
+}
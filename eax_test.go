@@ -0,0 +1,98 @@
+package simplecipher
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewEAX(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	key := String("key0key1key2key3key4key5key6key7")
+
+	// any nonce size
+	for _, nonce := range []string{"n", "nonce0nonce1", "nonce0nonce1nonce2nonce3"} {
+		for _, plaintext := range []string{"", "plaintext", strings.Repeat("plaintext", 100)} {
+			testCipher(nonce, t, func() Cipher { return NewEAX(key, String(nonce)) }, plaintext)
+		}
+	}
+
+	if _, err := NewEAX(key, String("")).Encrypt("plaintext"); !errors.Is(err, ErrNonceSize) {
+		t.Errorf("Encrypt(empty nonce) error = %v, want %v", err, ErrNonceSize)
+	}
+
+	c := NewEAX(key, String("nonce0nonce1nonce")).(AADCipher)
+
+	ciphertext, err := c.EncryptWithAAD([]byte("header"), "plaintext")
+	if err != nil {
+		t.Fatalf("EncryptWithAAD error: %v", err)
+	}
+	if plaintext, err := c.DecryptWithAAD([]byte("header"), ciphertext); err != nil || plaintext != "plaintext" {
+		t.Errorf("DecryptWithAAD() = %q, %v, want %q", plaintext, err, "plaintext")
+	}
+	if _, err := c.Decrypt(ciphertext); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("Decrypt(without AAD) error = %v, want %v", err, ErrAuthenticationFailed)
+	}
+
+	raw, _ := DefaultStringCodec.DecodeString(ciphertext)
+	raw[0] ^= 1
+	if _, err := c.DecryptWithAAD([]byte("header"), DefaultStringCodec.EncodeToString(raw)); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("DecryptWithAAD(tampered) error = %v, want %v", err, ErrAuthenticationFailed)
+	}
+
+	if _, err := NewEAX(String("another0key1key2key3key4key5key6"), String("nonce0nonce1nonce")).Decrypt(ciphertext); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("Decrypt(wrong key) error = %v, want %v", err, ErrAuthenticationFailed)
+	}
+}
+
+// TestNewEAX_vectors checks the test vectors of the EAX paper.
+func TestNewEAX_vectors(t *testing.T) {
+	vectors := []struct {
+		plaintext, key, nonce, aad, ciphertext string
+	}{
+		{
+			key:        "233952DEE4D5ED5F9B9C6D6FF80FF478",
+			nonce:      "62EC67F9C3A4A407FCB2A8C49031A8B3",
+			aad:        "6BFB914FD07EAE6B",
+			ciphertext: "E037830E8389F27B025A2D6527E79D01",
+		},
+		{
+			plaintext:  "F7FB",
+			key:        "91945D3F4DCBEE0BF45EF52255F095A4",
+			nonce:      "BECAF043B0A23D843194BA972C66DEBD",
+			aad:        "FA3BFD4806EB53FA",
+			ciphertext: "19DD5C4C9331049D0BDAB0277408F67967E5",
+		},
+		{
+			plaintext:  "8B0A79306C9CE7ED99DAE4F87F8DD61636",
+			key:        "7C77D6E813BED5AC98BAA417477A2E7D",
+			nonce:      "1A8C98DCD73D38393B2BF1569DEEFC19",
+			aad:        "65D2017990D62528",
+			ciphertext: "02083E3979DA014812F59F11D52630DA30137327D10649B0AA6E1C181DB617D7F2",
+		},
+	}
+
+	for _, v := range vectors {
+		key, _ := hex.DecodeString(v.key)
+		nonce, _ := hex.DecodeString(v.nonce)
+		aad, _ := hex.DecodeString(v.aad)
+		plaintext, _ := hex.DecodeString(v.plaintext)
+
+		c := NewEAX(Bytes(key), Bytes(nonce)).(AADCipher)
+
+		ciphertext, err := c.EncryptWithAAD(aad, string(plaintext))
+		if err != nil {
+			t.Fatalf("EncryptWithAAD(%s) error: %v", v.key, err)
+		}
+		if !strings.EqualFold(ciphertext, v.ciphertext) {
+			t.Errorf("EncryptWithAAD(%s) = %s, want %s", v.key, ciphertext, v.ciphertext)
+		}
+
+		decrypted, err := c.DecryptWithAAD(aad, strings.ToLower(v.ciphertext))
+		if err != nil || decrypted != string(plaintext) {
+			t.Errorf("DecryptWithAAD(%s) = %x, %v, want %s", v.key, decrypted, err, v.plaintext)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package simplecipher
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEmptyPlaintext(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	key := String("key0key1key2key3key4key5key6key7")
+	iv := String("iv00iv01iv02iv03")
+	nonce := String("nonce0nonce1")
+
+	ciphers := []struct {
+		name string
+		new  func() Cipher
+		// wantLen is the expected length of the decoded ciphertext
+		wantLen int
+	}{
+		{"NewCBC", func() Cipher { return NewCBC(key, iv) }, 16},
+		{"SimpleCBC", func() Cipher { return SimpleCBC("key") }, 32},
+		{"NewCFB", func() Cipher { return NewCFB(key, iv) }, 16},
+		{"SimpleCFB", func() Cipher { return SimpleCFB("key") }, 16},
+		{"NewOFB", func() Cipher { return NewOFB(key, iv) }, 16},
+		{"SimpleOFB", func() Cipher { return SimpleOFB("key") }, 16},
+		{"NewCTR", func() Cipher { return NewCTR(key, iv) }, 16},
+		{"SimpleCTR", func() Cipher { return SimpleCTR("key") }, 16},
+		{"NewGCM", func() Cipher { return NewGCM(key, nonce) }, 16},
+		{"SimpleGCM", func() Cipher { return SimpleGCM("key", "nonce") }, 16},
+		{"RandomNonceGCM", func() Cipher { return NewGCMWithConfig(GCMConfig{Key: key, RandomNonce: true}) }, 28},
+		{"NewGCMSynthNonce", func() Cipher { return NewGCMSynthNonce(key) }, 28},
+		{"NewKeyring", func() Cipher { return NewKeyring(key) }, 28},
+		{"WithCompression", func() Cipher { return WithCompression(SimpleCTR("key"), -1) }, -1},
+	}
+
+	for _, c := range ciphers {
+		t.Run(c.name, func(t *testing.T) {
+			testCipher(c.name, t, c.new, "")
+
+			ciphertext, err := c.new().Encrypt("")
+			if err != nil {
+				t.Fatalf("Encrypt error: %v", err)
+			}
+
+			raw, err := DefaultStringCodec.DecodeString(ciphertext)
+			if err != nil {
+				t.Fatalf("DecodeString error: %v", err)
+			}
+			if c.wantLen >= 0 && len(raw) != c.wantLen {
+				t.Errorf("len(ciphertext) = %v, want %v", len(raw), c.wantLen)
+			}
+		})
+	}
+}
+
+func TestEmptyPlaintext_Stream(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	key := String("key0key1key2key3key4key5key6key7")
+	iv := String("iv00iv01iv02iv03")
+
+	streams := []struct {
+		name string
+		new  func() Stream
+		// wantLen is the expected length of the ciphertext
+		wantLen int
+	}{
+		{"NewCFBStream", func() Stream { return NewCFBStream(key, iv) }, 16},
+		{"SimpleCFBStream", func() Stream { return SimpleCFBStream("key") }, 16},
+		{"NewOFBStream", func() Stream { return NewOFBStream(key, iv) }, 16},
+		{"SimpleOFBStream", func() Stream { return SimpleOFBStream("key") }, 16},
+		{"NewCTRStream", func() Stream { return NewCTRStream(key, iv) }, 16},
+		{"SimpleCTRStream", func() Stream { return SimpleCTRStream("key") }, 16},
+		{"NewCTRSeekable", func() Stream { return NewCTRSeekable(key, iv) }, 16},
+		{"NewAuthenticatedCTRStream", func() Stream { return NewAuthenticatedCTRStream(key) }, 16 + 32},
+		{"NewRecordStream", func() Stream { return NewRecordStream(key, 0) }, 12 + 4 + 16},
+	}
+
+	for _, s := range streams {
+		t.Run(s.name, func(t *testing.T) {
+			testStream(s.name, t, s.new, "")
+
+			ciphertext := new(bytes.Buffer)
+			if err := s.new().EncryptStream(bytes.NewReader(nil), ciphertext); err != nil {
+				t.Fatalf("EncryptStream error: %v", err)
+			}
+			if ciphertext.Len() != s.wantLen {
+				t.Errorf("len(ciphertext) = %v, want %v", ciphertext.Len(), s.wantLen)
+			}
+		})
+	}
+}
@@ -3,7 +3,13 @@ package simplecipher
 import (
 	"encoding/base32"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strings"
+	"unicode/utf8"
 )
 
 // This file provides encoding and decoding functions for Cipher ciphertexts.
@@ -23,7 +29,8 @@ type StringCodec interface {
 // DefaultStringCodec is the default [StringCodec] used by [Cipher] implementations.
 // It is set to [HexCodec] by default.
 //
-// You can change it to [Base64StdCodec], [Base64URLCodec], [Base32StdCodec], or [Base32HexCodec]:
+// You can change it to [Base64StdCodec], [Base64URLCodec], [Base64URLNoPaddingCodec],
+// [Base32StdCodec], or [Base32HexCodec]:
 //
 //	simplecipher.DefaultStringCodec = simplecipher.Base64StdCodec
 //	ciphertext := simplecipher.SimpleCTR("strong-key").Encrypt("plaintext")
@@ -36,9 +43,156 @@ type StringCodec interface {
 //	ciphertext := simplecipher.SimpleCTR("strong-key").Encrypt("plaintext")
 //	rawCiphertextBytes := []byte(ciphertext) // rawCiphertextBytes is now the ciphertext bytes output by the algorithm without encoding.
 //
-// See also: [HexCodec], [Base64StdCodec], [Base64URLCodec], [Base32StdCodec], [Base32HexCodec], [NopCodec]
+// See also: [HexCodec], [Base64StdCodec], [Base64URLCodec], [Base64URLNoPaddingCodec], [Base32StdCodec], [Base32HexCodec], [NopCodec]
 var DefaultStringCodec StringCodec = HexCodec
 
+// MaxCiphertextLen is the maximum length in bytes of an encoded ciphertext
+// accepted by the Decrypt methods. Longer inputs are rejected with an error
+// wrapping [ErrCiphertextTooLarge] before decoding, so that untrusted inputs
+// can not cause huge allocations.
+//
+// It defaults to 64 MiB. Raise it to decrypt larger ciphertexts, or use
+// [WithMaxCiphertextLen] to lower the limit for a single Cipher.
+// A value <= 0 disables the limit.
+var MaxCiphertextLen int64 = 64 << 20
+
+// checkCipherTextLen checks the length of the encoded ciphertext against the limit.
+func checkCipherTextLen(cipherText string, limit int64) error {
+	if limit > 0 && int64(len(cipherText)) > limit {
+		return fmt.Errorf("%w: %d bytes > %d", ErrCiphertextTooLarge, len(cipherText), limit)
+	}
+	return nil
+}
+
+// decodeCipherText decodes the given ciphertext with [DefaultStringCodec].
+//
+// Ciphertexts longer than [MaxCiphertextLen] are rejected before decoding.
+// Any decoding error is wrapped with [ErrMalformedCiphertext], so that
+// callers can tell a bad input apart from a failed decryption.
+func decodeCipherText(cipherText string) ([]byte, error) {
+	if err := checkCipherTextLen(cipherText, MaxCiphertextLen); err != nil {
+		return nil, err
+	}
+
+	// fast path: a hex string of odd length can never be decoded
+	if DefaultStringCodec == HexCodec && len(cipherText)%2 != 0 {
+		return nil, fmt.Errorf("%w: odd length hex string", ErrMalformedCiphertext)
+	}
+
+	ciphertext, err := DefaultStringCodec.DecodeString(cipherText)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedCiphertext, err)
+	}
+
+	return ciphertext, nil
+}
+
+// encodeCipherText encodes the ciphertext with [DefaultStringCodec],
+// passing the error of the encryption through.
+func encodeCipherText(ciphertext []byte, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	return DefaultStringCodec.EncodeToString(ciphertext), nil
+}
+
+// maxLenCipher is a [Cipher] decorator that limits the length of the
+// ciphertexts to decrypt.
+type maxLenCipher struct {
+	inner Cipher
+	limit int64
+}
+
+var _ ModeCipher = (*maxLenCipher)(nil)
+
+// WithMaxCiphertextLen wraps the inner [Cipher] to reject encoded
+// ciphertexts longer than limit bytes with an error wrapping
+// [ErrCiphertextTooLarge], before passing them to the inner Cipher.
+//
+// The package-level [MaxCiphertextLen] still applies to the inner Cipher,
+// so this can only lower the limit.
+func WithMaxCiphertextLen(inner Cipher, limit int64) Cipher {
+	return &maxLenCipher{inner: inner, limit: limit}
+}
+
+// Describe describes the inner Cipher.
+func (c *maxLenCipher) Describe() CipherInfo {
+	return DescribeCipher(c.inner)
+}
+
+// Mode returns the mode of the inner Cipher, or "" if unknown.
+func (c *maxLenCipher) Mode() ModeID {
+	return modeOf(c.inner)
+}
+
+// Encrypt encrypts the plaintext with the inner Cipher.
+func (c *maxLenCipher) Encrypt(plainText string) (cipherText string, err error) {
+	defer wrapOpError(&err, encrypt, modeOf(c.inner))
+
+	return c.inner.Encrypt(plainText)
+}
+
+// Decrypt checks the length of the ciphertext and decrypts it with the inner Cipher.
+func (c *maxLenCipher) Decrypt(cipherText string) (plainText string, err error) {
+	defer wrapOpError(&err, decrypt, modeOf(c.inner))
+
+	if err := checkCipherTextLen(cipherText, c.limit); err != nil {
+		return "", err
+	}
+	return c.inner.Decrypt(cipherText)
+}
+
+// utf8Cipher is a [Cipher] decorator that checks the decrypted plaintexts
+// are valid UTF-8.
+type utf8Cipher struct {
+	inner Cipher
+}
+
+var _ ModeCipher = (*utf8Cipher)(nil)
+
+// WithUTF8Validation wraps the inner [Cipher] to return an error wrapping
+// [ErrInvalidUTF8] from Decrypt if the decrypted plaintext is not valid
+// UTF-8, instead of a string that would be silently corrupted later
+// (e.g., replaced with U+FFFD by [encoding/json]).
+//
+// Use it when the string API is used for text only. Binary plaintexts are
+// accepted by default for compatibility. Encrypt is not checked.
+func WithUTF8Validation(inner Cipher) Cipher {
+	return &utf8Cipher{inner: inner}
+}
+
+// Describe describes the inner Cipher.
+func (c *utf8Cipher) Describe() CipherInfo {
+	return DescribeCipher(c.inner)
+}
+
+// Mode returns the mode of the inner Cipher, or "" if unknown.
+func (c *utf8Cipher) Mode() ModeID {
+	return modeOf(c.inner)
+}
+
+// Encrypt encrypts the plaintext with the inner Cipher.
+func (c *utf8Cipher) Encrypt(plainText string) (cipherText string, err error) {
+	defer wrapOpError(&err, encrypt, modeOf(c.inner))
+
+	return c.inner.Encrypt(plainText)
+}
+
+// Decrypt decrypts the ciphertext with the inner Cipher, and checks that
+// the plaintext is valid UTF-8.
+func (c *utf8Cipher) Decrypt(cipherText string) (plainText string, err error) {
+	defer wrapOpError(&err, decrypt, modeOf(c.inner))
+
+	plainText, err = c.inner.Decrypt(cipherText)
+	if err != nil {
+		return "", err
+	}
+	if !utf8.ValidString(plainText) {
+		return "", ErrInvalidUTF8
+	}
+	return plainText, nil
+}
+
 type nopCodec struct{}
 
 func (nopCodec) EncodeToString(src []byte) string {
@@ -91,6 +245,26 @@ var Base64StdCodec StringCodec = base64Codec{base64.StdEncoding}
 // See also: [base64.URLEncoding]
 var Base64URLCodec StringCodec = base64Codec{base64.URLEncoding}
 
+// base64RawCodec is a StringCodec that encodes using unpadded base64
+// encoding, and decodes both padded and unpadded input.
+type base64RawCodec struct {
+	base64Codec
+}
+
+// DecodeString decodes a base64 string with or without the trailing padding.
+func (c base64RawCodec) DecodeString(s string) ([]byte, error) {
+	return c.Encoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// Base64URLNoPaddingCodec encodes and decodes using URL-compatible base64
+// encoding without padding, e.g., for tokens embedded in URLs:
+//   - alphabet is "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+//   - no padding character is output, and a trailing '=' padding is
+//     tolerated when decoding
+//
+// See also: [base64.RawURLEncoding]
+var Base64URLNoPaddingCodec StringCodec = base64RawCodec{base64Codec{base64.RawURLEncoding}}
+
 type base32Codec struct {
 	*base32.Encoding
 }
@@ -108,3 +282,228 @@ var Base32StdCodec StringCodec = base32Codec{base32.StdEncoding}
 //
 // See also: [base32.HexEncoding]
 var Base32HexCodec StringCodec = base32Codec{base32.HexEncoding}
+
+// Recode decodes the src string with the from codec, and re-encodes it with
+// the to codec, without decrypting it.
+//
+// It is handy to migrate ciphertexts from an encoding format to another:
+//
+//	hexCiphertext, err := simplecipher.Recode(b64Ciphertext, simplecipher.Base64StdCodec, simplecipher.HexCodec)
+//
+// An error wrapping [ErrMalformedCiphertext] is returned if src cannot be decoded.
+func Recode(src string, from, to StringCodec) (string, error) {
+	b, err := from.DecodeString(src)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrMalformedCiphertext, err)
+	}
+	return to.EncodeToString(b), nil
+}
+
+// ReEncode re-encodes the ciphertext of the ciphers of this package from
+// [DefaultStringCodec] (the codec of the ciphers) to the to codec, without
+// decrypting it, e.g., to migrate the stored ciphertexts in bulk before
+// switching DefaultStringCodec:
+//
+//	for _, row := range rows {
+//		row.Secret, err = simplecipher.ReEncode(row.Secret, simplecipher.Base64URLCodec)
+//	}
+//	simplecipher.DefaultStringCodec = simplecipher.Base64URLCodec
+//
+// It is [Recode] from DefaultStringCodec. Ciphertexts longer than
+// [MaxCiphertextLen] are rejected like by Decrypt.
+func ReEncode(cipherText string, to StringCodec) (string, error) {
+	b, err := decodeCipherText(cipherText)
+	if err != nil {
+		return "", err
+	}
+	return to.EncodeToString(b), nil
+}
+
+//////// Checksum ////////
+
+// checksumSize is the size of the CRC-32 checksum appended by [NewChecksummedCodec].
+const checksumSize = crc32.Size
+
+// checksummedCodec is a [StringCodec] appending a CRC-32 checksum to the
+// bytes before encoding them with the inner codec.
+type checksummedCodec struct {
+	inner StringCodec
+}
+
+// NewChecksummedCodec wraps the inner [StringCodec] to append a CRC-32
+// (IEEE) checksum of the raw bytes before encoding, and verify it on
+// decoding, returning an error wrapping [ErrChecksumMismatch] if the
+// encoded string has been corrupted in transit (e.g., a mistyped or
+// mangled character):
+//
+//	simplecipher.DefaultStringCodec = simplecipher.NewChecksummedCodec(simplecipher.Base64URLCodec)
+//
+// The checksum detects accidental corruption only, not tampering: anyone
+// can recompute it. Use an authenticated mode (e.g., GCM) against that.
+// The ciphertexts of the Decrypt methods are checked before decryption,
+// so that a corrupted input is told apart from a failed authentication.
+//
+// It does not implement [StreamCodec].
+func NewChecksummedCodec(inner StringCodec) StringCodec {
+	return &checksummedCodec{inner: inner}
+}
+
+// EncodeToString encodes src followed by its big-endian CRC-32.
+func (c *checksummedCodec) EncodeToString(src []byte) string {
+	b := make([]byte, 0, len(src)+checksumSize)
+	b = append(b, src...)
+	b = binary.BigEndian.AppendUint32(b, crc32.ChecksumIEEE(src))
+	return c.inner.EncodeToString(b)
+}
+
+// DecodeString decodes s, and verifies and strips the trailing CRC-32.
+func (c *checksummedCodec) DecodeString(s string) ([]byte, error) {
+	b, err := c.inner.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < checksumSize {
+		return nil, fmt.Errorf("%w: missing the checksum", ErrChecksumMismatch)
+	}
+
+	b, checksum := b[:len(b)-checksumSize], b[len(b)-checksumSize:]
+	if binary.BigEndian.Uint32(checksum) != crc32.ChecksumIEEE(b) {
+		return nil, ErrChecksumMismatch
+	}
+	return b, nil
+}
+
+//////// Streaming ////////
+
+// StreamCodec is an interface that provides incremental encoding and decoding
+// of ciphertexts in streams, keeping the memory usage bounded.
+//
+// All the [StringCodec]s provided by this package implement StreamCodec.
+type StreamCodec interface {
+	// NewEncoder returns a writer that encodes the data written to it into w.
+	// The caller must Close the encoder to flush any partially written data.
+	NewEncoder(w io.Writer) io.WriteCloser
+	// NewDecoder returns a reader that decodes the data read from r.
+	NewDecoder(r io.Reader) io.Reader
+}
+
+var (
+	_ StreamCodec = nopCodec{}
+	_ StreamCodec = hexCodec{}
+	_ StreamCodec = base64Codec{}
+	_ StreamCodec = base64RawCodec{}
+	_ StreamCodec = base32Codec{}
+)
+
+// nopWriteCloser is an [io.WriteCloser] with a no-op Close method.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func (nopCodec) NewEncoder(w io.Writer) io.WriteCloser {
+	return nopWriteCloser{w}
+}
+
+func (nopCodec) NewDecoder(r io.Reader) io.Reader {
+	return r
+}
+
+func (hexCodec) NewEncoder(w io.Writer) io.WriteCloser {
+	return nopWriteCloser{hex.NewEncoder(w)}
+}
+
+func (hexCodec) NewDecoder(r io.Reader) io.Reader {
+	return hex.NewDecoder(r)
+}
+
+func (c base64Codec) NewEncoder(w io.Writer) io.WriteCloser {
+	return base64.NewEncoder(c.Encoding, w)
+}
+
+func (c base64Codec) NewDecoder(r io.Reader) io.Reader {
+	return base64.NewDecoder(c.Encoding, r)
+}
+
+func (c base64RawCodec) NewDecoder(r io.Reader) io.Reader {
+	return base64.NewDecoder(c.Encoding, &dropPaddingReader{r: r})
+}
+
+// dropPaddingReader is an [io.Reader] dropping the trailing '=' padding
+// characters, like the DecodeString of base64RawCodec. A '=' followed by
+// another character fails with a [base64.CorruptInputError].
+type dropPaddingReader struct {
+	r         io.Reader
+	offset    int64 // offset in r of the next byte read
+	inPadding bool  // whether the last byte read is a '='
+	paddingAt int64 // offset in r of the first '=' of the padding
+}
+
+func (d *dropPaddingReader) Read(p []byte) (int, error) {
+	for {
+		n, err := d.r.Read(p)
+
+		kept := 0
+		for i, b := range p[:n] {
+			if b == '=' {
+				if !d.inPadding {
+					d.inPadding = true
+					d.paddingAt = d.offset + int64(i)
+				}
+				continue
+			}
+			if d.inPadding {
+				// not trailing: rejected like by DecodeString
+				d.offset += int64(n)
+				return kept, base64.CorruptInputError(d.paddingAt)
+			}
+			p[kept] = b
+			kept++
+		}
+		d.offset += int64(n)
+
+		// avoid returning (0, nil) for a read of padding only
+		if kept > 0 || err != nil {
+			return kept, err
+		}
+	}
+}
+
+func (c base32Codec) NewEncoder(w io.Writer) io.WriteCloser {
+	return base32.NewEncoder(c.Encoding, w)
+}
+
+func (c base32Codec) NewDecoder(r io.Reader) io.Reader {
+	return base32.NewDecoder(c.Encoding, r)
+}
+
+// codecNames maps the [StringCodec]s provided by this package to their names.
+var codecNames = map[StringCodec]string{
+	NopCodec:                "nop",
+	HexCodec:                "hex",
+	Base64StdCodec:          "base64std",
+	Base64URLCodec:          "base64url",
+	Base64URLNoPaddingCodec: "base64urlraw",
+	Base32StdCodec:          "base32std",
+	Base32HexCodec:          "base32hex",
+}
+
+// CodecName returns the name of the given [StringCodec],
+// or "" if it is not one of the codecs provided by this package.
+func CodecName(codec StringCodec) string {
+	return codecNames[codec]
+}
+
+// CodecByName returns the [StringCodec] with the given name
+// (as returned by [CodecName]), or nil if not found.
+func CodecByName(name string) StringCodec {
+	for codec, n := range codecNames {
+		if n == name {
+			return codec
+		}
+	}
+	return nil
+}
@@ -1,15 +1,25 @@
 package simplecipher
 
-import "testing"
+import (
+	"bytes"
+	"errors"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
 
 func FuzzStringCodecs(f *testing.F) {
 	codecs := map[string]StringCodec{
-		"NopCodec":       NopCodec,
-		"HexCodec":       HexCodec,
-		"Base64StdCodec": Base64StdCodec,
-		"Base64URLCodec": Base64URLCodec,
-		"Base32StdCodec": Base32StdCodec,
-		"Base32HexCodec": Base32HexCodec,
+		"NopCodec":                NopCodec,
+		"HexCodec":                HexCodec,
+		"Base64StdCodec":          Base64StdCodec,
+		"Base64URLCodec":          Base64URLCodec,
+		"Base64URLNoPaddingCodec": Base64URLNoPaddingCodec,
+		"Base32StdCodec":          Base32StdCodec,
+		"Base32HexCodec":          Base32HexCodec,
+		"ChecksummedCodec":        NewChecksummedCodec(Base64StdCodec),
 	}
 
 	// src: bytes
@@ -30,3 +40,272 @@ func FuzzStringCodecs(f *testing.F) {
 		}
 	})
 }
+
+func TestBase64URLNoPaddingCodec(t *testing.T) {
+	for n := 0; n <= 64; n++ {
+		src := make([]byte, n)
+		for i := range src {
+			src[i] = byte(i*31 + 0xfb)
+		}
+
+		encoded := Base64URLNoPaddingCodec.EncodeToString(src)
+		if strings.ContainsAny(encoded, "=+/") {
+			t.Errorf("EncodeToString(%d bytes) = %s, want no padding and URL-safe", n, encoded)
+		}
+
+		for _, input := range []string{encoded, Base64URLCodec.EncodeToString(src)} {
+			decoded, err := Base64URLNoPaddingCodec.DecodeString(input)
+			if err != nil {
+				t.Errorf("DecodeString(%s) error: %v", input, err)
+			}
+			if !bytes.Equal(decoded, src) {
+				t.Errorf("DecodeString(%s) = %x, want %x", input, decoded, src)
+			}
+
+			streamed, err := io.ReadAll(Base64URLNoPaddingCodec.(StreamCodec).NewDecoder(strings.NewReader(input)))
+			if err != nil {
+				t.Errorf("NewDecoder(%s) error: %v", input, err)
+			}
+			if !bytes.Equal(streamed, src) {
+				t.Errorf("NewDecoder(%s) = %x, want %x", input, streamed, src)
+			}
+		}
+	}
+
+	// padding is only tolerated at the end, by both decoders
+	for _, input := range []string{"QUJD=RA", "QU=JDRA==", "=QUJD"} {
+		if _, err := Base64URLNoPaddingCodec.DecodeString(input); err == nil {
+			t.Errorf("DecodeString(%s) error = nil, want an error", input)
+		}
+		if _, err := io.ReadAll(Base64URLNoPaddingCodec.(StreamCodec).NewDecoder(iotest.OneByteReader(strings.NewReader(input)))); err == nil {
+			t.Errorf("NewDecoder(%s) error = nil, want an error", input)
+		}
+	}
+
+	DefaultStringCodec = Base64URLNoPaddingCodec
+	defer func() { DefaultStringCodec = HexCodec }()
+
+	testCipher("SimpleGCM", t, func() Cipher { return SimpleGCM("key", "nonce") }, "plaintext")
+}
+
+func TestDecrypt_malformedCiphertext(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	ciphers := map[string]Cipher{
+		"SimpleCBC": SimpleCBC("key"),
+		"SimpleCTR": SimpleCTR("key"),
+		"SimpleGCM": SimpleGCM("key", "nonce"),
+	}
+
+	for name, cipher := range ciphers {
+		ciphertext, err := cipher.Encrypt("plaintext")
+		if err != nil {
+			t.Fatalf("%s: Encrypt error: %v", name, err)
+		}
+
+		tests := map[string]string{
+			"truncated": ciphertext[:len(ciphertext)-1],
+			"oddLength": "abc",
+			"notHex":    "zz" + ciphertext[2:],
+		}
+
+		for testName, malformed := range tests {
+			t.Run(name+"-"+testName, func(t *testing.T) {
+				_, err := cipher.Decrypt(malformed)
+				if !errors.Is(err, ErrMalformedCiphertext) {
+					t.Errorf("Decrypt(%q) error = %v, want %v", malformed, err, ErrMalformedCiphertext)
+				}
+			})
+		}
+	}
+}
+
+func TestDecrypt_ciphertextTooLarge(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	oversized := strings.Repeat("00", int(MaxCiphertextLen/2)+1)
+
+	ciphers := map[string]Cipher{
+		"SimpleCBC": SimpleCBC("key"),
+		"SimpleCTR": SimpleCTR("key"),
+		"SimpleGCM": SimpleGCM("key", "nonce"),
+	}
+
+	for name, cipher := range ciphers {
+		t.Run(name, func(t *testing.T) {
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+
+			_, err := cipher.Decrypt(oversized)
+
+			runtime.ReadMemStats(&after)
+
+			if !errors.Is(err, ErrCiphertextTooLarge) {
+				t.Errorf("Decrypt(oversized) error = %v, want %v", err, ErrCiphertextTooLarge)
+			}
+			if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+				t.Errorf("Decrypt(oversized) allocated %d bytes, want < 1 MiB", allocated)
+			}
+		})
+	}
+
+	limited := WithMaxCiphertextLen(SimpleCTR("key"), 64)
+	testCipher("WithMaxCiphertextLen", t, func() Cipher { return limited }, "short")
+
+	_, err := limited.Decrypt(strings.Repeat("00", 33))
+	if !errors.Is(err, ErrCiphertextTooLarge) {
+		t.Errorf("WithMaxCiphertextLen: Decrypt(oversized) error = %v, want %v", err, ErrCiphertextTooLarge)
+	}
+}
+
+func TestRecode(t *testing.T) {
+	codecs := map[string]StringCodec{
+		"HexCodec":       HexCodec,
+		"Base64StdCodec": Base64StdCodec,
+		"Base32StdCodec": Base32StdCodec,
+	}
+
+	src := []byte("👋，世界！\x00\xff")
+
+	for fromName, from := range codecs {
+		for toName, to := range codecs {
+			t.Run(fromName+"-"+toName, func(t *testing.T) {
+				recoded, err := Recode(from.EncodeToString(src), from, to)
+				if err != nil {
+					t.Fatalf("Recode error: %v", err)
+				}
+
+				decoded, err := to.DecodeString(recoded)
+				if err != nil {
+					t.Fatalf("%s.DecodeString(%s) error: %v", toName, recoded, err)
+				}
+				if string(decoded) != string(src) {
+					t.Errorf("Recode() decoded = %x, want %x", decoded, src)
+				}
+			})
+		}
+	}
+
+	_, err := Recode("not hex", HexCodec, Base64StdCodec)
+	if !errors.Is(err, ErrMalformedCiphertext) {
+		t.Errorf("Recode(malformed) error = %v, want %v", err, ErrMalformedCiphertext)
+	}
+}
+
+func TestReEncode(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+	defer func() { DefaultStringCodec = HexCodec }()
+
+	ciphers := map[string]Cipher{
+		"gcm": SimpleGCM("key", "nonce"),
+		"cbc": SimpleCBC("key"),
+		"ctr": SimpleCTR("key"),
+	}
+	for name, c := range ciphers {
+		t.Run(name, func(t *testing.T) {
+			DefaultStringCodec = HexCodec
+			hexCiphertext, _ := c.Encrypt("plaintext")
+
+			b64Ciphertext, err := ReEncode(hexCiphertext, Base64URLCodec)
+			if err != nil {
+				t.Fatalf("ReEncode() error = %v", err)
+			}
+			if len(b64Ciphertext) >= len(hexCiphertext) {
+				t.Errorf("ReEncode() = %q, want shorter than the hex %q", b64Ciphertext, hexCiphertext)
+			}
+
+			// decryptable after switching the codec
+			DefaultStringCodec = Base64URLCodec
+			plaintext, err := c.Decrypt(b64Ciphertext)
+			if err != nil || plaintext != "plaintext" {
+				t.Errorf("Decrypt(ReEncode()) = %q, %v, want %q", plaintext, err, "plaintext")
+			}
+		})
+	}
+
+	DefaultStringCodec = HexCodec
+	if _, err := ReEncode("not hex", Base64StdCodec); !errors.Is(err, ErrMalformedCiphertext) {
+		t.Errorf("ReEncode(malformed) error = %v, want %v", err, ErrMalformedCiphertext)
+	}
+}
+
+func TestNewChecksummedCodec(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	// 32 + 4 bytes: no base64 padding, every character carries data
+	src := bytes.Repeat([]byte("0123456789abcdef"), 2)
+
+	for name, inner := range map[string]StringCodec{
+		"HexCodec":       HexCodec,
+		"Base64StdCodec": Base64StdCodec,
+	} {
+		t.Run(name, func(t *testing.T) {
+			codec := NewChecksummedCodec(inner)
+
+			encoded := codec.EncodeToString(src)
+			decoded, err := codec.DecodeString(encoded)
+			if err != nil || !bytes.Equal(decoded, src) {
+				t.Fatalf("DecodeString() = %x, %v, want %x", decoded, err, src)
+			}
+
+			// every single flipped character is detected
+			for i := range encoded {
+				flipped := []byte(encoded)
+				if flipped[i] == 'a' {
+					flipped[i] = 'b'
+				} else {
+					flipped[i] = 'a'
+				}
+				if _, err := codec.DecodeString(string(flipped)); !errors.Is(err, ErrChecksumMismatch) {
+					t.Errorf("DecodeString(flipped at %d) error = %v, want %v", i, err, ErrChecksumMismatch)
+				}
+			}
+
+			if _, err := codec.DecodeString(inner.EncodeToString([]byte("abc"))); !errors.Is(err, ErrChecksumMismatch) {
+				t.Errorf("DecodeString(no checksum) error = %v, want %v", err, ErrChecksumMismatch)
+			}
+		})
+	}
+
+	DefaultStringCodec = NewChecksummedCodec(HexCodec)
+	defer func() { DefaultStringCodec = HexCodec }()
+
+	testCipher("SimpleGCM", t, func() Cipher { return SimpleGCM("key", "nonce") }, "plaintext")
+
+	ciphertext, err := SimpleGCM("key", "nonce").Encrypt("plaintext")
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+	corrupted := "0" + ciphertext[1:]
+	if corrupted == ciphertext {
+		corrupted = "1" + ciphertext[1:]
+	}
+	if _, err := SimpleGCM("key", "nonce").Decrypt(corrupted); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Decrypt(corrupted) error = %v, want %v", err, ErrChecksumMismatch)
+	}
+}
+
+func TestWithUTF8Validation(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	inner := SimpleGCM("key", "nonce")
+	c := WithUTF8Validation(inner)
+
+	for _, text := range []string{"plaintext", "👋，世界！", ""} {
+		ciphertext, _ := c.Encrypt(text)
+		plaintext, err := c.Decrypt(ciphertext)
+		if err != nil || plaintext != text {
+			t.Errorf("Decrypt(%q) = %q, %v, want %q", text, plaintext, err, text)
+		}
+	}
+
+	binary := "\x00\xff\xfe binary"
+	ciphertext, _ := inner.Encrypt(binary)
+	if _, err := c.Decrypt(ciphertext); !errors.Is(err, ErrInvalidUTF8) {
+		t.Errorf("Decrypt(binary) error = %v, want %v", err, ErrInvalidUTF8)
+	}
+	// off by default
+	if plaintext, err := inner.Decrypt(ciphertext); err != nil || plaintext != binary {
+		t.Errorf("Decrypt(binary) without validation = %q, %v, want %q", plaintext, err, binary)
+	}
+}
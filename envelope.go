@@ -0,0 +1,201 @@
+package simplecipher
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// This file implements a multi-recipient envelope (hybrid encryption).
+//
+// A random data encryption key (DEK) is generated for each message.
+// The payload is encrypted with AES-256-GCM under the DEK,
+// and the DEK is wrapped (AES-GCM encrypted) separately for each recipient key.
+//
+// The sealed envelope is laid out as follows, and then encoded with
+// [DefaultStringCodec]:
+//
+//	count (2 bytes, big-endian)
+//	count * [ wrap nonce (12 bytes) | wrapped DEK (48 bytes) ]
+//	payload nonce (12 bytes)
+//	payload ciphertext (with GCM tag)
+
+const (
+	// envelopeDekSize is the size of the random data encryption key.
+	envelopeDekSize = int(Aes256)
+	// envelopeNonceSize is the size of all the nonces used in the envelope.
+	envelopeNonceSize = int(NonceSize)
+	// envelopeWrappedDekSize is the size of a DEK sealed by AES-GCM (DEK + tag).
+	envelopeWrappedDekSize = envelopeDekSize + 16
+	// envelopeRecipientSize is the size of a recipient entry in the envelope.
+	envelopeRecipientSize = envelopeNonceSize + envelopeWrappedDekSize
+)
+
+// SealMultiRecipient encrypts the payload once, so that it can be opened
+// by any of the given recipient keys via [OpenMultiRecipient].
+//
+// Each recipient key must be 16, 24, or 32 bytes long to select AES-128,
+// AES-192, or AES-256. Use [NewAesKey] if you are not sure.
+//
+// The sealed envelope is returned with [DefaultStringCodec] encoding.
+func SealMultiRecipient(payload string, recipients []Key) (sealed string, err error) {
+	defer wrapOpError(&err, encrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	if len(recipients) == 0 {
+		return "", fmt.Errorf("%w: no recipients", ErrNoRecipient)
+	}
+	if len(recipients) > math.MaxUint16 {
+		return "", fmt.Errorf("%w: too many recipients: %d", ErrNoRecipient, len(recipients))
+	}
+
+	dek, err := randomBytes(envelopeDekSize)
+	if err != nil {
+		return "", err
+	}
+
+	envelope := binary.BigEndian.AppendUint16(nil, uint16(len(recipients)))
+
+	for i, recipient := range recipients {
+		wrapNonce, err := randomBytes(envelopeNonceSize)
+		if err != nil {
+			return "", err
+		}
+
+		wrappedDek, err := sealGCM(keyOrEmpty(recipient).Bytes(), wrapNonce, dek)
+		if err != nil {
+			return "", fmt.Errorf("recipient %d: %w", i, err)
+		}
+
+		envelope = append(envelope, wrapNonce...)
+		envelope = append(envelope, wrappedDek...)
+	}
+
+	payloadNonce, err := randomBytes(envelopeNonceSize)
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := sealGCM(dek, payloadNonce, []byte(payload))
+	if err != nil {
+		return "", err
+	}
+
+	envelope = append(envelope, payloadNonce...)
+	envelope = append(envelope, ciphertext...)
+
+	return DefaultStringCodec.EncodeToString(envelope), nil
+}
+
+// OpenMultiRecipient decrypts an envelope sealed by [SealMultiRecipient]
+// with the given recipient key.
+//
+// If myKey is not one of the recipients, an error wrapping [ErrNoRecipient]
+// is returned.
+func OpenMultiRecipient(sealed string, myKey Key) (payload string, err error) {
+	defer wrapOpError(&err, decrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	envelope, err := decodeCipherText(sealed)
+	if err != nil {
+		return "", err
+	}
+
+	if len(envelope) < 2 {
+		return "", ErrCipherTextTooShort
+	}
+
+	count := int(binary.BigEndian.Uint16(envelope))
+	envelope = envelope[2:]
+
+	if len(envelope) < count*envelopeRecipientSize+envelopeNonceSize {
+		return "", ErrCipherTextTooShort
+	}
+
+	key := keyOrEmpty(myKey).Bytes()
+
+	var dek []byte
+	for i := 0; i < count && dek == nil; i++ {
+		entry := envelope[i*envelopeRecipientSize : (i+1)*envelopeRecipientSize]
+		wrapNonce, wrappedDek := entry[:envelopeNonceSize], entry[envelopeNonceSize:]
+
+		dek, _ = openGCM(key, wrapNonce, wrappedDek)
+	}
+	if dek == nil {
+		return "", ErrNoRecipient
+	}
+
+	envelope = envelope[count*envelopeRecipientSize:]
+	payloadNonce, ciphertext := envelope[:envelopeNonceSize], envelope[envelopeNonceSize:]
+
+	plaintext, err := openGCM(dek, payloadNonce, ciphertext)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// sealGCM encrypts and authenticates the plaintext with AES-GCM.
+func sealGCM(key, nonce, plaintext []byte) ([]byte, error) {
+	block, err := newAesBlock(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNewAesCipher, err)
+	}
+
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return aesgcm.Seal(nil, nonce, plaintext, nil), nil
+}
+
+// openGCM decrypts and authenticates the ciphertext with AES-GCM.
+func openGCM(key, nonce, ciphertext []byte) ([]byte, error) {
+	block, err := newAesBlock(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNewAesCipher, err)
+	}
+
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := aesgcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAuthenticationFailed, err)
+	}
+
+	return plaintext, nil
+}
+
+// randomBytes returns n bytes read from [rand.Reader].
+// The callers do not take a source of randomness (see [WithRand]).
+func randomBytes(n int) ([]byte, error) {
+	return randomBytesFrom(nil, n)
+}
+
+// randomBytesFrom returns n bytes read from r, or [rand.Reader] if r is nil.
+func randomBytesFrom(r io.Reader, n int) ([]byte, error) {
+	b := make([]byte, n)
+	if err := readRandom(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// readRandom fills b with bytes read from r, or [rand.Reader] if r is nil.
+func readRandom(r io.Reader, b []byte) error {
+	if r == nil {
+		r = rand.Reader
+	}
+	if _, err := io.ReadFull(r, b); err != nil {
+		return fmt.Errorf("read random: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package simplecipher
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMultiRecipient(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	recipients := []Key{
+		NewAesKey("alice"),
+		NewAesKey("bob"),
+		NewAesKey("carol", WithLen(Aes128)),
+	}
+	payload := "Hello, everyone!"
+
+	sealed, err := SealMultiRecipient(payload, recipients)
+	if err != nil {
+		t.Fatalf("SealMultiRecipient error: %v", err)
+	}
+
+	for i, recipient := range recipients {
+		opened, err := OpenMultiRecipient(sealed, recipient)
+		if err != nil {
+			t.Fatalf("recipient %d: OpenMultiRecipient error: %v", i, err)
+		}
+		if opened != payload {
+			t.Fatalf("recipient %d: opened (%s) != payload (%s)", i, opened, payload)
+		}
+	}
+
+	_, err = OpenMultiRecipient(sealed, NewAesKey("eve"))
+	if !errors.Is(err, ErrNoRecipient) {
+		t.Fatalf("non-recipient: OpenMultiRecipient error = %v, want %v", err, ErrNoRecipient)
+	}
+}
+
+func FuzzMultiRecipient(f *testing.F) {
+	// key: bytes, payload: string
+	f.Add([]byte("key0key1key2key3"), "payload")
+	f.Add([]byte("badkey"), "payload")
+
+	f.Fuzz(func(t *testing.T, key []byte, payload string) {
+		recipients := []Key{Bytes(key), NewAesKey("another")}
+
+		sealed, err := SealMultiRecipient(payload, recipients)
+		if len(key) != 16 && len(key) != 24 && len(key) != 32 {
+			if err == nil {
+				t.Fatalf("badKeyLen: expected SealMultiRecipient error, got none")
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("SealMultiRecipient error: %v", err)
+		}
+
+		opened, err := OpenMultiRecipient(sealed, Bytes(key))
+		if err != nil {
+			t.Fatalf("OpenMultiRecipient error: %v", err)
+		}
+		if opened != payload {
+			t.Fatalf("opened (%s) != payload (%s)", opened, payload)
+		}
+	})
+}
@@ -0,0 +1,264 @@
+package simplecipher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"io"
+)
+
+// This file implements an authenticated stream cipher with the
+// Encrypt-then-MAC (EtM) construction: AES-CTR + HMAC-SHA256.
+//
+// The stream output is laid out as:
+//
+//	iv (16 bytes) | ciphertext | HMAC-SHA256(iv | ciphertext) (32 bytes, or truncated)
+//
+// See also:
+//  - https://en.wikipedia.org/wiki/Authenticated_encryption#Encrypt-then-MAC_(EtM)
+
+// HKDF info labels of the subkeys used by the EtM streams.
+const (
+	hkdfInfoEtmEncKey = "simplecipher etm encryption key"
+	hkdfInfoEtmMacKey = "simplecipher etm mac key"
+)
+
+// authCTRStream is the AES-CTR + HMAC-SHA256 implementation for the
+// [Stream] interface.
+type authCTRStream struct {
+	// encKey is the AES-256 subkey for CTR encryption.
+	encKey Key
+	// macKey is the subkey for HMAC-SHA256.
+	macKey Key
+	// macSize is the size of the (truncated) HMAC tag, 0 for the full 32 bytes.
+	macSize int
+}
+
+var (
+	_ Stream    = (*authCTRStream)(nil)
+	_ Validator = (*authCTRStream)(nil)
+)
+
+// MinMACSize is the minimum size in bytes of a truncated HMAC tag
+// accepted by [WithMACSize].
+const MinMACSize = 16
+
+// EtMOption is a functional option of the Encrypt-then-MAC stream
+// created by [NewAuthenticatedCTRStream].
+type EtMOption func(s *authCTRStream)
+
+// WithMACSize truncates the HMAC-SHA256 tag to its first n bytes,
+// between [MinMACSize] (16) and 32, to save space.
+//
+// A shorter tag is easier to forge: an attacker guessing a tag succeeds
+// with a probability of 2^(-8n) per attempt, i.e., 2^-128 for 16 bytes,
+// which is as strong as the tag of GCM. Both sides must use the same size.
+// An n out of range makes the stream fail with an error wrapping
+// [ErrInvalidConfig].
+func WithMACSize(n int) EtMOption {
+	return func(s *authCTRStream) {
+		s.macSize = n
+	}
+}
+
+// NewAuthenticatedCTRStream creates a new AES-256-CTR stream cipher
+// authenticated with HMAC-SHA256 (Encrypt-then-MAC).
+//
+// Independent encryption and MAC subkeys are derived from the key via HKDF,
+// so the key can be any high-entropy secret, e.g., [NewAesKey].
+//
+// A random iv is generated for each encryption and prepended to the
+// ciphertext, and an HMAC over the iv and the ciphertext is appended to it.
+//
+// DecryptStream verifies the HMAC at the end of the stream, and returns an
+// error wrapping [ErrAuthenticationFailed] if the stream has been tampered
+// with. Notice that the plaintext is written to the writer as it is
+// decrypted, before the verification: the error also wraps
+// [ErrPartialPlaintext] if any plaintext has been written.
+// Discard the output if an error is returned, or use [NewRecordStream]
+// to never write unauthenticated plaintext.
+//
+// Use [WithMACSize] to truncate the HMAC.
+func NewAuthenticatedCTRStream(key Key, options ...EtMOption) Stream {
+	key = keyOrEmpty(key)
+	s := &authCTRStream{
+		encKey: &hkdfKey{Secret: key, Info: hkdfInfoEtmEncKey, Len: Aes256},
+		macKey: &hkdfKey{Secret: key, Info: hkdfInfoEtmMacKey, Len: sha256.Size},
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// tagSize returns the size of the HMAC tag.
+func (s *authCTRStream) tagSize() int {
+	if s.macSize == 0 {
+		return sha256.Size
+	}
+	return s.macSize
+}
+
+// Validate checks the size of the HMAC tag.
+func (s *authCTRStream) Validate() error {
+	if n := s.tagSize(); n < MinMACSize || n > sha256.Size {
+		return fmt.Errorf("%w: MAC size %d not in [%d, %d]", ErrInvalidConfig, n, MinMACSize, sha256.Size)
+	}
+	return nil
+}
+
+// Mode returns [ModeCTR].
+func (s *authCTRStream) Mode() ModeID {
+	return ModeCTR
+}
+
+// Describe describes the stream cipher: AES-256-CTR+HMAC-SHA256,
+// or e.g. AES-256-CTR+HMAC-SHA256-128 with a 16-byte truncated tag.
+func (s *authCTRStream) Describe() CipherInfo {
+	mac := macHmacSha256
+	if n := s.tagSize(); n != sha256.Size {
+		mac = fmt.Sprintf("%s-%d", macHmacSha256, n*8)
+	}
+	return CipherInfo{Algorithm: algorithmAES, Mode: ModeCTR, KeyBits: int(Aes256) * 8, MAC: mac}
+}
+
+// EncryptStream encrypts the given plaintext using CTR,
+// and appends the HMAC of the iv and the ciphertext.
+func (s *authCTRStream) EncryptStream(plainText io.Reader, cipherText io.Writer) (err error) {
+	defer wrapOpError(&err, encrypt, ModeCTR)
+	defer recoverFromPanic(&err)
+
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
+	iv := NewRandomIv().Bytes()
+
+	block, err := newAesBlock(s.encKey.Bytes())
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrNewAesCipher, err)
+	}
+
+	stream, err := ctrStreamBuilder(block, iv, encrypt)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrNewAesCipher, err)
+	}
+
+	mac := hmac.New(sha256.New, s.macKey.Bytes())
+	out := io.MultiWriter(cipherText, mac)
+
+	if _, err := out.Write(iv); err != nil {
+		return fmt.Errorf("%w: %w", ErrCopy, err)
+	}
+
+	writer := &cipher.StreamWriter{S: stream, W: out}
+	if _, err := io.Copy(writer, plainText); err != nil {
+		return fmt.Errorf("%w: %w", ErrCopy, err)
+	}
+
+	if _, err := cipherText.Write(mac.Sum(nil)[:s.tagSize()]); err != nil {
+		return fmt.Errorf("%w: %w", ErrCopy, err)
+	}
+
+	return nil
+}
+
+// DecryptStream decrypts the given ciphertext using CTR,
+// and verifies the trailing HMAC at the end of the stream.
+func (s *authCTRStream) DecryptStream(cipherText io.Reader, plainText io.Writer) (err error) {
+	defer wrapOpError(&err, decrypt, ModeCTR)
+	defer recoverFromPanic(&err)
+
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
+	mac := hmac.New(sha256.New, s.macKey.Bytes())
+
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(cipherText, iv); err != nil {
+		return fmt.Errorf("%w: %w", ErrCopy, err)
+	}
+	mac.Write(iv)
+
+	block, err := newAesBlock(s.encKey.Bytes())
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrNewAesCipher, err)
+	}
+
+	stream, err := ctrStreamBuilder(block, iv, decrypt)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrNewAesCipher, err)
+	}
+
+	trailer := newTrailerReader(cipherText, s.tagSize())
+
+	reader := &cipher.StreamReader{S: stream, R: io.TeeReader(trailer, mac)}
+	written, err := io.Copy(plainText, reader)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCopy, err)
+	}
+
+	if len(trailer.Trailer()) != s.tagSize() {
+		return ErrCipherTextTooShort
+	}
+	// compare the truncated length only, in constant time
+	if !hmac.Equal(trailer.Trailer(), mac.Sum(nil)[:s.tagSize()]) {
+		if written > 0 {
+			return fmt.Errorf("%w (%d bytes): %w: hmac mismatch", ErrPartialPlaintext, written, ErrAuthenticationFailed)
+		}
+		return fmt.Errorf("%w: hmac mismatch", ErrAuthenticationFailed)
+	}
+
+	return nil
+}
+
+//////// trailerReader ////////
+
+// trailerReaderBufSize is the size of the read buffer of trailerReader.
+const trailerReaderBufSize = 32 * 1024
+
+// trailerReader is an [io.Reader] that withholds the last n bytes
+// (the trailer) of the underlying reader.
+//
+// Read returns all the bytes but the trailer, and the trailer is available
+// via Trailer after Read returns io.EOF.
+type trailerReader struct {
+	r     io.Reader
+	n     int
+	store []byte
+	buf   []byte // unread bytes in store, including the (potential) trailer
+	err   error
+}
+
+func newTrailerReader(r io.Reader, n int) *trailerReader {
+	store := make([]byte, n+trailerReaderBufSize)
+	return &trailerReader{r: r, n: n, store: store, buf: store[:0]}
+}
+
+func (t *trailerReader) Read(p []byte) (int, error) {
+	for len(t.buf) <= t.n {
+		if t.err != nil {
+			return 0, t.err
+		}
+
+		// compact and read more
+		copy(t.store, t.buf)
+		m, err := t.r.Read(t.store[len(t.buf):])
+		t.buf = t.store[:len(t.buf)+m]
+		t.err = err
+	}
+
+	k := copy(p, t.buf[:len(t.buf)-t.n])
+	t.buf = t.buf[k:]
+
+	return k, nil
+}
+
+// Trailer returns the withheld trailer. It may be shorter than n bytes,
+// if the underlying reader is.
+func (t *trailerReader) Trailer() []byte {
+	return t.buf
+}
@@ -0,0 +1,109 @@
+package simplecipher
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func FuzzNewAuthenticatedCTRStream(f *testing.F) {
+	// key: string, plaintext: string
+	f.Add("key", "plain-text-plain-text000")
+	f.Add("", "")
+
+	f.Fuzz(func(t *testing.T, key, plaintext string) {
+		createStream := func() Stream {
+			return NewAuthenticatedCTRStream(NewAesKey(key))
+		}
+
+		testStream("", t, createStream, plaintext)
+	})
+}
+
+func TestAuthenticatedCTRStream_tampered(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	stream := NewAuthenticatedCTRStream(NewAesKey("key"))
+	plaintext := strings.Repeat("plaintext", 10000)
+
+	ciphertext := new(bytes.Buffer)
+	if err := stream.EncryptStream(strings.NewReader(plaintext), ciphertext); err != nil {
+		t.Fatalf("EncryptStream error: %v", err)
+	}
+
+	tests := map[string]func(b []byte) []byte{
+		"iv":        func(b []byte) []byte { b[0] ^= 1; return b },
+		"middle":    func(b []byte) []byte { b[len(b)/2] ^= 1; return b },
+		"mac":       func(b []byte) []byte { b[len(b)-1] ^= 1; return b },
+		"truncated": func(b []byte) []byte { return b[:len(b)-100] },
+	}
+	for name, tamper := range tests {
+		t.Run(name, func(t *testing.T) {
+			tampered := tamper(bytes.Clone(ciphertext.Bytes()))
+
+			err := stream.DecryptStream(bytes.NewReader(tampered), new(bytes.Buffer))
+			if !errors.Is(err, ErrAuthenticationFailed) {
+				t.Errorf("DecryptStream(tampered) error = %v, want %v", err, ErrAuthenticationFailed)
+			}
+			if name == "mac" && !errors.Is(err, ErrPartialPlaintext) {
+				t.Errorf("DecryptStream(tampered mac) error = %v, want %v", err, ErrPartialPlaintext)
+			}
+		})
+	}
+
+	err := stream.DecryptStream(bytes.NewReader(ciphertext.Bytes()[:20]), new(bytes.Buffer))
+	if !errors.Is(err, ErrCipherTextTooShort) {
+		t.Errorf("DecryptStream(too short) error = %v, want %v", err, ErrCipherTextTooShort)
+	}
+}
+
+func TestAuthenticatedCTRStream_WithMACSize(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	plaintext := strings.Repeat("plaintext", 100)
+
+	full := NewAuthenticatedCTRStream(NewAesKey("key"))
+	truncated := NewAuthenticatedCTRStream(NewAesKey("key"), WithMACSize(MinMACSize))
+
+	testStream("", t, func() Stream { return truncated }, plaintext)
+
+	var fullOut, truncatedOut bytes.Buffer
+	_ = full.EncryptStream(strings.NewReader(plaintext), &fullOut)
+	if err := truncated.EncryptStream(strings.NewReader(plaintext), &truncatedOut); err != nil {
+		t.Fatalf("EncryptStream error: %v", err)
+	}
+	if fullOut.Len()-truncatedOut.Len() != 32-MinMACSize {
+		t.Errorf("EncryptStream wrote %d bytes, want %d less than the full tag", truncatedOut.Len(), 32-MinMACSize)
+	}
+
+	for name, tamper := range map[string]func(b []byte) []byte{
+		"middle": func(b []byte) []byte { b[len(b)/2] ^= 1; return b },
+		"mac":    func(b []byte) []byte { b[len(b)-1] ^= 1; return b },
+	} {
+		tampered := tamper(bytes.Clone(truncatedOut.Bytes()))
+		err := truncated.DecryptStream(bytes.NewReader(tampered), new(bytes.Buffer))
+		if !errors.Is(err, ErrAuthenticationFailed) {
+			t.Errorf("DecryptStream(tampered %s) error = %v, want %v", name, err, ErrAuthenticationFailed)
+		}
+	}
+
+	// the sizes must match
+	if err := full.DecryptStream(bytes.NewReader(truncatedOut.Bytes()), new(bytes.Buffer)); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("DecryptStream(other MAC size) error = %v, want %v", err, ErrAuthenticationFailed)
+	}
+
+	if info := DescribeStream(truncated).String(); info != "AES-256-CTR+HMAC-SHA256-128" {
+		t.Errorf("DescribeStream() = %s, want AES-256-CTR+HMAC-SHA256-128", info)
+	}
+
+	for _, n := range []int{MinMACSize - 1, 33} {
+		s := NewAuthenticatedCTRStream(NewAesKey("key"), WithMACSize(n))
+		if err := ValidateStream(s); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("ValidateStream(WithMACSize(%d)) error = %v, want %v", n, err, ErrInvalidConfig)
+		}
+		if err := s.EncryptStream(strings.NewReader(plaintext), new(bytes.Buffer)); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("EncryptStream(WithMACSize(%d)) error = %v, want %v", n, err, ErrInvalidConfig)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package simplecipher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// This file implements the FF1 format-preserving encryption
+// (NIST SP 800-38G), for values like credit card or account numbers
+// that must stay numeric (or alphanumeric) and keep their length.
+//
+// It follows Algorithms 7 and 8 of the spec step by step, and is checked
+// against all the nine NIST FF1 samples (AES-128, AES-192 and AES-256,
+// radix 10 and 36) in the tests.
+
+// ff1Alphabet is the alphabet of the numerals of the radixes supported by
+// [NewFF1]: the first radix characters are used.
+const ff1Alphabet = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+const (
+	// ff1Rounds is the number of Feistel rounds of FF1.
+	ff1Rounds = 10
+	// ff1MinDomain is the minimum size (radix^minlen) of the domain
+	// required by NIST SP 800-38G Rev. 1.
+	ff1MinDomain = 1_000_000
+	// ff1MaxLen is the maximum length of the input, 2^32 by the spec,
+	// limited further to keep the numbers reasonably small.
+	ff1MaxLen = 4096
+)
+
+// ff1 is a [Cipher] encrypting strings with FF1.
+type ff1 struct {
+	key   Key
+	radix int
+	tweak []byte
+}
+
+var (
+	_ Cipher    = (*ff1)(nil)
+	_ Validator = (*ff1)(nil)
+)
+
+// NewFF1 creates a format-preserving [Cipher] with FF1 (NIST SP 800-38G),
+// using AES with the key (16, 24, or 32 bytes).
+//
+// The plaintexts and the ciphertexts are strings of the numerals of the
+// radix (2 to 36): the first radix characters of "0-9a-z". For example,
+// radix 10 for decimal digits, and radix 36 for lowercase alphanumerics.
+// Encrypt returns a ciphertext of the same length and alphabet as the
+// plaintext, instead of a [DefaultStringCodec] encoded one:
+//
+//	c := simplecipher.NewFF1(key, 10, []byte("card"))
+//	ct, _ := c.Encrypt("4111111111111111") // 16 decimal digits
+//
+// The tweak is a public value that changes the permutation, like a salt,
+// and can be nil.
+//
+// An input with characters out of the alphabet is rejected with an error
+// wrapping [ErrUnsupportedType]. Inputs must be long enough that
+// radix^len >= 1,000,000 (e.g., 6 decimal digits), otherwise an error
+// wrapping [ErrInvalidConfig] is returned.
+//
+// FF1 is deterministic and NOT authenticated: equal plaintexts (with the
+// same tweak) produce equal ciphertexts, and any ciphertext decrypts.
+func NewFF1(key Key, radix int, tweak []byte) Cipher {
+	return &ff1{key: keyOrEmpty(key), radix: radix, tweak: tweak}
+}
+
+// Mode returns [ModeFF1].
+func (f *ff1) Mode() ModeID {
+	return ModeFF1
+}
+
+// Describe describes the cipher, e.g., AES-256-FF1.
+func (f *ff1) Describe() CipherInfo {
+	return CipherInfo{Algorithm: algorithmAES, Mode: ModeFF1, KeyBits: keyBits(f.key)}
+}
+
+// Validate checks the length of the key, and the radix.
+func (f *ff1) Validate() (err error) {
+	defer recoverFromPanic(&err)
+
+	if f.radix < 2 || f.radix > len(ff1Alphabet) {
+		return fmt.Errorf("%w: FF1 radix %d out of range [2, %d]", ErrInvalidConfig, f.radix, len(ff1Alphabet))
+	}
+	return validateAesKey(f.key)
+}
+
+// Encrypt encrypts the numeral string.
+func (f *ff1) Encrypt(plainText string) (cipherText string, err error) {
+	defer wrapOpError(&err, encrypt, ModeFF1)
+	defer recoverFromPanic(&err)
+
+	return f.crypt(plainText, true)
+}
+
+// Decrypt decrypts the numeral string.
+func (f *ff1) Decrypt(cipherText string) (plainText string, err error) {
+	defer wrapOpError(&err, decrypt, ModeFF1)
+	defer recoverFromPanic(&err)
+
+	return f.crypt(cipherText, false)
+}
+
+// crypt runs the FF1 encryption (Algorithm 7) or decryption (Algorithm 8).
+func (f *ff1) crypt(s string, encrypt bool) (string, error) {
+	if err := f.Validate(); err != nil {
+		return "", err
+	}
+
+	x, err := f.numerals(s)
+	if err != nil {
+		return "", err
+	}
+
+	n := len(x)
+	if n < 2 || math.Pow(float64(f.radix), float64(n)) < ff1MinDomain {
+		return "", fmt.Errorf("%w: FF1 input of %d numerals is too short for radix %d", ErrInvalidConfig, n, f.radix)
+	}
+	if n > ff1MaxLen {
+		return "", fmt.Errorf("%w: FF1 input of %d numerals, max %d", ErrPlaintextTooLarge, n, ff1MaxLen)
+	}
+
+	block, err := newAesBlock(f.key.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrNewAesCipher, err)
+	}
+
+	t := len(f.tweak)
+	u := n / 2
+	v := n - u
+	a, b := x[:u], x[u:]
+
+	// b is the byte length of NUM_radix of the (longer) half,
+	// d the byte length of the round output.
+	bLen := int(math.Ceil(math.Ceil(float64(v)*math.Log2(float64(f.radix))) / 8))
+	dLen := 4*((bLen+3)/4) + 4
+
+	p := make([]byte, 0, aes.BlockSize)
+	p = append(p, 1, 2, 1)
+	p = append(p, byte(f.radix>>16), byte(f.radix>>8), byte(f.radix))
+	p = append(p, 10, byte(u))
+	p = binary.BigEndian.AppendUint32(p, uint32(n))
+	p = binary.BigEndian.AppendUint32(p, uint32(t))
+
+	radix := big.NewInt(int64(f.radix))
+	modU := new(big.Int).Exp(radix, big.NewInt(int64(u)), nil)
+	modV := new(big.Int).Exp(radix, big.NewInt(int64(v)), nil)
+
+	// Q = T || [0]^((-t-b-1) mod 16) || [i]^1 || [NUM_radix(B)]^b
+	zeros := ((-t-bLen-1)%16 + 16) % 16
+	q := make([]byte, t+zeros+1+bLen)
+	copy(q, f.tweak)
+
+	round := func(i int, half []int) *big.Int {
+		q[t+zeros] = byte(i)
+		num := f.num(half).FillBytes(make([]byte, bLen))
+		copy(q[t+zeros+1:], num)
+
+		r := ff1PRF(block, append(p[:len(p):len(p)], q...))
+		return new(big.Int).SetBytes(ff1Expand(block, r, dLen))
+	}
+
+	c := new(big.Int)
+	if encrypt {
+		for i := 0; i < ff1Rounds; i++ {
+			y := round(i, b)
+			m, mod := v, modV
+			if i%2 == 0 {
+				m, mod = u, modU
+			}
+			c.Add(f.num(a), y).Mod(c, mod)
+			a, b = b, f.str(c, m)
+		}
+	} else {
+		for i := ff1Rounds - 1; i >= 0; i-- {
+			y := round(i, a)
+			m, mod := v, modV
+			if i%2 == 0 {
+				m, mod = u, modU
+			}
+			c.Sub(f.num(b), y).Mod(c, mod)
+			a, b = f.str(c, m), a
+		}
+	}
+
+	return f.format(append(a[:len(a):len(a)], b...)), nil
+}
+
+// ff1PRF is the CBC-MAC of the data (a multiple of the block size).
+func ff1PRF(block cipher.Block, data []byte) []byte {
+	r := make([]byte, aes.BlockSize)
+	for i := 0; i < len(data); i += aes.BlockSize {
+		for j := range r {
+			r[j] ^= data[i+j]
+		}
+		block.Encrypt(r, r)
+	}
+	return r
+}
+
+// ff1Expand returns the first d bytes of R || CIPH(R ^ [1]^16) || CIPH(R ^ [2]^16) ...
+func ff1Expand(block cipher.Block, r []byte, d int) []byte {
+	s := append(make([]byte, 0, d+aes.BlockSize), r...)
+	for j := 1; len(s) < d; j++ {
+		x := make([]byte, aes.BlockSize)
+		binary.BigEndian.PutUint64(x[8:], uint64(j))
+		for k := range x {
+			x[k] ^= r[k]
+		}
+		block.Encrypt(x, x)
+		s = append(s, x...)
+	}
+	return s[:d]
+}
+
+// numerals converts the string to its numerals in the radix.
+func (f *ff1) numerals(s string) ([]int, error) {
+	x := make([]int, len(s))
+	for i := 0; i < len(s); i++ {
+		n := strings.IndexByte(ff1Alphabet[:f.radix], s[i])
+		if n < 0 {
+			return nil, fmt.Errorf("%w: character at %d is not a numeral of radix %d", ErrUnsupportedType, i, f.radix)
+		}
+		x[i] = n
+	}
+	return x, nil
+}
+
+// format converts the numerals to a string.
+func (f *ff1) format(x []int) string {
+	var sb strings.Builder
+	sb.Grow(len(x))
+	for _, n := range x {
+		sb.WriteByte(ff1Alphabet[n])
+	}
+	return sb.String()
+}
+
+// num is NUM_radix(X): the number represented by the numerals, most
+// significant first.
+func (f *ff1) num(x []int) *big.Int {
+	radix := big.NewInt(int64(f.radix))
+	n := new(big.Int)
+	for _, d := range x {
+		n.Mul(n, radix).Add(n, big.NewInt(int64(d)))
+	}
+	return n
+}
+
+// str is STR^m_radix(x): the m numerals representing x.
+func (f *ff1) str(x *big.Int, m int) []int {
+	radix := big.NewInt(int64(f.radix))
+	x = new(big.Int).Set(x)
+	d := new(big.Int)
+	out := make([]int, m)
+	for i := m - 1; i >= 0; i-- {
+		x.DivMod(x, radix, d)
+		out[i] = int(d.Int64())
+	}
+	return out
+}
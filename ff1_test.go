@@ -0,0 +1,110 @@
+package simplecipher
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// TestFF1_nist checks the FF1 samples of NIST SP 800-38G
+// (https://csrc.nist.gov/CSRC/media/Projects/Cryptographic-Standards-and-Guidelines/documents/examples/FF1samples.pdf).
+func TestFF1_nist(t *testing.T) {
+	const (
+		key128 = "2b7e151628aed2a6abf7158809cf4f3c"
+		key192 = "2b7e151628aed2a6abf7158809cf4f3cef4359d8d580aa4f"
+		key256 = "2b7e151628aed2a6abf7158809cf4f3cef4359d8d580aa4f7f036d6f04fc6a94"
+	)
+	tests := []struct {
+		name       string
+		key        string
+		radix      int
+		tweak      string
+		plaintext  string
+		ciphertext string
+	}{
+		{"sample1", key128, 10, "", "0123456789", "2433477484"},
+		{"sample2", key128, 10, "39383736353433323130", "0123456789", "6124200773"},
+		{"sample3", key128, 36, "3737373770717273373737", "0123456789abcdefghi", "a9tv40mll9kdu509eum"},
+		{"sample4", key192, 10, "", "0123456789", "2830668132"},
+		{"sample5", key192, 10, "39383736353433323130", "0123456789", "2496655549"},
+		{"sample6", key192, 36, "3737373770717273373737", "0123456789abcdefghi", "xbj3kv35jrawxv32ysr"},
+		{"sample7", key256, 10, "", "0123456789", "6657667009"},
+		{"sample8", key256, 10, "39383736353433323130", "0123456789", "1001623463"},
+		{"sample9", key256, 36, "3737373770717273373737", "0123456789abcdefghi", "xs8a0azh2avyalyzuwd"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, _ := hex.DecodeString(tt.key)
+			tweak, _ := hex.DecodeString(tt.tweak)
+			c := NewFF1(Bytes(key), tt.radix, tweak)
+
+			got, err := c.Encrypt(tt.plaintext)
+			if err != nil || got != tt.ciphertext {
+				t.Errorf("Encrypt() = %q, %v, want %q", got, err, tt.ciphertext)
+			}
+
+			got, err = c.Decrypt(tt.ciphertext)
+			if err != nil || got != tt.plaintext {
+				t.Errorf("Decrypt() = %q, %v, want %q", got, err, tt.plaintext)
+			}
+		})
+	}
+}
+
+func TestFF1_roundTrip(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	key := NewAesKey("key")
+	tests := []struct {
+		name      string
+		radix     int
+		plaintext string
+	}{
+		{"card", 10, "4111111111111111"},
+		{"oddLength", 10, "1234567"},
+		{"leadingZeros", 10, "000000000042"},
+		{"alphanumeric", 36, "acct0042xyz"},
+		{"binary", 2, "10110011101010110010"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewFF1(key, tt.radix, []byte("tweak"))
+
+			ciphertext, err := c.Encrypt(tt.plaintext)
+			if err != nil {
+				t.Fatalf("Encrypt() error = %v", err)
+			}
+			if len(ciphertext) != len(tt.plaintext) {
+				t.Errorf("Encrypt() = %q, length %d, want %d", ciphertext, len(ciphertext), len(tt.plaintext))
+			}
+			if _, err := NewFF1(key, tt.radix, nil).Decrypt(ciphertext); err != nil {
+				t.Errorf("Decrypt(other tweak) error = %v", err)
+			}
+
+			plaintext, err := c.Decrypt(ciphertext)
+			if err != nil || plaintext != tt.plaintext {
+				t.Errorf("Decrypt() = %q, %v, want %q", plaintext, err, tt.plaintext)
+			}
+		})
+	}
+}
+
+func TestFF1_invalid(t *testing.T) {
+	key := Bytes(make([]byte, 16))
+
+	if _, err := NewFF1(key, 10, nil).Encrypt("12345x7890"); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("Encrypt(non-numeral) error = %v, want %v", err, ErrUnsupportedType)
+	}
+	if _, err := NewFF1(key, 16, nil).Encrypt("0123456789ABCDEF"); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("Encrypt(uppercase) error = %v, want %v", err, ErrUnsupportedType)
+	}
+	if _, err := NewFF1(key, 10, nil).Encrypt("12345"); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Encrypt(too short) error = %v, want %v", err, ErrInvalidConfig)
+	}
+	if _, err := NewFF1(key, 37, nil).Encrypt("0123456789"); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Encrypt(radix 37) error = %v, want %v", err, ErrInvalidConfig)
+	}
+	if _, err := NewFF1(Bytes(make([]byte, 10)), 10, nil).Encrypt("0123456789"); !errors.Is(err, ErrKeySize) {
+		t.Errorf("Encrypt(short key) error = %v, want %v", err, ErrKeySize)
+	}
+}
@@ -0,0 +1,90 @@
+package simplecipher
+
+import (
+	"fmt"
+	"strings"
+)
+
+// This file provides helpers to encrypt file paths component by component,
+// for encrypted file systems and backups.
+
+// MaxFilenameLen is the maximum length in bytes of an encrypted path
+// component produced by [EncryptFilename]: the common limit of the file
+// systems (e.g., ext4, APFS, and NTFS in UTF-16 units).
+const MaxFilenameLen = 255
+
+// EncryptFilename encrypts each component of the slash-separated path
+// independently with the cipher, keeping the separators, so that the
+// directory structure is preserved:
+//
+//	c := simplecipher.NewGCMSynthNonce(key)
+//	name, err := simplecipher.EncryptFilename(c, "photos/2024/beach.jpg")
+//	// name is like "AbC.../dEf.../GhI..."
+//
+// Each encrypted component is encoded with [Base64URLNoPaddingCodec],
+// which is safe in file names. Empty components (i.e., leading, trailing
+// or repeated slashes), "." and ".." are kept as is.
+//
+// Use a deterministic cipher (e.g., [NewGCMSynthNonce]), so that the same
+// name always encrypts to the same one: otherwise a file can not be looked
+// up by its name, and the files of a directory end up in several ones.
+// Notice that a deterministic cipher reveals which components are equal.
+//
+// The cipher must output [DefaultStringCodec] ciphertexts, like all the
+// ciphers of this package but [NewFF1]. An encrypted component longer than
+// [MaxFilenameLen] bytes is rejected with an error wrapping
+// [ErrPlaintextTooLarge]: with [NewGCMSynthNonce], the components are
+// limited to 163 bytes.
+func EncryptFilename(c Cipher, name string) (string, error) {
+	return transformFilename(name, func(component string) (string, error) {
+		cipherText, err := c.Encrypt(component)
+		if err != nil {
+			return "", err
+		}
+		ciphertext, err := decodeCipherText(cipherText)
+		if err != nil {
+			return "", err
+		}
+
+		encrypted := Base64URLNoPaddingCodec.EncodeToString(ciphertext)
+		if len(encrypted) > MaxFilenameLen {
+			return "", fmt.Errorf("%w: %d bytes encrypted, limit %d", ErrPlaintextTooLarge, len(encrypted), MaxFilenameLen)
+		}
+		return encrypted, nil
+	})
+}
+
+// DecryptFilename decrypts each component of the path encrypted by
+// [EncryptFilename] with the cipher.
+//
+// A component that is not [Base64URLNoPaddingCodec] encoded is rejected
+// with an error wrapping [ErrMalformedCiphertext].
+func DecryptFilename(c Cipher, name string) (string, error) {
+	return transformFilename(name, func(component string) (string, error) {
+		ciphertext, err := Base64URLNoPaddingCodec.DecodeString(component)
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", ErrMalformedCiphertext, err)
+		}
+		return c.Decrypt(DefaultStringCodec.EncodeToString(ciphertext))
+	})
+}
+
+// transformFilename applies fn to each component of the slash-separated
+// path but the empty ones, "." and "..".
+func transformFilename(name string, fn func(component string) (string, error)) (string, error) {
+	components := strings.Split(name, "/")
+
+	for i, component := range components {
+		if component == "" || component == "." || component == ".." {
+			continue
+		}
+
+		transformed, err := fn(component)
+		if err != nil {
+			return "", fmt.Errorf("path component %d: %w", i, err)
+		}
+		components[i] = transformed
+	}
+
+	return strings.Join(components, "/"), nil
+}
@@ -0,0 +1,72 @@
+package simplecipher
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEncryptFilename(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	c := NewGCMSynthNonce(NewAesKey("key"))
+
+	names := []string{
+		"file.txt",
+		"photos/2024/beach.jpg",
+		"/abs/path/",
+		"a//b/./c/../d",
+		"",
+	}
+	for _, name := range names {
+		encrypted, err := EncryptFilename(c, name)
+		if err != nil {
+			t.Fatalf("EncryptFilename(%q) error: %v", name, err)
+		}
+
+		// the structure is preserved
+		if got, want := strings.Count(encrypted, "/"), strings.Count(name, "/"); got != want {
+			t.Errorf("EncryptFilename(%q) = %q with %d separators, want %d", name, encrypted, got, want)
+		}
+		for _, component := range strings.Split(encrypted, "/") {
+			if strings.ContainsAny(component, "+=\\") {
+				t.Errorf("EncryptFilename(%q) component %q is not file name safe", name, component)
+			}
+		}
+
+		decrypted, err := DecryptFilename(c, encrypted)
+		if err != nil {
+			t.Fatalf("DecryptFilename(%q) error: %v", encrypted, err)
+		}
+		if decrypted != name {
+			t.Errorf("DecryptFilename(EncryptFilename(%q)) = %q", name, decrypted)
+		}
+	}
+
+	// the same directory encrypts to the same one
+	a, _ := EncryptFilename(c, "photos/2024/a.jpg")
+	b, _ := EncryptFilename(c, "photos/2024/b.jpg")
+	dirA, fileA := a[:strings.LastIndex(a, "/")], a[strings.LastIndex(a, "/"):]
+	dirB, fileB := b[:strings.LastIndex(b, "/")], b[strings.LastIndex(b, "/"):]
+	if dirA != dirB {
+		t.Errorf("EncryptFilename() = %q and %q, want the same directories", a, b)
+	}
+	if fileA == fileB {
+		t.Errorf("EncryptFilename() = %q and %q, want different files", a, b)
+	}
+
+	// the limits of the length of a component
+	if _, err := EncryptFilename(c, "dir/"+strings.Repeat("a", 163)); err != nil {
+		t.Errorf("EncryptFilename(163 bytes) error: %v", err)
+	}
+	if _, err := EncryptFilename(c, "dir/"+strings.Repeat("a", 164)); !errors.Is(err, ErrPlaintextTooLarge) {
+		t.Errorf("EncryptFilename(164 bytes) error = %v, want %v", err, ErrPlaintextTooLarge)
+	}
+
+	if _, err := DecryptFilename(c, "not+base64!"); !errors.Is(err, ErrMalformedCiphertext) {
+		t.Errorf("DecryptFilename(malformed) error = %v, want %v", err, ErrMalformedCiphertext)
+	}
+	if _, err := DecryptFilename(NewGCMSynthNonce(NewAesKey("another key")), a); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("DecryptFilename(wrong key) error = %v, want %v", err, ErrAuthenticationFailed)
+	}
+}
@@ -0,0 +1,158 @@
+package simplecipher
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// This file implements a Stream decorator that appends the plaintext
+// length to the ciphertext, to detect truncation in the unauthenticated
+// stream modes (CFB, OFB, CTR).
+//
+// The stream output is laid out as:
+//
+//	ciphertext of the inner Stream | length (8 bytes, big-endian) | HMAC-SHA256(ciphertext | length) (32 bytes)
+//
+// The MAC covers the whole ciphertext (including the iv of the inner
+// Stream), so that a footer can not be spliced from another message.
+
+const (
+	// hkdfInfoLengthFooter is the HKDF info label of the footer MAC subkey.
+	hkdfInfoLengthFooter = "simplecipher length footer mac key"
+	// lengthFooterSize is the size of the length footer.
+	lengthFooterSize = 8 + sha256.Size
+)
+
+// lengthFooterStream is a [Stream] decorator that appends an authenticated
+// length footer to the ciphertext of the inner Stream.
+type lengthFooterStream struct {
+	inner  Stream
+	macKey Key
+}
+
+var _ Stream = (*lengthFooterStream)(nil)
+
+// WithLengthFooter wraps the inner [Stream] (e.g., [NewCTRStream]) to append
+// the plaintext length, protected by an HMAC, to the ciphertext. So
+// DecryptStream detects a truncated ciphertext and returns an error wrapping
+// [ErrTruncated], which the CFB, OFB and CTR modes can not do alone.
+//
+// The MAC subkey is derived from the key via HKDF, so the key of the inner
+// Stream can be reused.
+//
+// The HMAC covers the ciphertext as well as the length, so a footer copied
+// from another message, or a modified ciphertext, fails with an error
+// wrapping both [ErrTruncated] and [ErrAuthenticationFailed]: the two can
+// not be told apart. Use [NewAuthenticatedCTRStream] for a stream that is
+// meant to be authenticated, or [NewRecordStream] to never write
+// unauthenticated plaintext.
+//
+// As the footer is at the end, the plaintext is written before the
+// truncation is detected: the error also wraps [ErrPartialPlaintext] then.
+func WithLengthFooter(inner Stream, key Key) Stream {
+	return &lengthFooterStream{
+		inner:  inner,
+		macKey: &hkdfKey{Secret: key, Info: hkdfInfoLengthFooter, Len: sha256.Size},
+	}
+}
+
+// Describe describes the inner Stream.
+func (s *lengthFooterStream) Describe() CipherInfo {
+	return DescribeStream(s.inner)
+}
+
+// newMAC returns the HMAC of the footer, to be fed with the ciphertext.
+func (s *lengthFooterStream) newMAC() hash.Hash {
+	return hmac.New(sha256.New, s.macKey.Bytes())
+}
+
+// footer returns the footer of the plaintext length, completing the mac
+// of the ciphertext with the length.
+func (s *lengthFooterStream) footer(mac hash.Hash, length uint64) []byte {
+	footer := binary.BigEndian.AppendUint64(nil, length)
+	mac.Write(footer)
+	return mac.Sum(footer)
+}
+
+// EncryptStream encrypts the plaintext with the inner Stream,
+// and appends the length footer.
+func (s *lengthFooterStream) EncryptStream(plainText io.Reader, cipherText io.Writer) (err error) {
+	defer wrapOpError(&err, encrypt, modeOf(s.inner))
+	defer recoverFromPanic(&err)
+
+	mac := s.newMAC()
+	counter := &countingReader{r: plainText}
+	if err := s.inner.EncryptStream(counter, io.MultiWriter(cipherText, mac)); err != nil {
+		return err
+	}
+
+	if _, err := cipherText.Write(s.footer(mac, counter.n)); err != nil {
+		return fmt.Errorf("%w: %w", ErrCopy, err)
+	}
+	return nil
+}
+
+// DecryptStream decrypts the ciphertext with the inner Stream,
+// and checks the plaintext length against the footer.
+func (s *lengthFooterStream) DecryptStream(cipherText io.Reader, plainText io.Writer) (err error) {
+	defer wrapOpError(&err, decrypt, modeOf(s.inner))
+	defer recoverFromPanic(&err)
+
+	mac := s.newMAC()
+	trailer := newTrailerReader(cipherText, lengthFooterSize)
+	counter := &writeCounter{w: plainText}
+
+	if err := s.inner.DecryptStream(io.TeeReader(trailer, mac), counter); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			// too short for the inner Stream, e.g., for its iv
+			return fmt.Errorf("%w: %w", ErrTruncated, err)
+		}
+		return err
+	}
+
+	footer := trailer.Trailer()
+	switch {
+	case len(footer) != lengthFooterSize:
+		err = fmt.Errorf("%w: missing the length footer", ErrTruncated)
+	case !hmac.Equal(footer, s.footer(mac, binary.BigEndian.Uint64(footer))):
+		err = fmt.Errorf("%w: %w: length footer mismatch", ErrTruncated, ErrAuthenticationFailed)
+	case binary.BigEndian.Uint64(footer) != uint64(counter.n):
+		err = fmt.Errorf("%w: %d bytes, want %d", ErrTruncated, counter.n, binary.BigEndian.Uint64(footer))
+	default:
+		return nil
+	}
+
+	if counter.n > 0 {
+		return fmt.Errorf("%w (%d bytes): %w", ErrPartialPlaintext, counter.n, err)
+	}
+	return err
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n uint64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += uint64(n)
+	return n, err
+}
+
+// writeCounter counts the bytes written to w.
+type writeCounter struct {
+	w io.Writer
+	n uint64
+}
+
+func (c *writeCounter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += uint64(n)
+	return n, err
+}
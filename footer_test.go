@@ -0,0 +1,77 @@
+package simplecipher
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestWithLengthFooter(t *testing.T) {
+	key := Bytes([]byte("key0key1key2key3key4key5key6key7"))
+	iv := Bytes([]byte("iv00iv01iv02iv03"))
+
+	streams := map[string]Stream{
+		"CTR": WithLengthFooter(NewCTRStream(key, iv), key),
+		"CFB": WithLengthFooter(NewCFBStream(key, iv), key),
+		"OFB": WithLengthFooter(NewOFBStream(key, iv), key),
+	}
+
+	plaintext := bytes.Repeat([]byte("plaintext"), 100)
+
+	for name, stream := range streams {
+		t.Run(name, func(t *testing.T) {
+			ciphertext := new(bytes.Buffer)
+			if err := stream.EncryptStream(bytes.NewReader(plaintext), ciphertext); err != nil {
+				t.Fatalf("EncryptStream error: %v", err)
+			}
+			if want := 16 + len(plaintext) + lengthFooterSize; ciphertext.Len() != want {
+				t.Errorf("len(ciphertext) = %d, want %d", ciphertext.Len(), want)
+			}
+
+			decrypted := new(bytes.Buffer)
+			if err := stream.DecryptStream(bytes.NewReader(ciphertext.Bytes()), decrypted); err != nil {
+				t.Fatalf("DecryptStream error: %v", err)
+			}
+			if !bytes.Equal(decrypted.Bytes(), plaintext) {
+				t.Errorf("DecryptStream() = %q, want %q", decrypted.Bytes(), plaintext)
+			}
+
+			for _, n := range []int{1, 8, lengthFooterSize - 1, lengthFooterSize, lengthFooterSize + 1, 500, len(plaintext) + lengthFooterSize} {
+				truncated := ciphertext.Bytes()[:ciphertext.Len()-n]
+				err := stream.DecryptStream(bytes.NewReader(truncated), new(bytes.Buffer))
+				if !errors.Is(err, ErrTruncated) {
+					t.Errorf("DecryptStream(without the last %d bytes) error = %v, want %v", n, err, ErrTruncated)
+				}
+			}
+
+			// a footer of another length does not verify
+			forged := bytes.Clone(ciphertext.Bytes()[:ciphertext.Len()-lengthFooterSize-1])
+			forged = binary.BigEndian.AppendUint64(forged, uint64(len(plaintext)-1))
+			forged = append(forged, ciphertext.Bytes()[ciphertext.Len()-sha256.Size:]...)
+			if err := stream.DecryptStream(bytes.NewReader(forged), new(bytes.Buffer)); !errors.Is(err, ErrTruncated) {
+				t.Errorf("DecryptStream(forged length) error = %v, want %v", err, ErrTruncated)
+			}
+
+			// a footer spliced from another message of the truncated length
+			other := new(bytes.Buffer)
+			if err := stream.EncryptStream(bytes.NewReader(bytes.Repeat([]byte("other"), 10)), other); err != nil {
+				t.Fatalf("EncryptStream error: %v", err)
+			}
+			spliced := bytes.Clone(ciphertext.Bytes()[:other.Len()-lengthFooterSize])
+			spliced = append(spliced, other.Bytes()[other.Len()-lengthFooterSize:]...)
+			err := stream.DecryptStream(bytes.NewReader(spliced), new(bytes.Buffer))
+			if !errors.Is(err, ErrTruncated) || !errors.Is(err, ErrAuthenticationFailed) {
+				t.Errorf("DecryptStream(spliced footer) error = %v, want %v and %v", err, ErrTruncated, ErrAuthenticationFailed)
+			}
+
+			// a modified ciphertext
+			modified := bytes.Clone(ciphertext.Bytes())
+			modified[20] ^= 1
+			if err := stream.DecryptStream(bytes.NewReader(modified), new(bytes.Buffer)); !errors.Is(err, ErrAuthenticationFailed) {
+				t.Errorf("DecryptStream(modified) error = %v, want %v", err, ErrAuthenticationFailed)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package simplecipher
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// This file provides helpers to pack several independently encrypted
+// messages into one ciphertext, and unpack them.
+//
+// The packed ciphertext is laid out as (before the encoding):
+//
+//	frame: length (4 bytes, big-endian) | ciphertext of a message (length bytes)
+//
+// repeated for each message, in order.
+
+// frameLengthSize is the size of the length prefix of a frame.
+const frameLengthSize = 4
+
+// EncryptFramed encrypts each message with the cipher (so each one has its
+// own iv or nonce), and packs the ciphertexts into one [DefaultStringCodec]
+// string, each prefixed with its length:
+//
+//	blob, err := simplecipher.EncryptFramed(cipher, []string{"a", "b", "c"})
+//	messages, err := simplecipher.DecryptFramed(cipher, blob)
+//
+// The cipher must output [DefaultStringCodec] ciphertexts, like all the
+// ciphers of this package but [NewFF1].
+func EncryptFramed(c Cipher, messages []string) (string, error) {
+	var blob []byte
+
+	for i, message := range messages {
+		cipherText, err := c.Encrypt(message)
+		if err != nil {
+			return "", fmt.Errorf("message %d: %w", i, err)
+		}
+		ciphertext, err := decodeCipherText(cipherText)
+		if err != nil {
+			return "", fmt.Errorf("message %d: %w", i, err)
+		}
+		if uint64(len(ciphertext)) > math.MaxUint32 {
+			return "", fmt.Errorf("message %d: %w: %d bytes ciphertext", i, ErrCiphertextTooLarge, len(ciphertext))
+		}
+
+		blob = binary.BigEndian.AppendUint32(blob, uint32(len(ciphertext)))
+		blob = append(blob, ciphertext...)
+	}
+
+	return DefaultStringCodec.EncodeToString(blob), nil
+}
+
+// DecryptFramed unpacks the ciphertexts packed by [EncryptFramed], and
+// decrypts each of them with the cipher.
+//
+// A truncated blob fails with an error wrapping [ErrCipherTextTooShort],
+// naming the frame, and no message is returned.
+func DecryptFramed(c Cipher, blob string) ([]string, error) {
+	b, err := decodeCipherText(blob)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []string
+
+	for i := 0; len(b) > 0; i++ {
+		if len(b) < frameLengthSize {
+			return nil, fmt.Errorf("%w: frame %d: missing the length prefix", ErrCipherTextTooShort, i)
+		}
+		n := binary.BigEndian.Uint32(b)
+		b = b[frameLengthSize:]
+		if uint64(n) > uint64(len(b)) {
+			return nil, fmt.Errorf("%w: frame %d: %d bytes, want %d", ErrCipherTextTooShort, i, len(b), n)
+		}
+
+		message, err := c.Decrypt(DefaultStringCodec.EncodeToString(b[:n]))
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+		messages = append(messages, message)
+
+		b = b[n:]
+	}
+
+	return messages, nil
+}
@@ -0,0 +1,36 @@
+package simplecipher
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestEncryptFramed(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	c := SimpleCTR("key")
+	messages := []string{"first", "", "third message"}
+
+	blob, err := EncryptFramed(c, messages)
+	if err != nil {
+		t.Fatalf("EncryptFramed error: %v", err)
+	}
+
+	got, err := DecryptFramed(c, blob)
+	if err != nil {
+		t.Fatalf("DecryptFramed error: %v", err)
+	}
+	if !slices.Equal(got, messages) {
+		t.Errorf("DecryptFramed() = %q, want %q", got, messages)
+	}
+
+	// the last frame is 4+16+13 bytes, i.e. 66 hex characters:
+	// cut in its ciphertext, and in its length prefix
+	for _, cut := range []int{2, 62} {
+		truncated := blob[:len(blob)-cut]
+		if _, err := DecryptFramed(c, truncated); !errors.Is(err, ErrCipherTextTooShort) {
+			t.Errorf("DecryptFramed(truncated by %d) error = %v, want %v", cut, err, ErrCipherTextTooShort)
+		}
+	}
+}
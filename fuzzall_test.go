@@ -92,9 +92,11 @@ func findAllFuzzTest() ([]string, error) {
 
 // runFuzz runs the fuzz test with the given name and duration.
 func runFuzz(f string, fuzzTime time.Duration) error {
+	// anchored, so that e.g. FuzzNewGCM does not match FuzzNewGCMSynthNonce
+	pattern := "^" + f + "$"
 	cmd := exec.Command("go", "test", "-v", ".",
-		"-run", f,
-		"-fuzz", f,
+		"-run", pattern,
+		"-fuzz", pattern,
 		"-fuzztime", fuzzTime.String())
 
 	cmd.Stdout = os.Stdout
@@ -2,4 +2,10 @@ module github.com/cdfmlr/simplecipher
 
 go 1.23.1
 
-require golang.org/x/crypto v0.27.0
+require (
+	github.com/ProtonMail/go-crypto v1.1.6
+	golang.org/x/crypto v0.27.0
+	golang.org/x/term v0.24.0
+)
+
+require golang.org/x/sys v0.25.0 // indirect
@@ -0,0 +1,140 @@
+package simplecipher
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// This file provides descriptions of the ciphers, e.g., for audit logs
+// recording which algorithm was used without logging the key.
+
+// CipherInfo describes the algorithm of a [Cipher] or a [Stream].
+type CipherInfo struct {
+	// Algorithm is the block cipher, e.g., "AES".
+	Algorithm string
+	// Mode is the cipher mode of operation.
+	Mode ModeID
+	// KeyBits is the key length in bits, 0 if unknown.
+	KeyBits int
+	// MAC is the message authentication code used along with a
+	// non-authenticated mode, e.g., "HMAC-SHA256", "" if none.
+	MAC string
+	// Codec is the name of the codec of the ciphertext (see [CodecName]),
+	// "" for the raw bytes of a [Stream].
+	Codec string
+}
+
+// String returns the description in the form of "AES-256-GCM (hex)".
+func (i CipherInfo) String() string {
+	s := fmt.Sprintf("%s-%d-%s", i.Algorithm, i.KeyBits, i.Mode)
+	if i.MAC != "" {
+		s += "+" + i.MAC
+	}
+	if i.Codec != "" {
+		s += " (" + i.Codec + ")"
+	}
+	return s
+}
+
+// Describer is implemented by the [Cipher] and [Stream] implementations of
+// this package to describe their algorithms.
+//
+//	log.Printf("encrypted with %s", simplecipher.DescribeCipher(c))
+//	// encrypted with AES-256-GCM (hex)
+type Describer interface {
+	Describe() CipherInfo
+}
+
+var (
+	_ Describer = (*cbc)(nil)
+	_ Describer = (*gcm)(nil)
+	_ Describer = (*gcmSynthNonce)(nil)
+	_ Describer = (*gcmCounter)(nil)
+	_ Describer = (*gcmAutoSalt)(nil)
+	_ Describer = (*gcmVersioned)(nil)
+	_ Describer = (*replayGuard)(nil)
+	_ Describer = (*streamToBlock)(nil)
+	_ Describer = (*blockToStream)(nil)
+	_ Describer = (*ff1)(nil)
+	_ Describer = (*aeadCipher)(nil)
+	_ Describer = (*keyring)(nil)
+	_ Describer = (*keyringWithIDs)(nil)
+	_ Describer = (*versionedKeyCipher)(nil)
+	_ Describer = (*compressCipher)(nil)
+	_ Describer = (*lengthHidingCipher)(nil)
+	_ Describer = (*randomPrefixCipher)(nil)
+	_ Describer = (*maxLenCipher)(nil)
+	_ Describer = (*rateLimitCipher)(nil)
+	_ Describer = (*utf8Cipher)(nil)
+	_ Describer = (*steam)(nil)
+	_ Describer = (*authCTRStream)(nil)
+	_ Describer = (*recordStream)(nil)
+	_ Describer = (*lengthFooterStream)(nil)
+)
+
+// DescribeCipher describes the given [Cipher] if it implements [Describer].
+// Otherwise, a zero CipherInfo is returned.
+func DescribeCipher(c Cipher) CipherInfo {
+	if d, ok := c.(Describer); ok {
+		return d.Describe()
+	}
+	return CipherInfo{}
+}
+
+// DescribeStream describes the given [Stream] if it implements [Describer].
+// Otherwise, a zero CipherInfo is returned.
+func DescribeStream(s Stream) CipherInfo {
+	if d, ok := s.(Describer); ok {
+		return d.Describe()
+	}
+	return CipherInfo{}
+}
+
+// KeyFingerprint returns a short fingerprint of the key: the hex of the
+// first 8 bytes of the SHA-256 of the key bytes, e.g., "9f86d081884c7d65".
+//
+// It identifies the key in logs without exposing it, e.g., to correlate
+// ciphertexts with the keys that encrypted them:
+//
+//	log.Printf("encrypted with key %s", simplecipher.KeyFingerprint(key))
+//
+// The same key always has the same fingerprint. Notice that a fingerprint
+// allows to check a guess of the key offline: only log the fingerprints of
+// high-entropy keys (e.g., derived by [NewAesKey]), not of passwords.
+// A nil key has the fingerprint of an empty key.
+func KeyFingerprint(k Key) string {
+	sum := sha256.Sum256(keyOrEmpty(k).Bytes())
+	return hex.EncodeToString(sum[:8])
+}
+
+// algorithm names
+const (
+	algorithmAES       = "AES"
+	algorithmBlowfish  = "Blowfish"
+	algorithmTwofish   = "Twofish"
+	algorithmTripleDES = "3DES"
+)
+
+// macHmacSha256 is the name of the MAC used by the authenticated streams.
+const macHmacSha256 = "HMAC-SHA256"
+
+// keyBits returns the length of the key in bits, or 0 if the key can not
+// be read.
+func keyBits(key Key) (bits int) {
+	defer func() {
+		if recover() != nil {
+			bits = 0
+		}
+	}()
+	return len(key.Bytes()) * 8
+}
+
+// defaultCodecName returns the name of [DefaultStringCodec],
+// or "custom" if it is not provided by this package.
+func defaultCodecName() string {
+	if name := CodecName(DefaultStringCodec); name != "" {
+		return name
+	}
+	return "custom"
+}
@@ -0,0 +1,98 @@
+package simplecipher
+
+import (
+	"compress/gzip"
+	"testing"
+)
+
+func TestDescribeCipher(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	key128 := NewAesKey("key", WithLen(Aes128))
+
+	tests := []struct {
+		name   string
+		cipher Cipher
+		want   string
+	}{
+		{"SimpleGCM", SimpleGCM("key", "nonce"), "AES-256-GCM (hex)"},
+		{"NewGCM", NewGCM(key128, NewNonce("nonce")), "AES-128-GCM (hex)"},
+		{"NewGCMSynthNonce", NewGCMSynthNonce(NewAesKey("key")), "AES-256-GCM (hex)"},
+		{"SimpleCBC", SimpleCBC("key"), "AES-256-CBC (hex)"},
+		{"NewCBC", NewCBC(key128, NewIv("iv")), "AES-128-CBC (hex)"},
+		{"SimpleCFB", SimpleCFB("key"), "AES-256-CFB (hex)"},
+		{"SimpleOFB", SimpleOFB("key"), "AES-256-OFB (hex)"},
+		{"SimpleCTR", SimpleCTR("key"), "AES-256-CTR (hex)"},
+		{"NewKeyring", NewKeyring(key128, NewAesKey("old")), "AES-128-GCM (hex)"},
+		{"NewKeyringWithIDs", NewKeyringWithIDs(map[string]Key{"v1": key128}, "v1"), "AES-128-GCM (hex)"},
+		{"WithCompression", WithCompression(SimpleCTR("key"), gzip.DefaultCompression), "AES-256-CTR (hex)"},
+		{"WithMaxCiphertextLen", WithMaxCiphertextLen(SimpleGCM("key", "nonce"), 1024), "AES-256-GCM (hex)"},
+		{"NewBlowfishCBC", NewBlowfishCBC(String("legacy-key"), String("iv00iv01")), "Blowfish-80-CBC (hex)"},
+		{"NewTwofishCBC", NewTwofishCBC(key128, NewIv("iv")), "Twofish-128-CBC (hex)"},
+		{"NewTripleDESCBC", NewTripleDESCBC(String("key0key1key2key3key4key5"), String("iv00iv01")), "3DES-192-CBC (hex)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DescribeCipher(tt.cipher).String(); got != tt.want {
+				t.Errorf("DescribeCipher() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	info := DescribeCipher(SimpleGCM("key", "nonce"))
+	want := CipherInfo{Algorithm: "AES", Mode: ModeGCM, KeyBits: 256, Codec: "hex"}
+	if info != want {
+		t.Errorf("DescribeCipher(SimpleGCM) = %+v, want %+v", info, want)
+	}
+}
+
+func TestDescribeStream(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	tests := []struct {
+		name   string
+		stream Stream
+		want   string
+	}{
+		{"SimpleCTRStream", SimpleCTRStream("key"), "AES-256-CTR"},
+		{"NewAuthenticatedCTRStream", NewAuthenticatedCTRStream(NewAesKey("key")), "AES-256-CTR+HMAC-SHA256"},
+		{"NewRecordStream", NewRecordStream(NewAesKey("key", WithLen(Aes128)), 0), "AES-128-GCM"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DescribeStream(tt.stream).String(); got != tt.want {
+				t.Errorf("DescribeStream() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyFingerprint(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	// SHA-256("test") = 9f86d081884c7d65...
+	if got := KeyFingerprint(String("test")); got != "9f86d081884c7d65" {
+		t.Errorf("KeyFingerprint(test) = %s, want 9f86d081884c7d65", got)
+	}
+
+	// SHA-256("") = e3b0c44298fc1c14...
+	if got := KeyFingerprint(nil); got != "e3b0c44298fc1c14" {
+		t.Errorf("KeyFingerprint(nil) = %s, want e3b0c44298fc1c14", got)
+	}
+
+	if KeyFingerprint(NewAesKey("key")) != KeyFingerprint(NewAesKey("key")) {
+		t.Errorf("KeyFingerprint() differs for the same key")
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		fp := KeyFingerprint(Bytes([]byte{byte(i), byte(i >> 8)}))
+		if len(fp) != 16 {
+			t.Errorf("KeyFingerprint() = %q, want 16 hex characters", fp)
+		}
+		if seen[fp] {
+			t.Errorf("KeyFingerprint() collision: %s", fp)
+		}
+		seen[fp] = true
+	}
+}
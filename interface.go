@@ -16,10 +16,15 @@ import (
 //
 // Cipher encodes the ciphertext with [DefaultStringCodec] when Encrypting
 // and decodes the ciphertext from a [DefaultStringCodec] string when Decrypting.
+//
+// An empty plaintext is valid for all the implementations in this package:
+// Encrypt("") outputs only the overhead of the mode (e.g., the iv, the tag,
+// or a block of padding), which Decrypt turns back into "".
 type Cipher interface {
 	// Encrypt the given plaintext and return the ciphertext as a [DefaultStringCodec] encoded string.
 	Encrypt(plainText string) (cipherText string, err error)
 	// Decrypt the given ciphertext ([DefaultStringCodec] encoded) and return the plaintext.
+	// An error wrapping [ErrMalformedCiphertext] is returned if the ciphertext cannot be decoded.
 	Decrypt(cipherText string) (plainText string, err error)
 }
 
@@ -37,12 +42,111 @@ type Stream interface {
 	DecryptStream(cipherText io.Reader, plainText io.Writer) error
 }
 
+// Validator is an optional interface implemented by [Cipher] and [Stream]
+// implementations to eagerly check their configuration (e.g., key, iv and
+// nonce lengths) without performing a dummy encryption.
+//
+// Use [ValidateCipher] or [ValidateStream] to fail fast at startup.
+type Validator interface {
+	// Validate returns an error if the configuration is invalid.
+	Validate() error
+}
+
+// RawCipher is an optional interface implemented by [Cipher]
+// implementations that can return the ciphertext as raw bytes,
+// skipping the [DefaultStringCodec] encoding, e.g., to store it in a
+// binary column without encoding it only to decode it again:
+//
+//	raw, err := c.(simplecipher.RawCipher).EncryptRaw("plaintext")
+//
+// The ciphertexts are the decoded ones of Encrypt and Decrypt.
+// The CBC and GCM ciphers (e.g., [NewCBC], [SimpleCBC], [NewGCM] and
+// [SimpleGCM]) implement RawCipher.
+type RawCipher interface {
+	Cipher
+	// EncryptRaw encrypts the plaintext and returns the raw ciphertext.
+	EncryptRaw(plainText string) ([]byte, error)
+	// DecryptRaw decrypts the raw ciphertext.
+	DecryptRaw(cipherText []byte) (string, error)
+}
+
 // Errors
 var (
-	ErrPlaintextBlockSize  = errors.New("plaintext is not a multiple of the block size")
-	ErrCipherTextTooShort  = errors.New("ciphertext too short")
-	ErrCipherTextBlockSize = errors.New("ciphertext is not a multiple of the block size")
-	ErrPanic               = errors.New("recovered from panic")
-	ErrCopy                = errors.New("copy error")
-	ErrNewAesCipher        = errors.New("aes.NewCipher error")
+	ErrPlaintextBlockSize   = errors.New("plaintext is not a multiple of the block size")
+	ErrCipherTextTooShort   = errors.New("ciphertext too short")
+	ErrCipherTextBlockSize  = errors.New("ciphertext is not a multiple of the block size")
+	ErrPanic                = errors.New("recovered from panic")
+	ErrCopy                 = errors.New("copy error")
+	ErrNewAesCipher         = errors.New("aes.NewCipher error")
+	ErrAuthenticationFailed = errors.New("message authentication failed")
+	ErrMalformedCiphertext  = errors.New("malformed ciphertext")
+	ErrNonceSize            = errors.New("nonce size mismatch")
+	ErrNoRecipient          = errors.New("key is not a recipient of the envelope")
+	ErrUnknownKey           = errors.New("unknown key")
+	ErrPlaintextTooLarge    = errors.New("plaintext too large")
+	ErrKeySize              = errors.New("invalid key size")
+	ErrIvSize               = errors.New("invalid iv size")
+	ErrInvalidConfig        = errors.New("invalid configuration")
+	ErrCiphertextTooLarge   = errors.New("ciphertext too large")
+	ErrUnsupportedType      = errors.New("unsupported type")
+	ErrPartialPlaintext     = errors.New("partial plaintext written before the error")
+	ErrEnvVarMissing        = errors.New("environment variable not set")
+	ErrRateLimited          = errors.New("rate limited")
+	ErrUnsupportedVersion   = errors.New("unsupported ciphertext version")
+	ErrKDFTimeout           = errors.New("key derivation timed out")
+	ErrReplayDetected       = errors.New("replayed message")
+	ErrNonceReused          = errors.New("nonce reused")
+	ErrInvalidUTF8          = errors.New("plaintext is not valid UTF-8")
+	ErrEmptyKey             = errors.New("empty key")
+	ErrTruncated            = errors.New("ciphertext truncated")
+	ErrUnknownKeyVersion    = errors.New("unknown key version")
+	ErrChecksumMismatch     = errors.New("checksum mismatch")
 )
+
+// opError wraps an error returned by a cipher with the operation and the
+// mode it happened in, e.g., "simplecipher decrypt (GCM): message
+// authentication failed", so that logs tell them apart.
+// The wrapped error is still matched by [errors.Is].
+type opError struct {
+	op   encryptOrDecrypt
+	mode ModeID
+	err  error
+}
+
+func (e *opError) Error() string {
+	if e.mode == "" {
+		return "simplecipher " + e.op.String() + ": " + e.err.Error()
+	}
+	return "simplecipher " + e.op.String() + " (" + string(e.mode) + "): " + e.err.Error()
+}
+
+func (e *opError) Unwrap() error {
+	return e.err
+}
+
+// wrapOpError wraps the non-nil error with the operation context.
+// Errors wrapped already (by an inner cipher) are kept as they are.
+// It's meant to be deferred before recoverFromPanic, to wrap the recovered
+// panics as well:
+//
+//	defer wrapOpError(&err, decrypt, ModeGCM)
+//	defer recoverFromPanic(&err)
+func wrapOpError(err *error, op encryptOrDecrypt, mode ModeID) {
+	if *err == nil {
+		return
+	}
+	var e *opError
+	if errors.As(*err, &e) {
+		return
+	}
+	*err = &opError{op: op, mode: mode, err: *err}
+}
+
+// stripOpError returns the error without its operation context, if any,
+// e.g., to aggregate the errors of an operation under a single context.
+func stripOpError(err error) error {
+	if e, ok := err.(*opError); ok {
+		return e.err
+	}
+	return err
+}
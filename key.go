@@ -1,11 +1,26 @@
 package simplecipher
 
 import (
+	"bytes"
 	"crypto/aes"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"golang.org/x/crypto/hkdf"
 	"golang.org/x/crypto/scrypt"
+	"hash"
+	"io"
 	mathrand "math/rand"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -21,11 +36,83 @@ import (
 // Notice different use cases of keys require different lengths.
 // Use [NewAesKey], [NewNonce], or [NewIv] to create keys
 // matching the requirements if you are not sure.
+//
+// IVs and nonces can be typed as [IV] and [Nonce], which are Keys as well.
+//
+// A nil or empty cipher key (e.g., Bytes(nil)) makes the ciphers fail with
+// an error wrapping [ErrEmptyKey]. Keys derived from an empty passphrase
+// are not empty, though.
 type Key interface {
 	// Bytes return a byte slice of the key.
 	Bytes() []byte
 }
 
+//////// IV & Nonce //////////
+
+// IV is a [Key] used as the initialization vector of the block cipher modes
+// (CBC, CFB, OFB, CTR).
+//
+// It is a distinct type from [Nonce], so that code passing ivs and nonces
+// around can tell them apart at compile time:
+//
+//	func newCipher(key simplecipher.Key, iv simplecipher.IV) simplecipher.Cipher {
+//		return simplecipher.NewCBC(key, iv)
+//	}
+//
+//	newCipher(key, simplecipher.AsNonce(nonce)) // does not compile
+//
+// The constructors of this package accept any [Key] as the iv for
+// compatibility, so an IV is accepted as well. Use [AsIV] to type a [Key]
+// (e.g., from [NewIv] or [NewRandomIv]) as an IV.
+type IV interface {
+	Key
+	isIV()
+}
+
+// Nonce is a [Key] used as the nonce of the AEAD ciphers (GCM).
+//
+// Like [IV], it is accepted wherever a nonce [Key] is.
+// Use [AsNonce] to type a [Key] (e.g., from [NewNonce] or [TimeNonce])
+// as a Nonce.
+type Nonce interface {
+	Key
+	isNonce()
+}
+
+// ivKey marks a [Key] as an [IV].
+type ivKey struct {
+	Key
+}
+
+func (ivKey) isIV() {}
+
+// nonceKey marks a [Key] as a [Nonce].
+type nonceKey struct {
+	Key
+}
+
+func (nonceKey) isNonce() {}
+
+// AsIV converts the key to an [IV]. It is a no-op if the key is an IV already.
+//
+//	iv := simplecipher.AsIV(simplecipher.Bytes(ivBytes))
+func AsIV(key Key) IV {
+	if iv, ok := key.(IV); ok {
+		return iv
+	}
+	return ivKey{Key: key}
+}
+
+// AsNonce converts the key to a [Nonce]. It is a no-op if the key is a Nonce already.
+//
+//	nonce := simplecipher.AsNonce(simplecipher.Bytes(nonceBytes))
+func AsNonce(key Key) Nonce {
+	if nonce, ok := key.(Nonce); ok {
+		return nonce
+	}
+	return nonceKey{Key: key}
+}
+
 //////// Bytes & String //////////
 
 // bytesKey is a simple type to convert a byte slice to a [Key].
@@ -37,6 +124,27 @@ func (k bytesKey) Bytes() []byte {
 	return k
 }
 
+var (
+	_ encoding.TextMarshaler   = (*bytesKey)(nil)
+	_ encoding.TextUnmarshaler = (*bytesKey)(nil)
+)
+
+// MarshalText encodes the key as a hex string.
+func (k bytesKey) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(k)), nil
+}
+
+// UnmarshalText decodes the key from a hex string,
+// so that a key can be loaded directly from a config string.
+func (k *bytesKey) UnmarshalText(text []byte) error {
+	b, err := hex.DecodeString(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid hex key: %w", err)
+	}
+	*k = b
+	return nil
+}
+
 // Bytes is a helper function to convert a byte slice to a [Key].
 func Bytes(b []byte) Key {
 	return bytesKey(b)
@@ -54,6 +162,157 @@ func String(s string) Key {
 	return stringKey(s)
 }
 
+// MaterializeKey computes k.Bytes() once and returns the result as a
+// plain byte [Key].
+//
+// Keys derived from passphrases ([NewKey], [NewAesKey], ...) are lazy:
+// the expensive scrypt derivation runs on every Bytes() call, i.e., on
+// every encryption or decryption of every cipher using the key.
+// MaterializeKey trades this lazy derivation for a one-time cost, and the
+// returned key can be shared by many ciphers:
+//
+//	key := simplecipher.MaterializeKey(simplecipher.NewAesKey("passphrase"))
+//	users := simplecipher.NewGCM(key, simplecipher.NewNonce("users"))
+//	orders := simplecipher.NewGCM(key, simplecipher.NewNonce("orders"))
+//
+// The derived bytes are kept in memory for the lifetime of the returned key.
+func MaterializeKey(k Key) Key {
+	return bytesKey(bytes.Clone(keyOrEmpty(k).Bytes()))
+}
+
+// keyOrEmpty returns the key, or an empty key if it is nil.
+//
+// The constructors normalize their keys with it, so that a nil Key fails
+// with an error wrapping [ErrEmptyKey] like an empty one, instead of a
+// nil pointer panic on first use.
+func keyOrEmpty(k Key) Key {
+	if k == nil {
+		return bytesKey(nil)
+	}
+	return k
+}
+
+//////// Environment //////////
+
+// KeyFromEnv reads a key from the environment variable varName,
+// e.g., for 12-factor deployments:
+//
+//	key, err := simplecipher.KeyFromEnv("APP_ENCRYPTION_KEY", simplecipher.Aes256)
+//
+// The value must be the hex or the (standard, padded or not) base64
+// encoding of exactly expectedLen bytes. Hex is tried first.
+//
+// An error wrapping [ErrEnvVarMissing] is returned if the variable is not
+// set (or empty), [ErrInvalidConfig] if it can not be decoded, and
+// [ErrKeySize] if the decoded key is not expectedLen bytes long.
+// The error never contains the value.
+func KeyFromEnv(varName string, expectedLen KeyLen) (key Key, err error) {
+	defer wrapOpError(&err, loadKey, "")
+
+	value := strings.TrimSpace(os.Getenv(varName))
+	if value == "" {
+		return nil, fmt.Errorf("%w: %s", ErrEnvVarMissing, varName)
+	}
+
+	decoded, err := decodeKeyString(value, expectedLen)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s is neither hex nor base64", ErrInvalidConfig, varName)
+	}
+
+	if len(decoded) != int(expectedLen) {
+		return nil, fmt.Errorf("%w: %s is %d bytes, want %d", ErrKeySize, varName, len(decoded), expectedLen)
+	}
+
+	return bytesKey(decoded), nil
+}
+
+// decodeKeyString decodes a hex or base64 encoded key.
+// Hex is preferred, unless only the base64 decoding is expectedLen bytes long.
+func decodeKeyString(s string, expectedLen KeyLen) ([]byte, error) {
+	hexKey, hexErr := hex.DecodeString(s)
+	if hexErr == nil && len(hexKey) == int(expectedLen) {
+		return hexKey, nil
+	}
+
+	b64Key, b64Err := base64.RawStdEncoding.DecodeString(strings.TrimRight(s, "="))
+	if b64Err == nil && (len(b64Key) == int(expectedLen) || hexErr != nil) {
+		return b64Key, nil
+	}
+
+	if hexErr == nil {
+		return hexKey, nil
+	}
+	return nil, b64Err
+}
+
+//////// Func //////////
+
+// FallibleKey is a [Key] that can fail to produce its bytes,
+// and reports the error through BytesE, as Bytes can not.
+//
+// [KeyFunc], [SaltedKey] and all the keys derived from passphrases (by
+// [NewKey], [NewAesKey], [NewNonce], [NewIv] and [DeriveKeyAndIV])
+// implement FallibleKey.
+type FallibleKey interface {
+	Key
+	// BytesE returns the key bytes, or the reason why they are not
+	// available.
+	BytesE() ([]byte, error)
+}
+
+var (
+	_ FallibleKey = (KeyFunc)(nil)
+	_ FallibleKey = (*keyGen)(nil)
+	_ FallibleKey = (*hkdfKey)(nil)
+)
+
+// KeyFunc is a [Key] fetching its bytes from a function on every use,
+// e.g., to integrate an external key store (HSM, KMS, vault):
+//
+//	key := simplecipher.KeyFromFunc(func() ([]byte, error) {
+//		return kms.Decrypt(ctx, wrappedKey)
+//	})
+//
+// Bytes swallows the error of the function to fit the [Key] interface:
+// it returns nil instead, so the ciphers fail with an error such as
+// [ErrKeySize]. Call BytesE to get the error itself.
+//
+// The function is called on every Bytes call, i.e., on every encryption
+// and decryption: the ciphers (including [NewGCM], which memoizes other
+// keys) fetch a KeyFunc key per operation, so a rotated key is picked up
+// by the next operation, and a failed fetch only fails that operation.
+// Cache the key in the function if fetching it is slow or rate-limited,
+// or use [MaterializeKey] to fetch it once.
+type KeyFunc func() ([]byte, error)
+
+var _ Key = (KeyFunc)(nil)
+
+// KeyFromFunc converts the function to a [Key]. See [KeyFunc].
+func KeyFromFunc(f KeyFunc) Key {
+	return f
+}
+
+// Bytes calls the function, and returns nil if it fails.
+func (f KeyFunc) Bytes() []byte {
+	b, err := f()
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// BytesE calls the function, and returns its error.
+func (f KeyFunc) BytesE() ([]byte, error) {
+	return f()
+}
+
+// isKeyFunc reports whether the key is a [KeyFunc], to be fetched on
+// every operation instead of being memoized.
+func isKeyFunc(k Key) bool {
+	_, ok := k.(KeyFunc)
+	return ok
+}
+
 //////// KeyGen //////////
 
 // keyGen derives a key from a passphrase and salt
@@ -65,6 +324,25 @@ type keyGen struct {
 	Len KeyLen
 	// Salt is a random string to make the key derivation more secure.
 	Salt string
+	// Purpose separates the domains of keys derived for different uses
+	// (e.g., "iv", "nonce") from the same Passphrase and Salt.
+	// It is mixed into the scrypt salt if not empty.
+	Purpose string
+	// Hash is the hash function of the KDFs applied on top of scrypt
+	// (e.g., HKDF in DeriveKeyAndIV). nil for SHA-256.
+	// scrypt itself always uses SHA-256.
+	Hash func() hash.Hash
+	// Pepper is a secret mixed into the Passphrase as
+	// HMAC-SHA256(Pepper, Passphrase) before scrypt, if not empty.
+	Pepper string
+	// N is the CPU/memory cost parameter of scrypt, 0 for 2048.
+	// See WithScryptN.
+	N int
+	// Timeout bounds the time of the scrypt derivation, if positive.
+	Timeout time.Duration
+	// Err is the error of an invalid option (e.g., WithHash(nil)),
+	// returned by BytesE instead of deriving the key.
+	Err error
 }
 
 var _ Key = (*keyGen)(nil)
@@ -101,8 +379,32 @@ func NewKey(passphrase string, len KeyLen, salt string) Key {
 //
 // Len <= 0 will return an empty byte slice ([]byte{}).
 func (k keyGen) Bytes() []byte {
+	key, _ := k.BytesE()
+	return key
+}
+
+// BytesE derives the key like Bytes, and returns the error of the
+// derivation as well: an error wrapping [ErrKDFTimeout] if it exceeded the
+// Timeout, [ErrInvalidConfig] for an invalid option, or the error of scrypt.
+// The key returned along with an error is the one Bytes returns, i.e., an
+// empty key on timeout or invalid option.
+func (k keyGen) BytesE() ([]byte, error) {
+	if k.Err != nil {
+		return []byte{}, k.Err
+	}
+
 	key := []byte(k.Passphrase)
+	if k.Pepper != "" {
+		mac := hmac.New(sha256.New, []byte(k.Pepper))
+		mac.Write(key)
+		key = mac.Sum(nil)
+	}
 	salt := []byte(k.Salt)
+	if k.Purpose != "" {
+		// domain separation: otherwise the same Passphrase and Salt
+		// derive an IV (or nonce) that is a prefix of the key.
+		salt = []byte(k.Purpose + ":" + k.Salt)
+	}
 	expectedKeyLen := int(k.Len)
 
 	if expectedKeyLen < 0 {
@@ -114,9 +416,17 @@ func (k keyGen) Bytes() []byte {
 	// N=32768 is recommended by https://pkg.go.dev/golang.org/x/crypto/scrypt#Key
 	// N=32768 takes < 100ms on modern computers,
 	// lower N for faster key derivation (e.g., 2048 for < 10ms)
-	key, err := scrypt.Key(key, salt, 2048, 8, 1, expectedKeyLen)
+	n := k.N
+	if n == 0 {
+		n = defaultScryptN
+	}
+	key, err := k.scrypt(key, salt, n, expectedKeyLen)
 	if err != nil && len(key) == expectedKeyLen {
-		return nil
+		return nil, err
+	}
+
+	if errors.Is(err, ErrKDFTimeout) || err != nil && StrictKDF {
+		return []byte{}, err
 	}
 
 	// scrypt failed, use the Passphrase key with naive padding/truncation.
@@ -133,9 +443,105 @@ func (k keyGen) Bytes() []byte {
 		key = key[:expectedKeyLen]
 	}
 
-	return key
+	return key, err
 }
 
+// defaultScryptN is the default scrypt cost parameter N.
+const defaultScryptN = 2048
+
+// scrypt runs [scryptKey], giving up after the Timeout if it is set.
+//
+// As scrypt is not cancellable, a timed out derivation keeps running.
+// So the timed derivations of the same inputs share a single run (see
+// kdfCall): a call made while one is in flight waits on it, rather than
+// starting another one, so that retrying a derivation that times out does
+// not pile up goroutines (and their scrypt memory).
+func (k keyGen) scrypt(password, salt []byte, n, keyLen int) ([]byte, error) {
+	if k.Timeout <= 0 {
+		return scryptKey(password, salt, n, 8, 1, keyLen)
+	}
+
+	call := startKDFCall(password, salt, n, keyLen)
+
+	timer := time.NewTimer(k.Timeout)
+	defer timer.Stop()
+
+	select {
+	case <-call.done:
+		// cloned, as the callers may clear their keys
+		return bytes.Clone(call.key), call.err
+	case <-timer.C:
+		return nil, fmt.Errorf("%w: after %v", ErrKDFTimeout, k.Timeout)
+	}
+}
+
+// kdfCall is an in-flight timed scrypt derivation, shared by the
+// derivations of the same inputs.
+type kdfCall struct {
+	done chan struct{} // closed once key and err are set
+	key  []byte
+	err  error
+}
+
+var (
+	kdfCallsMu sync.Mutex
+	// kdfCalls are the in-flight derivations by the hash of their inputs.
+	// A derivation is removed once it completes.
+	kdfCalls = map[[sha256.Size]byte]*kdfCall{}
+)
+
+// startKDFCall returns the in-flight derivation of the inputs, starting it
+// if there is none.
+func startKDFCall(password, salt []byte, n, keyLen int) *kdfCall {
+	// hashed, not to keep the password around as a map key
+	h := sha256.New()
+	for _, b := range [][]byte{password, salt} {
+		h.Write(binary.BigEndian.AppendUint64(nil, uint64(len(b))))
+		h.Write(b)
+	}
+	h.Write(binary.BigEndian.AppendUint64(nil, uint64(n)))
+	h.Write(binary.BigEndian.AppendUint64(nil, uint64(keyLen)))
+	var id [sha256.Size]byte
+	h.Sum(id[:0])
+
+	kdfCallsMu.Lock()
+	defer kdfCallsMu.Unlock()
+
+	if call, ok := kdfCalls[id]; ok {
+		return call
+	}
+
+	call := &kdfCall{done: make(chan struct{})}
+	kdfCalls[id] = call
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				call.key, call.err = nil, fmt.Errorf("%w: %v", ErrPanic, r)
+			}
+			kdfCallsMu.Lock()
+			delete(kdfCalls, id)
+			kdfCallsMu.Unlock()
+			close(call.done)
+		}()
+		call.key, call.err = scryptKey(password, salt, n, 8, 1, keyLen)
+	}()
+	return call
+}
+
+// scryptKey is [scrypt.Key], replaceable in tests.
+var scryptKey = scrypt.Key
+
+// StrictKDF disables the fallback of the key derivation to a naively
+// padded key if scrypt fails (which should never happen).
+//
+// In strict mode, the keys derived from passphrases ([NewKey], [NewAesKey],
+// [NewNonce], [NewIv]) are guaranteed to be real scrypt output: a failed
+// derivation returns an empty key instead, so that the ciphers using it
+// fail with an error rather than encrypting with a weak key.
+//
+// It defaults to false for compatibility.
+var StrictKDF = false
+
 // DefaultSalt returns a fixed random string to make the key derivation more
 // secure. keyGen use this salt by default.
 //
@@ -196,6 +602,115 @@ func WithLen(keyLen KeyLen) KeyGenOption {
 	}
 }
 
+// WithHash sets the hash function of the KDFs applied on top of scrypt,
+// i.e., the HKDF expansion of [DeriveKeyAndIV]. It defaults to SHA-256.
+//
+// Use it for interoperability with systems using another hash,
+// e.g., sha512.New. A nil hash is invalid: the derived keys are empty, so
+// the ciphers using them fail, and their BytesE method (see [FallibleKey])
+// returns an error wrapping [ErrInvalidConfig].
+//
+// Notice that the scrypt derivation itself always uses SHA-256,
+// so this option does not affect [NewKey], [NewAesKey], [NewNonce], or [NewIv].
+func WithHash(h func() hash.Hash) KeyGenOption {
+	return func(gen *keyGen) {
+		if h == nil {
+			gen.Err = fmt.Errorf("%w: nil hash function", ErrInvalidConfig)
+			return
+		}
+		gen.Hash = h
+	}
+}
+
+// WithLegacyDerivation derives the key without the domain separation of
+// [NewNonce] and [NewIv], like the older versions did, to decrypt the
+// ciphertexts encrypted with the nonces and IVs they derived.
+//
+// Use it for decryption only: the legacy IV or nonce is a prefix of the
+// AES key derived from the same passphrase and salt. Re-encrypt the data
+// (see [Reencrypt]) with a nonce or IV derived without it.
+// It does not affect the other keys, which are not domain separated.
+func WithLegacyDerivation() KeyGenOption {
+	return func(gen *keyGen) {
+		gen.Purpose = ""
+	}
+}
+
+// WithPepper mixes a secret pepper into the passphrase, as
+// HMAC-SHA256(pepper, passphrase), before the scrypt derivation.
+//
+// Unlike the salt, the pepper is a secret stored apart from the data
+// (e.g., in the application config or a KMS, while the salts and
+// ciphertexts are in the database), so that the keys can not be brute
+// forced from a database-only compromise. It is not stored in the ciphertext.
+//
+// Attention: the pepper is required to derive the key again.
+// Losing the pepper makes decryption impossible.
+// An empty pepper is ignored.
+func WithPepper(pepper string) KeyGenOption {
+	return func(gen *keyGen) {
+		gen.Pepper = pepper
+	}
+}
+
+// WithKDFTimeout bounds the time of the scrypt derivation of the key to d,
+// e.g., to avoid hanging on a malicious configuration with absurd cost
+// parameters. A derivation exceeding d yields an empty key, so that the
+// ciphers using it fail with an error rather than hang. The error wrapping
+// [ErrKDFTimeout] itself is returned by the BytesE method of the key:
+//
+//	key := simplecipher.NewAesKey("passphrase", simplecipher.WithKDFTimeout(time.Second))
+//	if _, err := key.(simplecipher.FallibleKey).BytesE(); err != nil { ... }
+//
+// As scrypt is not cancellable, the derivation keeps running in a
+// goroutine after the timeout until it completes; only the caller is
+// unblocked. The derivations of the same key share that run: they wait on
+// it (up to their own timeout) instead of starting another one, so that
+// the retries of a timed out key do not pile up. d <= 0 disables the
+// timeout.
+func WithKDFTimeout(d time.Duration) KeyGenOption {
+	return func(gen *keyGen) {
+		gen.Timeout = d
+	}
+}
+
+// WithScryptN sets the CPU/memory cost parameter N of scrypt.
+// It defaults to 2048, which takes a few milliseconds; the recommended
+// 32768 for interactive logins takes tens of milliseconds (and 32 MiB).
+// Use [BenchmarkKDF] to pick a value for your hardware.
+//
+// N must be a power of 2 greater than 1. If an invalid N is provided,
+// the default is used. Attention: N changes the derived key, so the same
+// N is required to derive the key again.
+func WithScryptN(n int) KeyGenOption {
+	if n <= 1 || n&(n-1) != 0 {
+		n = 0
+	}
+	return func(gen *keyGen) {
+		gen.N = n
+	}
+}
+
+// BenchmarkKDF times a single key derivation with the given options
+// (e.g., [WithScryptN]), for tuning the cost parameters at startup:
+//
+//	if d := simplecipher.BenchmarkKDF(simplecipher.WithScryptN(n)); d < 50*time.Millisecond {
+//		log.Printf("key derivation takes %v, consider a higher cost", d)
+//	}
+//
+// The derivation is the one of [NewAesKey] on a dummy passphrase. The
+// duration varies from run to run, and with the load of the machine.
+func BenchmarkKDF(opts ...KeyGenOption) time.Duration {
+	keygen := newKeyGen("simplecipher benchmark", Aes256, DefaultSalt())
+	for _, opt := range opts {
+		opt(keygen)
+	}
+
+	start := time.Now()
+	_, _ = keygen.BytesE()
+	return time.Since(start)
+}
+
 //////// AES //////////
 
 // Available [KeyLen] values for AES keys are 16, 24 and 32 bytes
@@ -224,8 +739,89 @@ func NewAesKey(passphrase string, options ...KeyGenOption) Key {
 	return keygen
 }
 
+//////// Salted key //////////
+
+// SaltedKey is an AES key derived from a passphrase with its own random
+// salt, instead of [DefaultSalt]. Store the Salt with the ciphertexts
+// (it is not secret), and reconstruct the key from it to decrypt:
+//
+//	key := simplecipher.NewSaltedKey(passphrase, nil) // a new random salt
+//	record.Salt, record.Data = key.Salt(), encrypt(key, data)
+//
+//	key = simplecipher.NewSaltedKey(passphrase, record.Salt) // the same key
+//
+// The random salt is generated on first use, and kept for the lifetime of
+// the SaltedKey. It is safe for concurrent use.
+type SaltedKey struct {
+	mu      sync.Mutex
+	gen     keyGen
+	salt    []byte
+	saltErr error
+}
+
+var (
+	_ Key         = (*SaltedKey)(nil)
+	_ FallibleKey = (*SaltedKey)(nil)
+)
+
+// NewSaltedKey creates a new AES key derived from the passphrase and the
+// salt, or a random 16 bytes salt if salt is nil.
+//
+// [Aes256] is used by default. The options customize the key derivation
+// like for [NewAesKey], but [WithSalt] which is ignored.
+func NewSaltedKey(passphrase string, salt []byte, options ...KeyGenOption) *SaltedKey {
+	k := &SaltedKey{gen: *NewAesKey(passphrase, options...).(*keyGen)}
+	if salt != nil {
+		k.salt = bytes.Clone(salt)
+	}
+	return k
+}
+
+// Salt returns the salt of the key, generating it if it's random and not
+// generated yet. It returns nil if the random generation failed.
+func (k *SaltedKey) Salt() []byte {
+	salt, _ := k.getSalt()
+	return bytes.Clone(salt)
+}
+
+// getSalt returns the salt, generating a random one on first call.
+func (k *SaltedKey) getSalt() ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.salt == nil && k.saltErr == nil {
+		k.salt, k.saltErr = randomBytes(autoSaltSize)
+	}
+	return k.salt, k.saltErr
+}
+
+// Bytes derives the key from the passphrase and the salt.
+func (k *SaltedKey) Bytes() []byte {
+	key, _ := k.BytesE()
+	return key
+}
+
+// BytesE derives the key like Bytes, and returns the error of the salt
+// generation or the derivation as well.
+func (k *SaltedKey) BytesE() ([]byte, error) {
+	salt, err := k.getSalt()
+	if err != nil {
+		return []byte{}, err
+	}
+	gen := k.gen
+	gen.Salt = string(salt)
+	return gen.BytesE()
+}
+
 //////// nonce //////////
 
+// Purposes of the derived keys for domain separation.
+// AES keys use no purpose, to stay compatible with the keys derived before.
+const (
+	keyPurposeNonce = "nonce"
+	keyPurposeIv    = "iv"
+)
+
 // NonceSize is the default size of the nonce for AEAD ciphers.
 const (
 	NonceSize KeyLen = 12
@@ -235,8 +831,20 @@ const (
 //
 // The output key will be derived from the passphrase via
 // Sequential Memory-Hard Functions with [DefaultSalt].
+//
+// The derivation is domain separated from [NewAesKey] and [NewIv],
+// so the same passphrase can be used without producing correlated outputs.
+//
+// Attention: the domain separation changed the derived nonces, so the
+// ciphertexts encrypted with the nonces of older versions (including the
+// ones of [SimpleGCM]) no longer decrypt with them. Derive the old nonce
+// with [WithLegacyDerivation] to decrypt them:
+//
+//	legacy := simplecipher.NewGCM(simplecipher.NewAesKey("key"),
+//		simplecipher.NewNonce("nonce", simplecipher.WithLegacyDerivation()))
 func NewNonce(passphrase string, options ...KeyGenOption) Key {
 	keygen := newKeyGen(passphrase, NonceSize, DefaultSalt())
+	keygen.Purpose = keyPurposeNonce
 
 	for _, opt := range options {
 		opt(keygen)
@@ -245,14 +853,45 @@ func NewNonce(passphrase string, options ...KeyGenOption) Key {
 	return keygen
 }
 
+// timeNonceCounter is the per-process counter used by [TimeNonce].
+var timeNonceCounter atomic.Uint32
+
+// TimeNonce creates a new [NonceSize] nonce from the current time.
+//
+// The first 8 bytes are the current Unix time in nanoseconds (big-endian),
+// and the last 4 bytes are a per-process atomic counter (big-endian).
+// The counter makes the nonces unique within a process, even if two
+// nonces are created at the same nanosecond.
+//
+// Notice that the uniqueness is not guaranteed across processes
+// (use a random nonce instead), or if the clock goes backward: a repeated
+// timestamp is only told apart by the counter, which wraps around every
+// 2^32 nonces.
+func TimeNonce() Key {
+	nonce := make([]byte, 0, NonceSize)
+	nonce = binary.BigEndian.AppendUint64(nonce, uint64(time.Now().UnixNano()))
+	nonce = binary.BigEndian.AppendUint32(nonce, timeNonceCounter.Add(1))
+	return Bytes(nonce)
+}
+
 //////// iv //////////
 
 // NewIv creates a new IV with [aes.BlockSize] bytes.
 //
 // The output key will be derived from the passphrase via
 // Sequential Memory-Hard Functions with [DefaultSalt].
+//
+// The derivation is domain separated from [NewAesKey] and [NewNonce],
+// so the same passphrase can be used without producing correlated outputs.
+//
+// Attention: the domain separation changed the derived IVs, so the
+// ciphertexts encrypted with the IVs of older versions and
+// [NewCBCNoIVPrepend] no longer decrypt with them. Derive the old IV with
+// [WithLegacyDerivation] to decrypt them. (The ciphertexts of [NewCBC]
+// are not affected, as they carry their IV.)
 func NewIv(passphrase string, options ...KeyGenOption) Key {
 	keygen := newKeyGen(passphrase, aes.BlockSize, DefaultSalt())
+	keygen.Purpose = keyPurposeIv
 
 	for _, opt := range options {
 		opt(keygen)
@@ -271,3 +910,149 @@ func NewRandomIv() Key {
 
 	return NewIv(fmt.Sprint(mathrand.Float64(), time.Now()))
 }
+
+//////// Key & IV //////////
+
+// hkdfKey expands a secret into a key of Len bytes using HKDF-SHA256,
+// with Info as the context label to separate keys of different purposes.
+type hkdfKey struct {
+	// Secret is the (already strong) input keying material.
+	Secret Key
+	// Info is the context and application specific label.
+	Info string
+	// Len is the length of the key to generate in bytes.
+	Len KeyLen
+	// Hash is the hash function of HKDF, nil for SHA-256.
+	Hash func() hash.Hash
+}
+
+var _ Key = (*hkdfKey)(nil)
+
+// Bytes return the key expanded from the Secret with the Info label.
+//
+// Len <= 0 will return an empty byte slice ([]byte{}).
+// So does an empty (or nil) Secret, to fail with [ErrEmptyKey] like it.
+func (k hkdfKey) Bytes() []byte {
+	key, _ := k.BytesE()
+	return key
+}
+
+// BytesE expands the key like Bytes, and returns the error of the Secret,
+// if it is a [FallibleKey], as well.
+func (k hkdfKey) BytesE() ([]byte, error) {
+	secret, err := keyBytesE(k.Secret)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	expectedKeyLen := int(k.Len)
+	if expectedKeyLen < 0 || len(secret) == 0 {
+		expectedKeyLen = 0
+	}
+
+	key := make([]byte, expectedKeyLen)
+
+	h := k.Hash
+	if h == nil {
+		h = sha256.New
+	}
+
+	reader := hkdf.Expand(h, secret, []byte(k.Info))
+	if _, err := io.ReadFull(reader, key); err != nil {
+		// only happens if Len > 255 * Hash().Size()
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// keyBytesE returns the bytes of the key, with the error of BytesE if it
+// is a [FallibleKey]. A nil key is empty.
+func keyBytesE(k Key) ([]byte, error) {
+	if f, ok := k.(FallibleKey); ok {
+		return f.BytesE()
+	}
+	return keyOrEmpty(k).Bytes(), nil
+}
+
+// HKDF info labels used by [DeriveKeyAndIV].
+const (
+	hkdfInfoAesKey = "simplecipher aes key"
+	hkdfInfoIv     = "simplecipher iv"
+)
+
+// DeriveKeyAndIV derives an AES key and an IV from a single passphrase.
+//
+// A master secret is derived from the passphrase via scrypt (with
+// [DefaultSalt], use [WithSalt] to customize it), and then expanded into
+// the key and the IV via HKDF (SHA-256 by default, see [WithHash])
+// with distinct info labels.
+// So that the key and the IV are independent of each other,
+// but reproducible from the same passphrase and salt.
+//
+// Available key lengths are [Aes128], [Aes192], and [Aes256].
+// If an invalid key length is provided, it will default to [Aes256].
+// The IV is [aes.BlockSize] bytes long.
+//
+// This is preferable to calling [NewAesKey] and [NewIv] with
+// the same passphrase:
+//
+//	key, iv := simplecipher.DeriveKeyAndIV("passphrase", simplecipher.Aes256)
+//	cipher := simplecipher.NewCBC(key, iv)
+func DeriveKeyAndIV(passphrase string, keyLen KeyLen, opts ...KeyGenOption) (key Key, iv Key) {
+	if keyLen != Aes128 && keyLen != Aes192 && keyLen != Aes256 {
+		// invalid key length for AES, default to Aes256
+		keyLen = Aes256
+	}
+
+	master := newKeyGen(passphrase, Aes256, DefaultSalt())
+
+	for _, opt := range opts {
+		opt(master)
+	}
+
+	key = &hkdfKey{Secret: master, Info: hkdfInfoAesKey, Len: keyLen, Hash: master.Hash}
+	iv = &hkdfKey{Secret: master, Info: hkdfInfoIv, Len: aes.BlockSize, Hash: master.Hash}
+
+	return key, iv
+}
+
+// hkdfInfoNamedKey is the HKDF info label prefix of the keys derived by
+// [DeriveKeys], followed by the name of the key.
+const hkdfInfoNamedKey = "simplecipher key:"
+
+// DeriveKeys derives several independent keys from a single passphrase,
+// one for each name in specs, of the length specified for it:
+//
+//	keys := simplecipher.DeriveKeys("passphrase", map[string]simplecipher.KeyLen{
+//		"encryption": simplecipher.Aes256,
+//		"mac":        32,
+//		"filename":   64,
+//	})
+//	cipher := simplecipher.NewGCMSynthNonce(keys["encryption"])
+//
+// Like [DeriveKeyAndIV], a master secret is derived from the passphrase via
+// scrypt (with [DefaultSalt], use [WithSalt] to customize it), and expanded
+// into each key via HKDF (SHA-256 by default, see [WithHash]) with the name
+// in the info label. But scrypt runs only once, in the call, instead of on
+// every use of the keys: the returned keys are materialized
+// (see [MaterializeKey]).
+//
+// The keys are reproducible from the same passphrase, salt and names.
+// A length <= 0 derives an empty key.
+func DeriveKeys(passphrase string, specs map[string]KeyLen, opts ...KeyGenOption) map[string]Key {
+	gen := newKeyGen(passphrase, Aes256, DefaultSalt())
+
+	for _, opt := range opts {
+		opt(gen)
+	}
+
+	master := MaterializeKey(gen)
+
+	keys := make(map[string]Key, len(specs))
+	for name, keyLen := range specs {
+		keys[name] = MaterializeKey(&hkdfKey{Secret: master, Info: hkdfInfoNamedKey + name, Len: keyLen, Hash: gen.Hash})
+	}
+
+	return keys
+}
@@ -1,9 +1,21 @@
 package simplecipher
 
 import (
+	"bytes"
+	"crypto/aes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"golang.org/x/crypto/scrypt"
 	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestBytes_Bytes(t *testing.T) {
@@ -39,6 +51,34 @@ func TestBytes_Bytes(t *testing.T) {
 	}
 }
 
+func TestBytes_TextMarshaler(t *testing.T) {
+	type config struct {
+		Key bytesKey `json:"key"`
+	}
+
+	var c config
+	err := json.Unmarshal([]byte(`{"key": "000102030a0b0c0d"}`), &c)
+	if err != nil {
+		t.Fatalf("json.Unmarshal error: %v", err)
+	}
+	if want := []byte{0, 1, 2, 3, 10, 11, 12, 13}; !reflect.DeepEqual(c.Key.Bytes(), want) {
+		t.Errorf("json.Unmarshal() key = %v, want %v", c.Key.Bytes(), want)
+	}
+
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("json.Marshal error: %v", err)
+	}
+	if want := `{"key":"000102030a0b0c0d"}`; string(b) != want {
+		t.Errorf("json.Marshal() = %s, want %s", b, want)
+	}
+
+	err = json.Unmarshal([]byte(`{"key": "not hex"}`), &c)
+	if err == nil {
+		t.Errorf("json.Unmarshal(not hex) expected error, got none")
+	}
+}
+
 func TestString_Bytes(t *testing.T) {
 	DefaultSalt = func() string { return "testsalt" }
 
@@ -145,6 +185,41 @@ func Test_keyGen_Bytes(t *testing.T) {
 	}
 }
 
+func Test_keyGen_Bytes_StrictKDF(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	defer func(orig bool) { StrictKDF = orig }(StrictKDF)
+
+	k := keyGen{Passphrase: "hello, world", Len: Aes256, Salt: "testsalt"}
+	want := k.Bytes()
+
+	StrictKDF = true
+	if got := k.Bytes(); !bytes.Equal(got, want) {
+		t.Errorf("strict: Bytes() = %x, want %x", got, want)
+	}
+
+	// make scrypt fail
+	defer func(orig func([]byte, []byte, int, int, int, int) ([]byte, error)) { scryptKey = orig }(scryptKey)
+	scryptKey = func([]byte, []byte, int, int, int, int) ([]byte, error) {
+		return nil, errors.New("scrypt failed")
+	}
+
+	StrictKDF = false
+	if got := k.Bytes(); len(got) != int(Aes256) {
+		t.Errorf("non-strict fallback: len(Bytes()) = %v, want %v", len(got), Aes256)
+	}
+
+	StrictKDF = true
+	if got := k.Bytes(); len(got) != 0 {
+		t.Errorf("strict fallback: Bytes() = %x, want empty", got)
+	}
+
+	_, err := NewGCM(&k, NewNonce("nonce")).Encrypt("plaintext")
+	if err == nil {
+		t.Errorf("strict fallback: Encrypt error = nil, want non-nil")
+	}
+}
+
 func TestNewAesKey(t *testing.T) {
 	DefaultSalt = func() string { return "testsalt" }
 
@@ -232,14 +307,14 @@ func TestNewNonce(t *testing.T) {
 			args: args{
 				passphrase: "",
 			},
-			wantBytes: "71fca1d2ac9cc7c23b1c5567",
+			wantBytes: "85bb428028af9f63caf6a6e9",
 		},
 		{
 			name: "helloworld",
 			args: args{
 				passphrase: "hello, world",
 			},
-			wantBytes: "4f1db40b0cd47e1d2639da8c",
+			wantBytes: "ca575d6acf48beaa86a8b6fd",
 		},
 		{
 			name: "custom_len",
@@ -249,7 +324,7 @@ func TestNewNonce(t *testing.T) {
 					WithLen(16),
 				},
 			},
-			wantBytes: "4f1db40b0cd47e1d2639da8c95ef6d1b",
+			wantBytes: "ca575d6acf48beaa86a8b6fdd62ae9cf",
 		},
 		{
 			name: "custom_salt",
@@ -259,7 +334,7 @@ func TestNewNonce(t *testing.T) {
 					WithSalt("custom salt"),
 				},
 			},
-			wantBytes: "ce5c691766c31c558f54aef8",
+			wantBytes: "112610e481d72134f4f009aa",
 		},
 	}
 	for _, tt := range tests {
@@ -292,14 +367,14 @@ func TestNewIv(t *testing.T) {
 			args: args{
 				passphrase: "",
 			},
-			wantBytes: "71fca1d2ac9cc7c23b1c5567aeb83df3",
+			wantBytes: "89bb444e9fa659533833cd3b69e15e13",
 		},
 		{
 			name: "helloworld",
 			args: args{
 				passphrase: "hello, world",
 			},
-			wantBytes: "4f1db40b0cd47e1d2639da8c95ef6d1b",
+			wantBytes: "63780834d0470637bdd967a93e00add3",
 		},
 		{
 			name: "custom_len",
@@ -309,7 +384,7 @@ func TestNewIv(t *testing.T) {
 					WithLen(16),
 				},
 			},
-			wantBytes: "4f1db40b0cd47e1d2639da8c95ef6d1b",
+			wantBytes: "63780834d0470637bdd967a93e00add3",
 		},
 		{
 			name: "custom_salt",
@@ -319,7 +394,7 @@ func TestNewIv(t *testing.T) {
 					WithSalt("custom salt"),
 				},
 			},
-			wantBytes: "ce5c691766c31c558f54aef88785963e",
+			wantBytes: "c02f5071802b9f0148570584fbfe9f1e",
 		},
 	}
 	for _, tt := range tests {
@@ -335,6 +410,101 @@ func TestNewIv(t *testing.T) {
 	}
 }
 
+func TestNewIv_notKeyPrefix(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	passphrase := "hello, world"
+
+	key := NewAesKey(passphrase).Bytes()
+	iv := NewIv(passphrase).Bytes()
+	nonce := NewNonce(passphrase).Bytes()
+
+	if bytes.HasPrefix(key, iv) {
+		t.Errorf("NewIv() = %x is a prefix of NewAesKey() = %x", iv, key)
+	}
+	if bytes.HasPrefix(key, nonce) {
+		t.Errorf("NewNonce() = %x is a prefix of NewAesKey() = %x", nonce, key)
+	}
+	if bytes.HasPrefix(iv, nonce) {
+		t.Errorf("NewNonce() = %x is a prefix of NewIv() = %x", nonce, iv)
+	}
+}
+
+func TestAsIV_AsNonce(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	raw := []byte("iv00iv01iv02iv03")
+
+	// IV and Nonce are distinct types: a Nonce is not an IV and vice versa
+	iv := AsIV(Bytes(raw))
+	if _, ok := any(iv).(Nonce); ok {
+		t.Errorf("AsIV() is a Nonce, want not")
+	}
+	nonce := AsNonce(Bytes(raw[:NonceSize]))
+	if _, ok := any(nonce).(IV); ok {
+		t.Errorf("AsNonce() is an IV, want not")
+	}
+
+	if !bytes.Equal(iv.Bytes(), raw) {
+		t.Errorf("AsIV(Bytes()).Bytes() = %x, want %x", iv.Bytes(), raw)
+	}
+	if got := AsIV(String(string(raw))).Bytes(); !bytes.Equal(got, raw) {
+		t.Errorf("AsIV(String()).Bytes() = %x, want %x", got, raw)
+	}
+	if _, nested := AsIV(iv).(ivKey).Key.(IV); nested {
+		t.Errorf("AsIV(iv) wraps the iv again, want the same iv")
+	}
+
+	if !bytes.Equal(nonce.Bytes(), raw[:NonceSize]) {
+		t.Errorf("AsNonce(Bytes()).Bytes() = %x, want %x", nonce.Bytes(), raw[:NonceSize])
+	}
+	if _, nested := AsNonce(nonce).(nonceKey).Key.(Nonce); nested {
+		t.Errorf("AsNonce(nonce) wraps the nonce again, want the same nonce")
+	}
+
+	// typed ivs and nonces are accepted by the constructors, like any Key
+	testCipher("NewCBC", t, func() Cipher { return NewCBC(NewAesKey("key"), AsIV(NewIv("iv"))) }, "plain-text-plain")
+	testCipher("NewGCM", t, func() Cipher { return NewGCM(NewAesKey("key"), AsNonce(NewNonce("nonce"))) }, "plaintext")
+}
+
+func TestWithLegacyDerivation(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	// the golden values of the versions before the domain separation
+	tests := []struct {
+		name string
+		key  Key
+		want string
+	}{
+		{"nonce", NewNonce("hello, world", WithLegacyDerivation()), "4f1db40b0cd47e1d2639da8c"},
+		{"nonceSalt", NewNonce("hello, world", WithLegacyDerivation(), WithSalt("custom salt")), "ce5c691766c31c558f54aef8"},
+		{"iv", NewIv("hello, world", WithLegacyDerivation()), "4f1db40b0cd47e1d2639da8c95ef6d1b"},
+		{"ivEmpty", NewIv("", WithLegacyDerivation()), "71fca1d2ac9cc7c23b1c5567aeb83df3"},
+		{"aesKey", NewAesKey("hello, world", WithLegacyDerivation()), hex.EncodeToString(NewAesKey("hello, world").Bytes())},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hex.EncodeToString(tt.key.Bytes()); got != tt.want {
+				t.Errorf("Bytes() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	// old ciphertexts decrypt with the legacy nonce
+	key := NewAesKey("key")
+	old := NewGCM(key, NewNonce("nonce", WithLegacyDerivation()))
+	ciphertext, err := NewGCM(key, NewKey("nonce", NonceSize, DefaultSalt())).Encrypt("plaintext")
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+	if plaintext, err := old.Decrypt(ciphertext); err != nil || plaintext != "plaintext" {
+		t.Errorf("Decrypt() with the legacy nonce = %q, %v, want %q", plaintext, err, "plaintext")
+	}
+	if _, err := SimpleGCM("key", "nonce").Decrypt(ciphertext); err == nil {
+		t.Errorf("Decrypt() with the domain separated nonce error = nil, want an error")
+	}
+}
+
 func TestNewRandomIv(t *testing.T) {
 	DefaultSalt = func() string { return "testsalt" }
 
@@ -347,6 +517,89 @@ func TestNewRandomIv(t *testing.T) {
 	// t.Logf("iv1: %x, iv2: %x", iv1.Bytes(), iv2.Bytes())
 }
 
+func TestTimeNonce(t *testing.T) {
+	const goroutines = 16
+	const perGoroutine = 1000
+
+	var mu sync.Mutex
+	seen := make(map[string]bool, goroutines*perGoroutine)
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perGoroutine; j++ {
+				nonce := TimeNonce().Bytes()
+				if len(nonce) != int(NonceSize) {
+					t.Errorf("TimeNonce() len = %v, want %v", len(nonce), NonceSize)
+				}
+
+				mu.Lock()
+				if seen[string(nonce)] {
+					t.Errorf("TimeNonce() = %x, duplicated", nonce)
+				}
+				seen[string(nonce)] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestDeriveKeyAndIV(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	tests := []struct {
+		name       string
+		passphrase string
+		keyLen     KeyLen
+		options    []KeyGenOption
+		wantKeyLen int
+	}{
+		{
+			name:       "aes256",
+			passphrase: "hello, world",
+			keyLen:     Aes256,
+			wantKeyLen: 32,
+		},
+		{
+			name:       "aes128_salt",
+			passphrase: "hello, world",
+			keyLen:     Aes128,
+			options:    []KeyGenOption{WithSalt("custom salt")},
+			wantKeyLen: 16,
+		},
+		{
+			name:       "invalid_len",
+			passphrase: "",
+			keyLen:     7,
+			wantKeyLen: 32,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, iv := DeriveKeyAndIV(tt.passphrase, tt.keyLen, tt.options...)
+
+			keyBytes, ivBytes := key.Bytes(), iv.Bytes()
+			if len(keyBytes) != tt.wantKeyLen {
+				t.Errorf("len(key) = %v, want %v", len(keyBytes), tt.wantKeyLen)
+			}
+			if len(ivBytes) != aes.BlockSize {
+				t.Errorf("len(iv) = %v, want %v", len(ivBytes), aes.BlockSize)
+			}
+			if bytes.HasPrefix(keyBytes, ivBytes) || bytes.Contains(keyBytes, ivBytes[:8]) {
+				t.Errorf("key (%x) and iv (%x) are correlated", keyBytes, ivBytes)
+			}
+
+			anotherKey, anotherIv := DeriveKeyAndIV(tt.passphrase, tt.keyLen, tt.options...)
+			if !bytes.Equal(keyBytes, anotherKey.Bytes()) || !bytes.Equal(ivBytes, anotherIv.Bytes()) {
+				t.Errorf("DeriveKeyAndIV() is not reproducible")
+			}
+		})
+	}
+}
+
 func ExampleNewKey() {
 	// derive a key from a passphrase
 
@@ -359,3 +612,471 @@ func ExampleNewKey() {
 	// use the key for encryption or any other purpose
 	_ = key
 }
+
+func TestDeriveKeyAndIV_WithHash(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	defaultKey, defaultIv := DeriveKeyAndIV("passphrase", Aes256)
+	sha256Key, sha256Iv := DeriveKeyAndIV("passphrase", Aes256, WithHash(sha256.New))
+	sha512Key, sha512Iv := DeriveKeyAndIV("passphrase", Aes256, WithHash(sha512.New))
+
+	if !bytes.Equal(defaultKey.Bytes(), sha256Key.Bytes()) || !bytes.Equal(defaultIv.Bytes(), sha256Iv.Bytes()) {
+		t.Errorf("default hash is not SHA-256")
+	}
+	if bytes.Equal(sha256Key.Bytes(), sha512Key.Bytes()) || bytes.Equal(sha256Iv.Bytes(), sha512Iv.Bytes()) {
+		t.Errorf("SHA-256 and SHA-512 derived the same key %x", sha256Key.Bytes())
+	}
+
+	anotherKey, anotherIv := DeriveKeyAndIV("passphrase", Aes256, WithHash(sha512.New))
+	if !bytes.Equal(sha512Key.Bytes(), anotherKey.Bytes()) || !bytes.Equal(sha512Iv.Bytes(), anotherIv.Bytes()) {
+		t.Errorf("WithHash(sha512.New) is not reproducible")
+	}
+	if len(sha512Key.Bytes()) != int(Aes256) || len(sha512Iv.Bytes()) != aes.BlockSize {
+		t.Errorf("WithHash(sha512.New) lengths = %d, %d, want %d, %d", len(sha512Key.Bytes()), len(sha512Iv.Bytes()), Aes256, aes.BlockSize)
+	}
+
+	// a nil hash is an error, not the default
+	nilKey, nilIv := DeriveKeyAndIV("passphrase", Aes256, WithHash(nil))
+	for name, k := range map[string]Key{"key": nilKey, "iv": nilIv} {
+		if b, err := k.(FallibleKey).BytesE(); !errors.Is(err, ErrInvalidConfig) || len(b) != 0 {
+			t.Errorf("WithHash(nil) %s BytesE() = %x, %v, want an empty key and %v", name, b, err, ErrInvalidConfig)
+		}
+	}
+	if _, err := NewCBC(nilKey, nilIv).Encrypt("plain-text-plain"); err == nil {
+		t.Errorf("NewCBC(WithHash(nil) key).Encrypt() error = nil, want an error")
+	}
+}
+
+func TestKeyFromEnv(t *testing.T) {
+	raw := []byte("key0key1key2key3key4key5key6key7")
+
+	tests := []struct {
+		name    string
+		value   string
+		keyLen  KeyLen
+		wantErr error
+	}{
+		{"hex", hex.EncodeToString(raw), Aes256, nil},
+		{"base64", base64.StdEncoding.EncodeToString(raw), Aes256, nil},
+		{"base64NoPadding", base64.RawStdEncoding.EncodeToString(raw), Aes256, nil},
+		{"whitespace", " " + hex.EncodeToString(raw) + "\n", Aes256, nil},
+		{"missing", "", Aes256, ErrEnvVarMissing},
+		{"wrongLen", hex.EncodeToString(raw), Aes128, ErrKeySize},
+		{"malformed", "not a key!", Aes256, ErrInvalidConfig},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SIMPLECIPHER_TEST_KEY", tt.value)
+
+			key, err := KeyFromEnv("SIMPLECIPHER_TEST_KEY", tt.keyLen)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("KeyFromEnv() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if strings.Contains(err.Error(), strings.TrimSpace(tt.value)) && tt.value != "" {
+					t.Errorf("KeyFromEnv() error = %v leaks the value", err)
+				}
+				return
+			}
+			if !bytes.Equal(key.Bytes(), raw) {
+				t.Errorf("KeyFromEnv() = %x, want %x", key.Bytes(), raw)
+			}
+		})
+	}
+
+	if _, err := KeyFromEnv("SIMPLECIPHER_TEST_KEY_UNSET", Aes256); !errors.Is(err, ErrEnvVarMissing) {
+		t.Errorf("KeyFromEnv(unset) error = %v, want %v", err, ErrEnvVarMissing)
+	}
+
+	// an undecodable key is not a ciphertext
+	t.Setenv("SIMPLECIPHER_TEST_KEY", "not a key!")
+	if _, err := KeyFromEnv("SIMPLECIPHER_TEST_KEY", Aes256); errors.Is(err, ErrMalformedCiphertext) {
+		t.Errorf("KeyFromEnv(malformed) error = %v, want not %v", err, ErrMalformedCiphertext)
+	}
+}
+
+func TestMaterializeKey(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	lazy := NewAesKey("passphrase")
+	key := MaterializeKey(lazy)
+
+	if _, ok := key.(bytesKey); !ok {
+		t.Errorf("MaterializeKey() = %T, want bytesKey", key)
+	}
+	if !bytes.Equal(key.Bytes(), lazy.Bytes()) {
+		t.Errorf("MaterializeKey().Bytes() = %x, want %x", key.Bytes(), lazy.Bytes())
+	}
+
+	// ciphers with the lazy and the materialized key are interchangeable
+	nonce := NewNonce("nonce")
+	ciphertext, err := NewGCM(lazy, nonce).Encrypt("plaintext")
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+	plaintext, err := NewGCM(key, nonce).Decrypt(ciphertext)
+	if err != nil || plaintext != "plaintext" {
+		t.Errorf("Decrypt() = %q, %v, want %q", plaintext, err, "plaintext")
+	}
+}
+
+// BenchmarkMaterializeKey compares building (and using) 100 ciphers
+// from the same passphrase-derived key, lazy vs. materialized.
+func BenchmarkMaterializeKey(b *testing.B) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	nonce := MaterializeKey(NewNonce("nonce"))
+
+	run := func(b *testing.B, key func() Key) {
+		for i := 0; i < b.N; i++ {
+			k := key()
+			for j := 0; j < 100; j++ {
+				if _, err := NewGCM(k, nonce).Encrypt("plaintext"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	}
+
+	b.Run("lazy", func(b *testing.B) {
+		run(b, func() Key { return NewAesKey("passphrase") })
+	})
+	b.Run("materialized", func(b *testing.B) {
+		run(b, func() Key { return MaterializeKey(NewAesKey("passphrase")) })
+	})
+}
+
+func TestKeyFromFunc(t *testing.T) {
+	raw := []byte("key0key1key2key3")
+
+	calls := 0
+	key := KeyFromFunc(func() ([]byte, error) {
+		calls++
+		return raw, nil
+	})
+	if !bytes.Equal(key.Bytes(), raw) {
+		t.Errorf("Bytes() = %x, want %x", key.Bytes(), raw)
+	}
+
+	cipher := NewCTR(key, NewIv("iv"))
+	ciphertext, _ := cipher.Encrypt("plaintext")
+	plaintext, err := cipher.Decrypt(ciphertext)
+	if err != nil || plaintext != "plaintext" {
+		t.Errorf("Decrypt() = %q, %v, want %q", plaintext, err, "plaintext")
+	}
+	if calls < 3 {
+		t.Errorf("KeyFunc called %d times, want once per use", calls)
+	}
+
+	errKMS := errors.New("kms unavailable")
+	failing := KeyFromFunc(func() ([]byte, error) { return nil, errKMS })
+	if b := failing.Bytes(); b != nil {
+		t.Errorf("Bytes() = %x, want nil", b)
+	}
+	if _, err := failing.(KeyFunc).BytesE(); !errors.Is(err, errKMS) {
+		t.Errorf("BytesE() error = %v, want %v", err, errKMS)
+	}
+	if _, err := NewGCM(failing, NewNonce("nonce")).Encrypt("plaintext"); err == nil {
+		t.Errorf("Encrypt() with a failing KeyFunc error = nil, want non-nil")
+	}
+}
+
+func TestKeyFromFunc_GCM(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	raw := []byte("key0key1key2key3")
+	errKMS := errors.New("kms unavailable")
+
+	calls := 0
+	fail := true
+	key := KeyFromFunc(func() ([]byte, error) {
+		calls++
+		if fail {
+			return nil, errKMS
+		}
+		return raw, nil
+	})
+	cipher := NewGCM(key, NewNonce("nonce"))
+
+	if _, err := cipher.Encrypt("plaintext"); err == nil {
+		t.Fatalf("Encrypt() with a failing KeyFunc error = nil, want non-nil")
+	}
+
+	// recovers once the key store is back
+	fail = false
+	ciphertext, err := cipher.Encrypt("plaintext")
+	if err != nil {
+		t.Fatalf("Encrypt() after the recovery error = %v, want nil", err)
+	}
+	if plaintext, err := cipher.Decrypt(ciphertext); err != nil || plaintext != "plaintext" {
+		t.Errorf("Decrypt() = %q, %v, want %q", plaintext, err, "plaintext")
+	}
+
+	// fetched per operation, not memoized
+	if calls != 3 {
+		t.Errorf("KeyFunc called %d times, want 3 (once per operation)", calls)
+	}
+
+	// a failing fetch fails the operation even after successes
+	fail = true
+	if _, err := cipher.Decrypt(ciphertext); err == nil {
+		t.Errorf("Decrypt() with a failing KeyFunc error = nil, want non-nil")
+	}
+}
+
+func TestWithPepper(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	plain := NewAesKey("passphrase").Bytes()
+	pepper1 := NewAesKey("passphrase", WithPepper("pepper1")).Bytes()
+	pepper2 := NewAesKey("passphrase", WithPepper("pepper2")).Bytes()
+
+	if bytes.Equal(pepper1, plain) || bytes.Equal(pepper1, pepper2) {
+		t.Errorf("WithPepper() keys are not distinct: %x, %x, %x", plain, pepper1, pepper2)
+	}
+	if !bytes.Equal(NewAesKey("passphrase", WithPepper("")).Bytes(), plain) {
+		t.Errorf("WithPepper(\"\") changed the key")
+	}
+	if !bytes.Equal(NewAesKey("passphrase", WithPepper("pepper1")).Bytes(), pepper1) {
+		t.Errorf("WithPepper() is not deterministic")
+	}
+
+	newGCM := func(pepper string) Cipher {
+		return NewGCM(NewAesKey("passphrase", WithPepper(pepper)), NewNonce("nonce"))
+	}
+	ciphertext, _ := newGCM("pepper1").Encrypt("plaintext")
+	if _, err := newGCM("pepper2").Decrypt(ciphertext); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("Decrypt(other pepper) error = %v, want %v", err, ErrAuthenticationFailed)
+	}
+	if plaintext, err := newGCM("pepper1").Decrypt(ciphertext); err != nil || plaintext != "plaintext" {
+		t.Errorf("Decrypt(same pepper) = %q, %v, want %q", plaintext, err, "plaintext")
+	}
+}
+
+func TestWithKDFTimeout(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	// N = 2^17 takes hundreds of milliseconds (and 128 MiB) to derive
+	gen := newKeyGen("passphrase", Aes256, "salt")
+	WithKDFTimeout(time.Millisecond)(gen)
+	gen.N = 1 << 17
+
+	start := time.Now()
+	key, err := gen.BytesE()
+	if !errors.Is(err, ErrKDFTimeout) {
+		t.Errorf("BytesE() error = %v, want %v", err, ErrKDFTimeout)
+	}
+	if len(key) != 0 {
+		t.Errorf("BytesE() = %x, want empty on timeout", key)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("BytesE() took %v, want the caller unblocked after the timeout", elapsed)
+	}
+
+	if _, err := NewGCM(gen, NewNonce("nonce")).Encrypt("plaintext"); err == nil {
+		t.Errorf("Encrypt() with a timed out key error = nil, want non-nil")
+	}
+
+	// a generous timeout does not change the key
+	key, err = NewAesKey("passphrase", WithKDFTimeout(time.Minute)).(FallibleKey).BytesE()
+	if err != nil || !bytes.Equal(key, NewAesKey("passphrase").Bytes()) {
+		t.Errorf("BytesE() = %x, %v, want the key without timeout", key, err)
+	}
+}
+
+func TestFallibleKey_derived(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	key, iv := DeriveKeyAndIV("passphrase", Aes256)
+	for name, k := range map[string]Key{
+		"NewKey":            NewKey("passphrase", Aes256, "salt"),
+		"NewAesKey":         NewAesKey("passphrase"),
+		"NewNonce":          NewNonce("passphrase"),
+		"NewIv":             NewIv("passphrase"),
+		"DeriveKeyAndIV":    key,
+		"DeriveKeyAndIV iv": iv,
+	} {
+		f, ok := k.(FallibleKey)
+		if !ok {
+			t.Errorf("%s key is not a FallibleKey", name)
+			continue
+		}
+		if b, err := f.BytesE(); err != nil || !bytes.Equal(b, k.Bytes()) {
+			t.Errorf("%s BytesE() = %x, %v, want %x", name, b, err, k.Bytes())
+		}
+	}
+}
+
+func TestWithKDFTimeout_shared(t *testing.T) {
+	release := make(chan struct{})
+	var calls atomic.Int32
+	defer func(f func(password, salt []byte, N, r, p, keyLen int) ([]byte, error)) { scryptKey = f }(scryptKey)
+	scryptKey = func(password, salt []byte, N, r, p, keyLen int) ([]byte, error) {
+		calls.Add(1)
+		<-release
+		return make([]byte, keyLen), nil
+	}
+
+	key := NewAesKey("passphrase", WithSalt("salt"), WithKDFTimeout(time.Millisecond))
+	cipher := NewGCM(key, Bytes([]byte("nonce0nonce1")))
+
+	// the retries of a timed out key wait on the same derivation
+	for i := 0; i < 10; i++ {
+		if _, err := key.(FallibleKey).BytesE(); !errors.Is(err, ErrKDFTimeout) {
+			t.Fatalf("BytesE() #%d error = %v, want %v", i, err, ErrKDFTimeout)
+		}
+		if _, err := cipher.Encrypt("plaintext"); err == nil {
+			t.Fatalf("Encrypt() #%d error = nil, want an error", i)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("scrypt runs = %d, want 1", n)
+	}
+
+	// another key derives on its own
+	if _, err := NewAesKey("other", WithSalt("salt"), WithKDFTimeout(time.Millisecond)).(FallibleKey).BytesE(); !errors.Is(err, ErrKDFTimeout) {
+		t.Errorf("BytesE(other) error = %v, want %v", err, ErrKDFTimeout)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("scrypt runs = %d, want 2", n)
+	}
+
+	// once completed, the result is shared with the waiting calls
+	done := make(chan error, 1)
+	go func() {
+		_, err := NewAesKey("passphrase", WithSalt("salt"), WithKDFTimeout(time.Minute)).(FallibleKey).BytesE()
+		done <- err
+	}()
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("BytesE() after the derivation error = %v, want nil", err)
+	}
+	if _, err := cipher.Encrypt("plaintext"); err != nil {
+		t.Errorf("Encrypt() after the derivation error = %v, want nil", err)
+	}
+}
+
+func TestWithScryptN(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	def := NewAesKey("passphrase").Bytes()
+
+	if key := NewAesKey("passphrase", WithScryptN(1024)).Bytes(); bytes.Equal(key, def) {
+		t.Errorf("WithScryptN(1024) derived the default key")
+	}
+	for _, n := range []int{-1, 0, 1, 1000} {
+		if key := NewAesKey("passphrase", WithScryptN(n)).Bytes(); !bytes.Equal(key, def) {
+			t.Errorf("WithScryptN(%d) = %x, want the default key %x", n, key, def)
+		}
+	}
+}
+
+func TestBenchmarkKDF(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	cheap := BenchmarkKDF(WithScryptN(1 << 10))
+	if cheap <= 0 {
+		t.Errorf("BenchmarkKDF(N=2^10) = %v, want > 0", cheap)
+	}
+
+	costly := BenchmarkKDF(WithScryptN(1 << 15))
+	if costly <= cheap {
+		t.Errorf("BenchmarkKDF(N=2^15) = %v, want > %v of N=2^10", costly, cheap)
+	}
+}
+
+func TestNewSaltedKey(t *testing.T) {
+	key := NewSaltedKey("passphrase", nil)
+
+	derived := key.Bytes()
+	if len(derived) != int(Aes256) {
+		t.Fatalf("len(Bytes()) = %d, want %d", len(derived), Aes256)
+	}
+
+	salt := key.Salt()
+	if len(salt) != autoSaltSize {
+		t.Fatalf("len(Salt()) = %d, want %d", len(salt), autoSaltSize)
+	}
+	if !bytes.Equal(key.Bytes(), derived) || !bytes.Equal(key.Salt(), salt) {
+		t.Errorf("SaltedKey changed after the first derivation")
+	}
+
+	reconstructed := NewSaltedKey("passphrase", salt)
+	if !bytes.Equal(reconstructed.Bytes(), derived) {
+		t.Errorf("reconstructed Bytes() = %x, want %x", reconstructed.Bytes(), derived)
+	}
+	if !bytes.Equal(derived, NewAesKey("passphrase", WithSalt(string(salt))).Bytes()) {
+		t.Errorf("Bytes() != NewAesKey with the salt")
+	}
+
+	if other := NewSaltedKey("passphrase", nil); bytes.Equal(other.Salt(), salt) || bytes.Equal(other.Bytes(), derived) {
+		t.Errorf("two random SaltedKeys share the salt %x", salt)
+	}
+
+	if got := NewSaltedKey("passphrase", salt, WithLen(Aes128)).Bytes(); len(got) != int(Aes128) {
+		t.Errorf("len(Bytes()) with WithLen(Aes128) = %d, want %d", len(got), Aes128)
+	}
+
+	ciphertext, err := NewGCM(key, NewNonce("nonce")).Encrypt("plaintext")
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+	plaintext, err := NewGCM(NewSaltedKey("passphrase", key.Salt()), NewNonce("nonce")).Decrypt(ciphertext)
+	if err != nil || plaintext != "plaintext" {
+		t.Errorf("Decrypt() = %q, %v, want %q", plaintext, err, "plaintext")
+	}
+}
+
+func TestDeriveKeys(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	runs := 0
+	defer func(f func(password, salt []byte, N, r, p, keyLen int) ([]byte, error)) { scryptKey = f }(scryptKey)
+	scryptKey = func(password, salt []byte, N, r, p, keyLen int) ([]byte, error) {
+		runs++
+		return scrypt.Key(password, salt, N, r, p, keyLen)
+	}
+
+	specs := map[string]KeyLen{
+		"encryption": Aes256,
+		"mac":        32,
+		"filename":   64,
+		"short":      Aes128,
+	}
+
+	keys := DeriveKeys("passphrase", specs)
+	if runs != 1 {
+		t.Errorf("scrypt runs = %d, want 1", runs)
+	}
+
+	seen := map[string]string{}
+	for name, keyLen := range specs {
+		key := keys[name].Bytes()
+		if len(key) != int(keyLen) {
+			t.Errorf("len(keys[%q]) = %d, want %d", name, len(key), keyLen)
+		}
+		if other, ok := seen[string(key)]; ok {
+			t.Errorf("keys[%q] == keys[%q]", name, other)
+		}
+		seen[string(key)] = name
+	}
+	if runs != 1 {
+		t.Errorf("scrypt runs after using the keys = %d, want 1", runs)
+	}
+
+	again := DeriveKeys("passphrase", specs)
+	for name := range specs {
+		if !bytes.Equal(keys[name].Bytes(), again[name].Bytes()) {
+			t.Errorf("keys[%q] is not reproducible", name)
+		}
+	}
+
+	salted := DeriveKeys("passphrase", specs, WithSalt("other salt"))
+	if bytes.Equal(keys["encryption"].Bytes(), salted["encryption"].Bytes()) {
+		t.Errorf("keys with different salts are equal")
+	}
+	if mac := DeriveKeys("passphrase", map[string]KeyLen{"mac": 16})["mac"].Bytes(); !bytes.Equal(mac, keys["mac"].Bytes()[:16]) {
+		// HKDF output is a prefix of the longer output with the same info
+		t.Errorf("keys[mac] of 16 bytes = %x, want the prefix of %x", mac, keys["mac"].Bytes())
+	}
+}
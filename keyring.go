@@ -0,0 +1,350 @@
+package simplecipher
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+)
+
+// This file implements a keyring of AES-GCM keys for key rotation.
+
+// keyring is a [Cipher] holding an ordered list of keys.
+//
+// Encrypt uses the first (primary) key, and Decrypt tries each key in turn
+// until one succeeds. Trying keys is only safe with an authenticated mode,
+// where a wrong key is detected instead of producing garbage plaintext,
+// so keyring always uses AES-GCM.
+type keyring struct {
+	keys []Key
+}
+
+var _ Cipher = (*keyring)(nil)
+
+// NewKeyring creates a new AES-GCM [Cipher] for key rotation.
+//
+// New ciphertexts are encrypted with the primary key,
+// while old ciphertexts encrypted with any of the fallback keys
+// (previous primary keys) can still be decrypted.
+//
+// A random nonce is generated for each encryption and prepended to the
+// ciphertext. Each key must be 16, 24, or 32 bytes long to select AES-128,
+// AES-192, or AES-256. Use [NewAesKey] if you are not sure.
+func NewKeyring(primary Key, fallbacks ...Key) Cipher {
+	keys := make([]Key, 0, 1+len(fallbacks))
+	keys = append(keys, keyOrEmpty(primary))
+	for _, key := range fallbacks {
+		keys = append(keys, keyOrEmpty(key))
+	}
+
+	return &keyring{keys: keys}
+}
+
+// Mode returns [ModeGCM].
+func (k *keyring) Mode() ModeID {
+	return ModeGCM
+}
+
+// Describe describes the cipher with the primary key, e.g., AES-256-GCM.
+func (k *keyring) Describe() CipherInfo {
+	return CipherInfo{Algorithm: algorithmAES, Mode: ModeGCM, KeyBits: keyBits(k.keys[0]), Codec: defaultCodecName()}
+}
+
+// Encrypt encrypts the given plaintext with the primary key using GCM.
+// The ciphertext is returned with [DefaultStringCodec] encoding.
+func (k *keyring) Encrypt(plainText string) (cipherText string, err error) {
+	defer wrapOpError(&err, encrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	nonce, err := randomBytes(int(NonceSize))
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := sealGCM(k.keys[0].Bytes(), nonce, []byte(plainText))
+	if err != nil {
+		return "", err
+	}
+
+	return DefaultStringCodec.EncodeToString(append(nonce, ciphertext...)), nil
+}
+
+// Decrypt decrypts the given ciphertext using GCM,
+// trying each key of the keyring in order.
+// The ciphertext must be a [DefaultStringCodec] string.
+//
+// If none of the keys can decrypt the ciphertext,
+// an error wrapping [ErrAuthenticationFailed] is returned.
+func (k *keyring) Decrypt(cipherText string) (plainText string, err error) {
+	defer wrapOpError(&err, decrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	ciphertext, err := decodeCipherText(cipherText)
+	if err != nil {
+		return "", err
+	}
+
+	if len(ciphertext) < int(NonceSize)+gcmTagSize {
+		return "", ErrCipherTextTooShort
+	}
+
+	nonce, ciphertext := ciphertext[:NonceSize], ciphertext[NonceSize:]
+
+	for _, key := range k.keys {
+		plaintext, err := openGCM(key.Bytes(), nonce, ciphertext)
+		if err == nil {
+			return string(plaintext), nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: no key in the keyring matches", ErrAuthenticationFailed)
+}
+
+//////// Keyring with key IDs ////////
+
+// keyringWithIDs is a [Cipher] holding keys indexed by key IDs.
+//
+// Encrypt writes the key ID of the primary key into the ciphertext header,
+// so that Decrypt can look up the exact key instead of trying each key.
+//
+// The ciphertext is laid out as:
+//
+//	len(id) (1 byte) | id | nonce (12 bytes) | GCM ciphertext
+type keyringWithIDs struct {
+	keys    map[string]Key
+	primary string
+}
+
+var _ ModeCipher = (*keyringWithIDs)(nil)
+
+// maxKeyIDLen is the maximum length of a key ID in bytes.
+const maxKeyIDLen = 255
+
+// NewKeyringWithIDs creates a new AES-GCM [Cipher] for key rotation,
+// with the keys indexed by (short, non-secret) key IDs.
+//
+// New ciphertexts are encrypted with the key of the primary ID, and the ID is
+// written (in cleartext) into the ciphertext header. Decrypt picks the key by
+// the ID in the header, and returns an error wrapping [ErrUnknownKey] if the
+// ID is not in the keyring.
+//
+// Key IDs must be at most 255 bytes long. Each key must be 16, 24, or 32 bytes
+// long to select AES-128, AES-192, or AES-256.
+func NewKeyringWithIDs(keys map[string]Key, primary string) Cipher {
+	return &keyringWithIDs{keys: keys, primary: primary}
+}
+
+// Mode returns [ModeGCM].
+func (k *keyringWithIDs) Mode() ModeID {
+	return ModeGCM
+}
+
+// Describe describes the cipher with the primary key, e.g., AES-256-GCM.
+func (k *keyringWithIDs) Describe() CipherInfo {
+	var primary Key
+	if key, ok := k.keys[k.primary]; ok {
+		primary = key
+	}
+	return CipherInfo{Algorithm: algorithmAES, Mode: ModeGCM, KeyBits: keyBits(primary), Codec: defaultCodecName()}
+}
+
+// Encrypt encrypts the given plaintext with the primary key using GCM.
+// The ciphertext is returned with [DefaultStringCodec] encoding.
+func (k *keyringWithIDs) Encrypt(plainText string) (cipherText string, err error) {
+	defer wrapOpError(&err, encrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	key, ok := k.keys[k.primary]
+	if !ok {
+		return "", fmt.Errorf("%w: primary key %q", ErrUnknownKey, k.primary)
+	}
+	if len(k.primary) > maxKeyIDLen {
+		return "", fmt.Errorf("key ID too long: %d bytes", len(k.primary))
+	}
+
+	nonce, err := randomBytes(int(NonceSize))
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := sealGCM(keyOrEmpty(key).Bytes(), nonce, []byte(plainText))
+	if err != nil {
+		return "", err
+	}
+
+	header := append([]byte{byte(len(k.primary))}, k.primary...)
+	header = append(header, nonce...)
+
+	return DefaultStringCodec.EncodeToString(append(header, ciphertext...)), nil
+}
+
+// Decrypt decrypts the given ciphertext using GCM,
+// with the key of the ID found in the ciphertext header.
+// The ciphertext must be a [DefaultStringCodec] string.
+func (k *keyringWithIDs) Decrypt(cipherText string) (plainText string, err error) {
+	defer wrapOpError(&err, decrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	ciphertext, err := decodeCipherText(cipherText)
+	if err != nil {
+		return "", err
+	}
+
+	if len(ciphertext) < 1 {
+		return "", ErrCipherTextTooShort
+	}
+
+	idLen := int(ciphertext[0])
+	if len(ciphertext) < 1+idLen+int(NonceSize)+gcmTagSize {
+		return "", ErrCipherTextTooShort
+	}
+
+	id := string(ciphertext[1 : 1+idLen])
+	ciphertext = ciphertext[1+idLen:]
+
+	key, ok := k.keys[id]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownKey, id)
+	}
+
+	nonce, ciphertext := ciphertext[:NonceSize], ciphertext[NonceSize:]
+
+	plaintext, err := openGCM(keyOrEmpty(key).Bytes(), nonce, ciphertext)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+//////// Versioned keys ////////
+
+// keyVersionSize is the size of the key version in the ciphertext header.
+const keyVersionSize = 2
+
+// versionedKeyCipher is a [Cipher] holding keys indexed by numeric versions.
+//
+// The ciphertext is laid out as:
+//
+//	version (2 bytes, big-endian) | nonce (12 bytes) | GCM ciphertext
+//
+// The version is authenticated as the additional data.
+type versionedKeyCipher struct {
+	keys    map[uint16]Key
+	current uint16
+
+	mu sync.Mutex
+	// gcms are the GCM ciphers of the versions used so far, so that each
+	// key is derived once (see gcm.state) rather than on every call.
+	gcms map[uint16]*gcm
+}
+
+var _ ModeCipher = (*versionedKeyCipher)(nil)
+
+// NewVersionedKeyCipher creates a new AES-GCM [Cipher] for key rotation,
+// with the keys indexed by explicit versions.
+//
+// New ciphertexts are encrypted with the key of the current version, and the
+// version is written (in cleartext, authenticated) into the first 2 bytes of
+// the ciphertext. Decrypt picks the key by that version, rather than trying
+// each key, and returns an error wrapping [ErrUnknownKeyVersion] if the
+// version is not in the map.
+//
+// To rotate, add the new key with a greater version and make it current,
+// keeping the old versions until no ciphertext needs them any more.
+// Each key must be 16, 24, or 32 bytes long to select AES-128, AES-192,
+// or AES-256.
+func NewVersionedKeyCipher(keys map[uint16]Key, current uint16) Cipher {
+	return &versionedKeyCipher{keys: keys, current: current}
+}
+
+// Mode returns [ModeGCM].
+func (v *versionedKeyCipher) Mode() ModeID {
+	return ModeGCM
+}
+
+// Describe describes the cipher with the current key, e.g., AES-256-GCM.
+func (v *versionedKeyCipher) Describe() CipherInfo {
+	return CipherInfo{Algorithm: algorithmAES, Mode: ModeGCM, KeyBits: keyBits(v.keys[v.current]), Codec: defaultCodecName()}
+}
+
+// gcm returns the GCM cipher with a random nonce for the key of the version,
+// created on first use.
+func (v *versionedKeyCipher) gcm(version uint16) (*gcm, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if g, ok := v.gcms[version]; ok {
+		return g, nil
+	}
+
+	key, ok := v.keys[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownKeyVersion, version)
+	}
+
+	g := &gcm{cfg: GCMConfig{Key: keyOrEmpty(key), RandomNonce: true}}
+	if v.gcms == nil {
+		v.gcms = make(map[uint16]*gcm)
+	}
+	v.gcms[version] = g
+	return g, nil
+}
+
+// Encrypt encrypts the given plaintext with the key of the current version
+// using GCM. The ciphertext is returned with [DefaultStringCodec] encoding.
+func (v *versionedKeyCipher) Encrypt(plainText string) (cipherText string, err error) {
+	defer wrapOpError(&err, encrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	g, err := v.gcm(v.current)
+	if err != nil {
+		return "", err
+	}
+
+	aesgcm, prefix, nonce, err := g.sealNonce()
+	if err != nil {
+		return "", err
+	}
+
+	out := binary.BigEndian.AppendUint16(nil, v.current)
+	additionalData := out[:keyVersionSize]
+	out = append(out, prefix...)
+	out = aesgcm.Seal(out, nonce, []byte(plainText), additionalData)
+
+	return DefaultStringCodec.EncodeToString(out), nil
+}
+
+// Decrypt decrypts the given ciphertext using GCM,
+// with the key of the version found in the ciphertext header.
+// The ciphertext must be a [DefaultStringCodec] string.
+func (v *versionedKeyCipher) Decrypt(cipherText string) (plainText string, err error) {
+	defer wrapOpError(&err, decrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	ciphertext, err := decodeCipherText(cipherText)
+	if err != nil {
+		return "", err
+	}
+
+	if len(ciphertext) < keyVersionSize {
+		return "", ErrCipherTextTooShort
+	}
+	additionalData, ciphertext := ciphertext[:keyVersionSize], ciphertext[keyVersionSize:]
+
+	g, err := v.gcm(binary.BigEndian.Uint16(additionalData))
+	if err != nil {
+		return "", err
+	}
+
+	aesgcm, nonce, ciphertext, err := g.openNonce(ciphertext)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := aesgcm.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrAuthenticationFailed, err)
+	}
+
+	return string(plaintext), nil
+}
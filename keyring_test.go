@@ -0,0 +1,185 @@
+package simplecipher
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestKeyring_rotation(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	oldKey := NewAesKey("old key")
+	newKey := NewAesKey("new key")
+
+	before := NewKeyring(oldKey)
+
+	oldCiphertext, err := before.Encrypt("old plaintext")
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+
+	// rotate: newKey becomes the primary, oldKey is kept as a fallback
+	after := NewKeyring(newKey, oldKey)
+
+	decrypted, err := after.Decrypt(oldCiphertext)
+	if err != nil {
+		t.Fatalf("Decrypt(old) error: %v", err)
+	}
+	if decrypted != "old plaintext" {
+		t.Errorf("Decrypt(old) = %s, want %s", decrypted, "old plaintext")
+	}
+
+	testCipher("afterRotation", t, func() Cipher { return after }, "new plaintext")
+
+	newCiphertext, err := after.Encrypt("new plaintext")
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+	_, err = before.Decrypt(newCiphertext)
+	if !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("Decrypt(new) with old keyring error = %v, want %v", err, ErrAuthenticationFailed)
+	}
+}
+
+func TestKeyringWithIDs(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	v1 := NewAesKey("key v1")
+	v2 := NewAesKey("key v2")
+
+	before := NewKeyringWithIDs(map[string]Key{"v1": v1}, "v1")
+
+	oldCiphertext, err := before.Encrypt("old plaintext")
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+
+	after := NewKeyringWithIDs(map[string]Key{"v1": v1, "v2": v2}, "v2")
+
+	decrypted, err := after.Decrypt(oldCiphertext)
+	if err != nil {
+		t.Fatalf("Decrypt(old) error: %v", err)
+	}
+	if decrypted != "old plaintext" {
+		t.Errorf("Decrypt(old) = %s, want %s", decrypted, "old plaintext")
+	}
+
+	testCipher("afterRotation", t, func() Cipher { return after }, "new plaintext")
+
+	// the key is picked by ID: no trial decryption with the other keys
+	swapped := NewKeyringWithIDs(map[string]Key{"v1": v2, "v2": v1}, "v2")
+	_, err = swapped.Decrypt(oldCiphertext)
+	if !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("Decrypt(old) with swapped IDs error = %v, want %v", err, ErrAuthenticationFailed)
+	}
+
+	newCiphertext, err := after.Encrypt("new plaintext")
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+	_, err = before.Decrypt(newCiphertext)
+	if !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Decrypt(new) with old keyring error = %v, want %v", err, ErrUnknownKey)
+	}
+
+	_, err = NewKeyringWithIDs(map[string]Key{"v1": v1}, "v3").Encrypt("plaintext")
+	if !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Encrypt with unknown primary error = %v, want %v", err, ErrUnknownKey)
+	}
+}
+
+func TestVersionedKeyCipher(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	v1 := NewAesKey("key v1")
+	v2 := NewAesKey("key v2")
+
+	before := NewVersionedKeyCipher(map[uint16]Key{1: v1}, 1)
+	after := NewVersionedKeyCipher(map[uint16]Key{1: v1, 2: v2}, 2)
+
+	testCipher("afterRotation", t, func() Cipher { return after }, "new plaintext")
+
+	oldCiphertext, err := before.Encrypt("old plaintext")
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+	newCiphertext, err := after.Encrypt("new plaintext")
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name       string
+		cipherText string
+		want       string
+	}{
+		{"v1", oldCiphertext, "old plaintext"},
+		{"v2", newCiphertext, "new plaintext"},
+	} {
+		decrypted, err := after.Decrypt(tt.cipherText)
+		if err != nil {
+			t.Fatalf("Decrypt(%s) error: %v", tt.name, err)
+		}
+		if decrypted != tt.want {
+			t.Errorf("Decrypt(%s) = %s, want %s", tt.name, decrypted, tt.want)
+		}
+	}
+
+	// each version decrypts with its own key only
+	swapped := NewVersionedKeyCipher(map[uint16]Key{1: v2, 2: v1}, 2)
+	for _, cipherText := range []string{oldCiphertext, newCiphertext} {
+		if _, err := swapped.Decrypt(cipherText); !errors.Is(err, ErrAuthenticationFailed) {
+			t.Errorf("Decrypt with swapped versions error = %v, want %v", err, ErrAuthenticationFailed)
+		}
+	}
+
+	_, err = before.Decrypt(newCiphertext)
+	if !errors.Is(err, ErrUnknownKeyVersion) {
+		t.Errorf("Decrypt(v2) with v1 only error = %v, want %v", err, ErrUnknownKeyVersion)
+	}
+
+	_, err = NewVersionedKeyCipher(map[uint16]Key{1: v1}, 3).Encrypt("plaintext")
+	if !errors.Is(err, ErrUnknownKeyVersion) {
+		t.Errorf("Encrypt with missing current version error = %v, want %v", err, ErrUnknownKeyVersion)
+	}
+
+	// the version is authenticated
+	raw, err := DefaultStringCodec.DecodeString(newCiphertext)
+	if err != nil {
+		t.Fatalf("DecodeString error: %v", err)
+	}
+	raw[1] = 1
+	_, err = NewVersionedKeyCipher(map[uint16]Key{1: v2}, 1).Decrypt(DefaultStringCodec.EncodeToString(raw))
+	if !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("Decrypt with forged version error = %v, want %v", err, ErrAuthenticationFailed)
+	}
+}
+
+func TestVersionedKeyCipher_keyOnce(t *testing.T) {
+	v1 := &flakyKey{key: []byte("key0key1key2key3")}
+	v2 := &flakyKey{key: []byte("key4key5key6key7")}
+	c := NewVersionedKeyCipher(map[uint16]Key{1: v1, 2: v2}, 2)
+	old, err := NewVersionedKeyCipher(map[uint16]Key{1: v1}, 1).Encrypt("old plaintext")
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+	v1.calls = 0
+
+	for i := 0; i < 5; i++ {
+		ciphertext, err := c.Encrypt("plaintext")
+		if err != nil {
+			t.Fatalf("Encrypt #%d error: %v", i, err)
+		}
+		if _, err := c.Decrypt(ciphertext); err != nil {
+			t.Fatalf("Decrypt #%d error: %v", i, err)
+		}
+		if _, err := c.Decrypt(old); err != nil {
+			t.Fatalf("Decrypt(old) #%d error: %v", i, err)
+		}
+	}
+
+	// one key derivation per version, not per call
+	if v1.calls != 1 || v2.calls != 1 {
+		t.Errorf("Bytes() calls = %d, %d, want 1, 1", v1.calls, v2.calls)
+	}
+}
@@ -0,0 +1,120 @@
+package simplecipher
+
+import (
+	"crypto/cipher"
+	"crypto/des"
+	"golang.org/x/crypto/blowfish"
+	"golang.org/x/crypto/twofish"
+)
+
+// This file implements non-AES block ciphers for interoperability with
+// legacy systems, in the CBC and CTR modes.
+//
+// Available block ciphers are:
+//
+//   - Blowfish: 8 bytes block, 1 to 56 bytes key.
+//   - Twofish: 16 bytes block, 16, 24, or 32 bytes key.
+//   - 3DES (TripleDES, EDE3): 8 bytes block, 24 bytes key.
+//
+// Do NOT use them for new data: the 64-bit blocks of Blowfish and 3DES
+// are vulnerable to birthday attacks (Sweet32) on large amounts of data.
+// Use AES instead.
+//
+// See also:
+//  - https://pkg.go.dev/golang.org/x/crypto/blowfish
+//  - https://pkg.go.dev/golang.org/x/crypto/twofish
+//  - https://pkg.go.dev/crypto/des
+
+// BlowfishBlockSize is the block size of Blowfish in bytes,
+// and therefore the size of its iv.
+const BlowfishBlockSize = blowfish.BlockSize
+
+// TripleDESBlockSize is the block size of 3DES in bytes,
+// and therefore the size of its iv.
+const TripleDESBlockSize = des.BlockSize
+
+func newBlowfishBlock(key []byte) (cipher.Block, error) {
+	return blowfish.NewCipher(key)
+}
+
+func newTwofishBlock(key []byte) (cipher.Block, error) {
+	return twofish.NewCipher(key)
+}
+
+func newTripleDESBlock(key []byte) (cipher.Block, error) {
+	return des.NewTripleDESCipher(key)
+}
+
+// NewBlowfishCBC creates a new Blowfish-CBC cipher with the given key and iv.
+//
+// It works like [NewCBC], but with Blowfish instead of AES:
+//
+//   - The key must be 1 to 56 bytes long.
+//   - The IV must be [BlowfishBlockSize] (8) bytes long.
+//   - The plaintext must be padded to a multiple of [BlowfishBlockSize] bytes.
+//
+// The iv will be prepended to the ciphertext during encryption,
+// and the first block of the ciphertext will be treated as the IV during decryption.
+func NewBlowfishCBC(key, iv Key) Cipher {
+	return &cbc{key: keyOrEmpty(key), iv: iv, newBlock: newBlowfishBlock, algorithm: algorithmBlowfish}
+}
+
+// NewBlowfishCTR creates a new Blowfish-CTR cipher with the given key and iv.
+//
+// The key must be 1 to 56 bytes long.
+// The iv must be [BlowfishBlockSize] (8) bytes long.
+//
+// The iv will be prepended to the ciphertext during encryption,
+// and the first block of the ciphertext will be treated as the IV during decryption.
+func NewBlowfishCTR(key, iv Key) Cipher {
+	return BlockFromStream(NewBlowfishCTRStream(key, iv))
+}
+
+// NewBlowfishCTRStream creates a new Blowfish-CTR stream cipher with the given key and iv.
+//
+// The key must be 1 to 56 bytes long.
+// The iv must be [BlowfishBlockSize] (8) bytes long.
+func NewBlowfishCTRStream(key, iv Key) Stream {
+	return &steam{key: keyOrEmpty(key), iv: iv, cipherStream: ctrStreamBuilder, mode: ModeCTR, newBlock: newBlowfishBlock, algorithm: algorithmBlowfish}
+}
+
+// NewTwofishCBC creates a new Twofish-CBC cipher with the given key and iv.
+//
+// It works like [NewCBC], but with Twofish instead of AES.
+// The key must be 16, 24, or 32 bytes long, and the iv 16 bytes long.
+func NewTwofishCBC(key, iv Key) Cipher {
+	return &cbc{key: keyOrEmpty(key), iv: iv, newBlock: newTwofishBlock, algorithm: algorithmTwofish}
+}
+
+// NewTwofishCTR creates a new Twofish-CTR cipher with the given key and iv.
+//
+// The key must be 16, 24, or 32 bytes long, and the iv 16 bytes long.
+//
+// The iv will be prepended to the ciphertext during encryption,
+// and the first block of the ciphertext will be treated as the IV during decryption.
+func NewTwofishCTR(key, iv Key) Cipher {
+	return BlockFromStream(NewTwofishCTRStream(key, iv))
+}
+
+// NewTwofishCTRStream creates a new Twofish-CTR stream cipher with the given key and iv.
+//
+// The key must be 16, 24, or 32 bytes long, and the iv 16 bytes long.
+func NewTwofishCTRStream(key, iv Key) Stream {
+	return &steam{key: keyOrEmpty(key), iv: iv, cipherStream: ctrStreamBuilder, mode: ModeCTR, newBlock: newTwofishBlock, algorithm: algorithmTwofish}
+}
+
+// NewTripleDESCBC creates a new 3DES-CBC (TripleDES EDE3) cipher with the
+// given key and iv, for interoperability with legacy (e.g., banking) systems.
+//
+// It works like [NewCBC], but with 3DES instead of AES:
+//
+//   - The key must be 24 bytes long (three 8 bytes DES keys).
+//   - The IV must be [TripleDESBlockSize] (8) bytes long.
+//   - The plaintext must be padded to a multiple of [TripleDESBlockSize] bytes.
+//
+// Deprecated status: NIST has deprecated 3DES (SP 800-131A Rev. 2) and
+// disallows it for encryption after 2023. Only use it to decrypt or
+// exchange data with systems that require it.
+func NewTripleDESCBC(key, iv Key) Cipher {
+	return &cbc{key: keyOrEmpty(key), iv: iv, newBlock: newTripleDESBlock, algorithm: algorithmTripleDES}
+}
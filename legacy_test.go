@@ -0,0 +1,109 @@
+package simplecipher
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewBlowfishCBC_vector(t *testing.T) {
+	// CBC test vector of Eric Young's Blowfish implementation (bf_cbc).
+	key, _ := hex.DecodeString("0123456789ABCDEFF0E1D2C3B4A59687")
+	iv, _ := hex.DecodeString("FEDCBA9876543210")
+	plaintext, _ := hex.DecodeString("37363534333231204E6F77206973207468652074696D6520666F722000000000")
+	want := "FEDCBA9876543210" + "6B77B4D63006DEE605B156E27403979358DEB9E7154616D959F1652BD5FF92CC"
+
+	c := NewBlowfishCBC(Bytes(key), Bytes(iv))
+
+	ciphertext, err := c.Encrypt(string(plaintext))
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+	if ciphertext != strings.ToLower(want) {
+		t.Errorf("Encrypt() = %s, want %s", ciphertext, strings.ToLower(want))
+	}
+
+	decrypted, err := c.Decrypt(ciphertext)
+	if err != nil {
+		t.Fatalf("Decrypt error: %v", err)
+	}
+	if decrypted != string(plaintext) {
+		t.Errorf("Decrypt() = %x, want %x", decrypted, plaintext)
+	}
+}
+
+func TestNewTripleDESCBC_vector(t *testing.T) {
+	// Known-answer vector of NIST SP 800-67 Rev. 2 (TDEA example),
+	// CBC with a zero iv on a single block is the same as ECB.
+	key, _ := hex.DecodeString("0123456789ABCDEF" + "23456789ABCDEF01" + "456789ABCDEF0123")
+	iv := make([]byte, TripleDESBlockSize)
+	plaintext := "The qufc"
+	want := "0000000000000000" + "a826fd8ce53b855f"
+
+	c := NewTripleDESCBC(Bytes(key), Bytes(iv))
+
+	ciphertext, err := c.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+	if ciphertext != want {
+		t.Errorf("Encrypt() = %s, want %s", ciphertext, want)
+	}
+
+	decrypted, err := c.Decrypt(ciphertext)
+	if err != nil {
+		t.Fatalf("Decrypt error: %v", err)
+	}
+	if decrypted != plaintext {
+		t.Errorf("Decrypt() = %s, want %s", decrypted, plaintext)
+	}
+}
+
+func TestLegacyCiphers(t *testing.T) {
+	blowfishKey := String("legacy-blowfish-key")
+	blowfishIv := String("iv00iv01")
+	twofishKey := String("key0key1key2key3key4key5key6key7")
+	twofishIv := String("iv00iv01iv02iv03")
+	tripleDESKey := String("key0key1key2key3key4key5")
+
+	ciphers := map[string]func() Cipher{
+		"NewBlowfishCBC":       func() Cipher { return NewBlowfishCBC(blowfishKey, blowfishIv) },
+		"NewBlowfishCTR":       func() Cipher { return NewBlowfishCTR(blowfishKey, blowfishIv) },
+		"NewBlowfishCTRStream": func() Cipher { return BlockFromStream(NewBlowfishCTRStream(blowfishKey, blowfishIv)) },
+		"NewTwofishCBC":        func() Cipher { return NewTwofishCBC(twofishKey, twofishIv) },
+		"NewTwofishCTR":        func() Cipher { return NewTwofishCTR(twofishKey, twofishIv) },
+		"NewTwofishCTRStream":  func() Cipher { return BlockFromStream(NewTwofishCTRStream(twofishKey, twofishIv)) },
+		"NewTripleDESCBC":      func() Cipher { return NewTripleDESCBC(tripleDESKey, blowfishIv) },
+	}
+
+	for name, createCipher := range ciphers {
+		// a multiple of both 8 and 16 bytes for CBC
+		testCipher(name, t, createCipher, "plain-text-plain-text000plain-te")
+
+		if err := ValidateCipher(createCipher()); err != nil {
+			t.Errorf("%s: Validate() error = %v, want nil", name, err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		cipher Cipher
+		want   error
+	}{
+		{"blowfish_badKey", NewBlowfishCBC(String(""), blowfishIv), ErrKeySize},
+		{"blowfish_longKey", NewBlowfishCBC(Bytes(make([]byte, 57)), blowfishIv), ErrKeySize},
+		{"blowfish_aesIv", NewBlowfishCBC(blowfishKey, twofishIv), ErrIvSize},
+		{"twofish_badKey", NewTwofishCBC(blowfishKey, twofishIv), ErrKeySize},
+		{"twofish_badIv", NewTwofishCBC(twofishKey, blowfishIv), ErrIvSize},
+		{"tripleDES_badKey", NewTripleDESCBC(twofishKey, blowfishIv), ErrKeySize},
+		{"tripleDES_badIv", NewTripleDESCBC(tripleDESKey, twofishIv), ErrIvSize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateCipher(tt.cipher); !errors.Is(err, tt.want) {
+				t.Errorf("Validate() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package simplecipher
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// This file implements a Cipher decorator that pads the plaintext to hide
+// its length.
+
+// lengthPrefixSize is the size of the big-endian plaintext length
+// prepended by [WithLengthHiding].
+const lengthPrefixSize = 4
+
+// lengthHidingCipher is a [Cipher] decorator that pads the length-prefixed
+// plaintext to a multiple of the bucket size before passing it to the inner
+// Cipher.
+type lengthHidingCipher struct {
+	inner      Cipher
+	bucketSize int
+}
+
+var _ ModeCipher = (*lengthHidingCipher)(nil)
+
+// WithLengthHiding wraps the inner [Cipher] to pad the plaintext up to the
+// next multiple of bucketSize bytes before encryption, and strip the padding
+// after decryption. So the ciphertext length only reveals the bucket of the
+// plaintext length, not the exact length:
+//
+//	c := simplecipher.WithLengthHiding(simplecipher.SimpleGCM("key", "nonce"), 256)
+//	c.Encrypt("yes") // same length as c.Encrypt("no")
+//
+// The plaintext is prefixed with its length (4 bytes) and padded with zeros,
+// so even an empty plaintext takes a whole bucket. A bucketSize <= 0 fails
+// with an error wrapping [ErrInvalidConfig].
+//
+// Larger buckets hide more, at the cost of larger ciphertexts.
+// Notice that the number of buckets of a long plaintext is still revealed.
+func WithLengthHiding(inner Cipher, bucketSize int) Cipher {
+	return &lengthHidingCipher{inner: inner, bucketSize: bucketSize}
+}
+
+// Describe describes the inner Cipher.
+func (c *lengthHidingCipher) Describe() CipherInfo {
+	return DescribeCipher(c.inner)
+}
+
+// Mode returns the mode of the inner Cipher, or "" if unknown.
+func (c *lengthHidingCipher) Mode() ModeID {
+	return modeOf(c.inner)
+}
+
+// Encrypt pads the plaintext and encrypts it with the inner Cipher.
+func (c *lengthHidingCipher) Encrypt(plainText string) (cipherText string, err error) {
+	defer wrapOpError(&err, encrypt, modeOf(c.inner))
+	defer recoverFromPanic(&err)
+
+	if c.bucketSize <= 0 {
+		return "", fmt.Errorf("%w: bucket size %d", ErrInvalidConfig, c.bucketSize)
+	}
+	if uint64(c.bucketSize) > math.MaxUint32 || uint64(len(plainText)) > math.MaxUint32-uint64(c.bucketSize) {
+		return "", fmt.Errorf("%w: %d bytes", ErrPlaintextTooLarge, len(plainText))
+	}
+
+	n := lengthPrefixSize + len(plainText)
+	padded := make([]byte, lengthPrefixSize, n+(c.bucketSize-n%c.bucketSize)%c.bucketSize)
+	binary.BigEndian.PutUint32(padded, uint32(len(plainText)))
+	padded = append(padded, plainText...)
+	padded = padded[:cap(padded)] // the zero padding
+
+	return c.inner.Encrypt(string(padded))
+}
+
+// Decrypt decrypts the ciphertext with the inner Cipher and strips the padding.
+func (c *lengthHidingCipher) Decrypt(cipherText string) (plainText string, err error) {
+	defer wrapOpError(&err, decrypt, modeOf(c.inner))
+	defer recoverFromPanic(&err)
+
+	padded, err := c.inner.Decrypt(cipherText)
+	if err != nil {
+		return "", err
+	}
+
+	if len(padded) < lengthPrefixSize {
+		return "", fmt.Errorf("%w: missing the length prefix", ErrMalformedCiphertext)
+	}
+	n := binary.BigEndian.Uint32([]byte(padded[:lengthPrefixSize]))
+	if uint64(n) > uint64(len(padded)-lengthPrefixSize) {
+		return "", fmt.Errorf("%w: length %d out of %d bytes", ErrMalformedCiphertext, n, len(padded)-lengthPrefixSize)
+	}
+
+	return padded[lengthPrefixSize : lengthPrefixSize+int(n)], nil
+}
@@ -0,0 +1,61 @@
+package simplecipher
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWithLengthHiding(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	plaintexts := map[string]string{
+		"empty":       "",
+		"short":       "plaintext",
+		"bucket":      strings.Repeat("a", 64-lengthPrefixSize),
+		"multiBucket": strings.Repeat("plaintext", 100),
+	}
+
+	for name, plaintext := range plaintexts {
+		createCipher := func() Cipher {
+			return WithLengthHiding(SimpleGCM("key", "nonce"), 64)
+		}
+
+		testCipher(name, t, createCipher, plaintext)
+	}
+
+	c := WithLengthHiding(SimpleGCM("key", "nonce"), 64)
+
+	lengths := map[int]bool{}
+	for _, plaintext := range []string{"", "no", "yes", strings.Repeat("a", 60)} {
+		ciphertext, err := c.Encrypt(plaintext)
+		if err != nil {
+			t.Fatalf("Encrypt error: %v", err)
+		}
+		lengths[len(ciphertext)] = true
+	}
+	if len(lengths) != 1 {
+		t.Errorf("ciphertext lengths in the same bucket = %v, want one length", lengths)
+	}
+
+	ciphertext, err := c.Encrypt(strings.Repeat("a", 61))
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+	if lengths[len(ciphertext)] {
+		t.Errorf("ciphertext length of the next bucket = %v, want another length", len(ciphertext))
+	}
+
+	if _, err := WithLengthHiding(SimpleGCM("key", "nonce"), 0).Encrypt("plaintext"); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Encrypt(bucketSize=0) error = %v, want %v", err, ErrInvalidConfig)
+	}
+
+	// a ciphertext without the length prefix
+	unpadded, err := SimpleGCM("key", "nonce").Encrypt("\xff\xff\xff\xffplaintext")
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+	if _, err := c.Decrypt(unpadded); !errors.Is(err, ErrMalformedCiphertext) {
+		t.Errorf("Decrypt(unpadded) error = %v, want %v", err, ErrMalformedCiphertext)
+	}
+}
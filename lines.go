@@ -0,0 +1,80 @@
+package simplecipher
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// This file provides helpers to encrypt and decrypt texts line by line,
+// e.g., log files, where each line must be decryptable alone.
+
+// EncryptLines reads the plaintext from r line by line, encrypts each line
+// (without the newline) with the cipher, and writes the ciphertext, which
+// is a [DefaultStringCodec] string, followed by a newline to w:
+//
+//	err := simplecipher.EncryptLines(cipher, logFile, encryptedLogFile)
+//
+// Each output line is decrypted alone with the Decrypt method of the
+// cipher, or all of them with [DecryptLines]. Blank lines are encrypted as
+// any other line. The last line is written without a newline if it has
+// none in r, so that DecryptLines restores r exactly.
+//
+// Notice that the lengths of the lines are not hidden
+// (see [WithLengthHiding]).
+func EncryptLines(c Cipher, r io.Reader, w io.Writer) error {
+	return transformLines(r, w, c.Encrypt)
+}
+
+// DecryptLines reads the lines written by [EncryptLines] from r, decrypts
+// each of them with the cipher, and writes the plaintext lines to w.
+//
+// The plaintext of the lines before a failed one has been written then,
+// and the error names the line (from 1).
+func DecryptLines(c Cipher, r io.Reader, w io.Writer) error {
+	return transformLines(r, w, c.Decrypt)
+}
+
+// transformLines applies the transform to each line of r, and writes the
+// results to w, keeping the newlines.
+func transformLines(r io.Reader, w io.Writer, transform func(string) (string, error)) error {
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+
+	for n := 1; ; n++ {
+		line, err := br.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("%w: line %d: %w", ErrCopy, n, err)
+		}
+		if line == "" { // EOF
+			break
+		}
+
+		text, newline := strings.CutSuffix(line, "\n")
+
+		out, err := transform(text)
+		if err != nil {
+			return errors.Join(fmt.Errorf("line %d: %w", n, err), flushLines(bw))
+		}
+		if _, err := bw.WriteString(out); err != nil {
+			return fmt.Errorf("%w: %w", ErrCopy, err)
+		}
+		if newline {
+			if err := bw.WriteByte('\n'); err != nil {
+				return fmt.Errorf("%w: %w", ErrCopy, err)
+			}
+		}
+	}
+
+	return flushLines(bw)
+}
+
+// flushLines flushes the lines buffered in bw.
+func flushLines(bw *bufio.Writer) error {
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("%w: %w", ErrCopy, err)
+	}
+	return nil
+}
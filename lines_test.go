@@ -0,0 +1,70 @@
+package simplecipher
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEncryptLines(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	c := SimpleCTR("key")
+
+	inputs := map[string]string{
+		"empty":             "",
+		"oneLine":           "hello\n",
+		"noTrailingNewline": "hello\nworld",
+		"blankLines":        "\n\nhello\n\nworld\n\n",
+		"onlyNewline":       "\n",
+	}
+
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			encrypted := new(bytes.Buffer)
+			if err := EncryptLines(c, strings.NewReader(input), encrypted); err != nil {
+				t.Fatalf("EncryptLines error: %v", err)
+			}
+
+			if got, want := strings.Count(encrypted.String(), "\n"), strings.Count(input, "\n"); got != want {
+				t.Errorf("encrypted lines = %d newlines, want %d", got, want)
+			}
+
+			// each line is decryptable alone
+			lines := strings.Split(input, "\n")
+			for i, line := range strings.Split(encrypted.String(), "\n") {
+				if line == "" && i == len(lines)-1 {
+					continue // after the trailing newline
+				}
+				plaintext, err := c.Decrypt(line)
+				if err != nil || plaintext != lines[i] {
+					t.Errorf("Decrypt(line %d) = %q, %v, want %q", i+1, plaintext, err, lines[i])
+				}
+			}
+
+			decrypted := new(bytes.Buffer)
+			if err := DecryptLines(c, encrypted, decrypted); err != nil {
+				t.Fatalf("DecryptLines error: %v", err)
+			}
+			if decrypted.String() != input {
+				t.Errorf("DecryptLines() = %q, want %q", decrypted.String(), input)
+			}
+		})
+	}
+
+	encrypted := new(bytes.Buffer)
+	if err := EncryptLines(c, strings.NewReader("first\nsecond\n"), encrypted); err != nil {
+		t.Fatalf("EncryptLines error: %v", err)
+	}
+	tampered := strings.Replace(encrypted.String(), "\n", "\nzz", 1)
+
+	decrypted := new(bytes.Buffer)
+	err := DecryptLines(c, strings.NewReader(tampered), decrypted)
+	if !errors.Is(err, ErrMalformedCiphertext) || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("DecryptLines(tampered) error = %v, want %v at line 2", err, ErrMalformedCiphertext)
+	}
+	if decrypted.String() != "first\n" {
+		t.Errorf("DecryptLines(tampered) wrote %q, want the lines before the error", decrypted.String())
+	}
+}
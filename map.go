@@ -0,0 +1,91 @@
+package simplecipher
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// This file provides helpers to encrypt and decrypt the values of string
+// maps, e.g., secrets in config maps.
+
+// MapOption is a functional option of [EncryptMap] and [DecryptMap].
+type MapOption func(opts *mapOptions)
+
+// mapOptions are the options of [EncryptMap] and [DecryptMap].
+type mapOptions struct {
+	keys bool
+}
+
+// WithMapKeys encrypts (or decrypts) the keys of the map as well as the
+// values.
+//
+// Use a deterministic cipher (e.g., [NewGCM] with a fixed nonce, or
+// [NewGCMSynthNonce]) to look up an entry by its encrypted key.
+func WithMapKeys() MapOption {
+	return func(opts *mapOptions) {
+		opts.keys = true
+	}
+}
+
+// EncryptMap encrypts the values of the map with the cipher, and returns
+// them in a new map with the same keys. The input map is not modified.
+//
+//	secrets, err := simplecipher.EncryptMap(cipher, map[string]string{
+//		"db_password": "hunter2",
+//		"api_token":   "s3cr3t",
+//	})
+//
+// All the entries are processed even if some of them fail: the errors are
+// aggregated with [errors.Join], each naming the key of the entry, and no
+// map is returned.
+func EncryptMap(c Cipher, m map[string]string, options ...MapOption) (out map[string]string, err error) {
+	defer wrapOpError(&err, encrypt, modeOf(c))
+
+	return transformMap(m, c.Encrypt, options)
+}
+
+// DecryptMap decrypts the values of the map with the cipher.
+// It reverses [EncryptMap] with the same options.
+func DecryptMap(c Cipher, m map[string]string, options ...MapOption) (out map[string]string, err error) {
+	defer wrapOpError(&err, decrypt, modeOf(c))
+
+	return transformMap(m, c.Decrypt, options)
+}
+
+// transformMap applies the transform to the values (and the keys with
+// [WithMapKeys]) of the map.
+func transformMap(m map[string]string, transform func(string) (string, error), options []MapOption) (map[string]string, error) {
+	var opts mapOptions
+	for _, option := range options {
+		option(&opts)
+	}
+
+	out := make(map[string]string, len(m))
+	var errs []error
+
+	// sorted, so that the errors are in a stable order
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		value, err := transform(m[k])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("value of %q: %w", k, stripOpError(err)))
+			continue
+		}
+
+		key := k
+		if opts.keys {
+			if key, err = transform(k); err != nil {
+				errs = append(errs, fmt.Errorf("key %q: %w", k, stripOpError(err)))
+				continue
+			}
+		}
+
+		out[key] = value
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return out, nil
+}
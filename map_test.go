@@ -0,0 +1,85 @@
+package simplecipher
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncryptMap(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	m := map[string]string{
+		"db_password": "hunter2",
+		"api_token":   "s3cr3t",
+		"empty":       "",
+	}
+	want := map[string]string{
+		"db_password": "hunter2",
+		"api_token":   "s3cr3t",
+		"empty":       "",
+	}
+
+	t.Run("values", func(t *testing.T) {
+		cipher := NewGCMWithConfig(GCMConfig{Key: NewAesKey("key"), RandomNonce: true})
+
+		encrypted, err := EncryptMap(cipher, m)
+		if err != nil {
+			t.Fatalf("EncryptMap() error = %v", err)
+		}
+		if !reflect.DeepEqual(m, want) {
+			t.Errorf("EncryptMap() modified the input: %v", m)
+		}
+		for k, v := range encrypted {
+			if _, ok := m[k]; !ok || v == m[k] {
+				t.Errorf("EncryptMap()[%q] = %q, want an encrypted value of an input key", k, v)
+			}
+		}
+
+		decrypted, err := DecryptMap(cipher, encrypted)
+		if err != nil || !reflect.DeepEqual(decrypted, want) {
+			t.Errorf("DecryptMap() = %v, %v, want %v", decrypted, err, want)
+		}
+	})
+
+	t.Run("keys", func(t *testing.T) {
+		cipher := NewGCMSynthNonce(NewAesKey("key"))
+
+		encrypted, err := EncryptMap(cipher, m, WithMapKeys())
+		if err != nil {
+			t.Fatalf("EncryptMap() error = %v", err)
+		}
+		if len(encrypted) != len(m) {
+			t.Errorf("EncryptMap() has %d entries, want %d", len(encrypted), len(m))
+		}
+		encryptedKey, _ := cipher.Encrypt("db_password")
+		if _, ok := encrypted[encryptedKey]; !ok {
+			t.Errorf("EncryptMap() has no entry for the encrypted key %q", encryptedKey)
+		}
+
+		decrypted, err := DecryptMap(cipher, encrypted, WithMapKeys())
+		if err != nil || !reflect.DeepEqual(decrypted, want) {
+			t.Errorf("DecryptMap() = %v, %v, want %v", decrypted, err, want)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		cipher := NewGCM(NewAesKey("key"), NewNonce("nonce"))
+
+		encrypted, _ := EncryptMap(cipher, m)
+		encrypted["api_token"] = "not hex"
+		encrypted["db_password"] = encrypted["db_password"][:len(encrypted["db_password"])-2]
+
+		decrypted, err := DecryptMap(cipher, encrypted)
+		if decrypted != nil {
+			t.Errorf("DecryptMap() = %v, want nil on error", decrypted)
+		}
+		if !errors.Is(err, ErrMalformedCiphertext) || !errors.Is(err, ErrAuthenticationFailed) {
+			t.Errorf("DecryptMap() error = %v, want both %v and %v", err, ErrMalformedCiphertext, ErrAuthenticationFailed)
+		}
+		if msg := err.Error(); !strings.Contains(msg, `"api_token"`) || !strings.Contains(msg, `"db_password"`) || strings.Contains(msg, `"empty"`) {
+			t.Errorf("DecryptMap() error = %q, want the failed keys only", msg)
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package simplecipher
+
+import "crypto/aes"
+
+// This file provides identifiers of the cipher modes implemented in this
+// package, and helpers working with them.
+
+// ModeID identifies a cipher mode of operation.
+type ModeID string
+
+// Available [ModeID] values.
+const (
+	ModeCBC ModeID = "CBC"
+	ModeCFB ModeID = "CFB"
+	ModeOFB ModeID = "OFB"
+	ModeCTR ModeID = "CTR"
+	ModeGCM ModeID = "GCM"
+	ModeFF1 ModeID = "FF1"
+	ModeOCB ModeID = "OCB"
+	ModeEAX ModeID = "EAX"
+)
+
+// ModeCipher is a [Cipher] that knows its cipher mode.
+//
+// All the [Cipher] implementations in this package implement ModeCipher.
+// The decorators (e.g., [WithCompression]) return the mode of their inner
+// Cipher, or "" if it does not know its mode.
+type ModeCipher interface {
+	Cipher
+	// Mode returns the cipher mode of operation.
+	Mode() ModeID
+}
+
+var (
+	_ ModeCipher = (*cbc)(nil)
+	_ ModeCipher = (*simpleCBC)(nil)
+	_ ModeCipher = (*gcm)(nil)
+	_ ModeCipher = (*streamToBlock)(nil)
+	_ ModeCipher = (*keyring)(nil)
+	_ ModeCipher = (*ff1)(nil)
+	_ ModeCipher = (*aeadCipher)(nil)
+)
+
+// modeOf returns the mode of the [Cipher] or [Stream] if it reports it
+// (e.g., a [ModeCipher]), or "" otherwise.
+func modeOf(c any) ModeID {
+	if m, ok := c.(interface{ Mode() ModeID }); ok {
+		return m.Mode()
+	}
+	return ""
+}
+
+// gcmTagSize is the size of the authentication tag appended by GCM.
+const gcmTagSize = 16
+
+// GuessMode returns the cipher modes that could plausibly have produced
+// the given ciphertext ([DefaultStringCodec] encoded) of this package.
+//
+// It is a best-effort heuristic for debugging, based only on the length of
+// the decoded ciphertext:
+//
+//   - CFB, OFB and CTR prepend an [aes.BlockSize] bytes IV to the ciphertext,
+//     which has the same length as the plaintext.
+//   - CBC prepends the IV too, and the ciphertext is a multiple of [aes.BlockSize].
+//   - GCM, OCB and EAX append a 16 bytes authentication tag to the ciphertext.
+//
+// The result is NOT authoritative: a ciphertext usually fits multiple modes,
+// and there is no way to tell the stream modes apart by length.
+// nil is returned if the ciphertext cannot be decoded or fits no mode.
+func GuessMode(cipherText string) []ModeID {
+	ciphertext, err := decodeCipherText(cipherText)
+	if err != nil {
+		return nil
+	}
+
+	n := len(ciphertext)
+
+	var modes []ModeID
+
+	if n >= aes.BlockSize && n%aes.BlockSize == 0 {
+		modes = append(modes, ModeCBC)
+	}
+	if n >= aes.BlockSize {
+		modes = append(modes, ModeCFB, ModeOFB, ModeCTR)
+	}
+	if n >= gcmTagSize {
+		modes = append(modes, ModeGCM, ModeOCB, ModeEAX)
+	}
+
+	return modes
+}
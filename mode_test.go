@@ -0,0 +1,96 @@
+package simplecipher
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGuessMode(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	tests := []struct {
+		name      string
+		cipher    Cipher
+		plaintext string
+		want      ModeID
+	}{
+		{
+			name:      "cbc",
+			cipher:    SimpleCBC("key"),
+			plaintext: "plaintext",
+			want:      ModeCBC,
+		},
+		{
+			name:      "ctr",
+			cipher:    SimpleCTR("key"),
+			plaintext: "plaintext",
+			want:      ModeCTR,
+		},
+		{
+			name:      "gcm",
+			cipher:    SimpleGCM("key", "nonce"),
+			plaintext: "plaintext",
+			want:      ModeGCM,
+		},
+		{
+			name:      "gcm_empty",
+			cipher:    SimpleGCM("key", "nonce"),
+			plaintext: "",
+			want:      ModeGCM,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ciphertext, err := tt.cipher.Encrypt(tt.plaintext)
+			if err != nil {
+				t.Fatalf("Encrypt error: %v", err)
+			}
+
+			got := GuessMode(ciphertext)
+			if !slices.Contains(got, tt.want) {
+				t.Errorf("GuessMode() = %v, want to contain %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := GuessMode("abcd"); got != nil {
+		t.Errorf("GuessMode(too short) = %v, want nil", got)
+	}
+	if got := GuessMode("not hex"); got != nil {
+		t.Errorf("GuessMode(malformed) = %v, want nil", got)
+	}
+}
+
+func TestModeCipher_decorators(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	gcm := SimpleGCM("key", "nonce")
+	decorators := map[string]Cipher{
+		"WithCompression":      WithCompression(gcm, 0),
+		"WithMaxCiphertextLen": WithMaxCiphertextLen(gcm, 100),
+		"WithUTF8Validation":   WithUTF8Validation(gcm),
+		"WithLengthHiding":     WithLengthHiding(gcm, 16),
+		"WithRandomPrefix":     WithRandomPrefix(gcm, 8),
+		"WithDecryptRateLimit": WithDecryptRateLimit(gcm, 1),
+		"nested":               WithUTF8Validation(WithCompression(SimpleCBC("key"), 0)),
+	}
+	for name, c := range decorators {
+		t.Run(name, func(t *testing.T) {
+			m, ok := c.(ModeCipher)
+			if !ok {
+				t.Fatalf("%T is not a ModeCipher", c)
+			}
+			want := ModeGCM
+			if name == "nested" {
+				want = ModeCBC
+			}
+			if got := m.Mode(); got != want {
+				t.Errorf("Mode() = %q, want %q", got, want)
+			}
+		})
+	}
+
+	if got := WithUTF8Validation(errCipher{}).(ModeCipher).Mode(); got != "" {
+		t.Errorf("Mode() of an unknown inner Cipher = %q, want \"\"", got)
+	}
+}
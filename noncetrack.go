@@ -0,0 +1,97 @@
+package simplecipher
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// This file implements a debugging aid detecting the reuse of GCM nonces
+// within a process.
+
+// nonceTrackerSize is the maximum number of (key, nonce) pairs remembered
+// by the nonce reuse detection. The least recently used ones are evicted.
+const nonceTrackerSize = 1 << 16
+
+// nonceReuseDetection enables the nonce reuse detection.
+var nonceReuseDetection atomic.Bool
+
+// EnableNonceReuseDetection enables (or disables) the detection of reused
+// nonces for debugging and testing.
+//
+// When enabled, the GCM ciphers created by [NewGCM], [NewGCMWithNonceSize],
+// [NewGCMWithConfig] and [SimpleGCM] record the (key, nonce) pairs they
+// encrypt with, and the encryption with a pair that was used before fails
+// with an error wrapping [ErrNonceReused], instead of silently breaking
+// the security of GCM. E.g., a fixed nonce reused for another message:
+//
+//	simplecipher.EnableNonceReuseDetection(true)
+//	c := simplecipher.NewGCM(key, simplecipher.NewNonce("fixed"))
+//	c.Encrypt("a") // ok
+//	c.Encrypt("b") // ErrNonceReused
+//
+// Only the last 65536 pairs are remembered, in a LRU list keyed by the
+// SHA-256 of the key, so a reuse far apart is not detected. It is a
+// process-wide setting and costs a global lock on every encryption:
+// do not enable it in production. Disabling it clears the records.
+func EnableNonceReuseDetection(enable bool) {
+	nonceReuseDetection.Store(enable)
+	if !enable {
+		defaultNonceTracker.reset()
+	}
+}
+
+// nonceTracker is a bounded LRU set of the (key, nonce) pairs used.
+type nonceTracker struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // of string, the most recently used at the front
+	seen  map[string]*list.Element
+}
+
+// defaultNonceTracker records the pairs used when the detection is enabled.
+var defaultNonceTracker = newNonceTracker(nonceTrackerSize)
+
+func newNonceTracker(size int) *nonceTracker {
+	return &nonceTracker{size: size, order: list.New(), seen: make(map[string]*list.Element)}
+}
+
+// track records the (key, nonce) pair, or returns an error wrapping
+// [ErrNonceReused] if it has been recorded already.
+func (t *nonceTracker) track(keyID [32]byte, nonce []byte) error {
+	pair := string(keyID[:]) + string(nonce)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if e, ok := t.seen[pair]; ok {
+		t.order.MoveToFront(e)
+		return fmt.Errorf("%w: nonce %x", ErrNonceReused, nonce)
+	}
+
+	t.seen[pair] = t.order.PushFront(pair)
+	if t.order.Len() > t.size {
+		oldest := t.order.Back()
+		t.order.Remove(oldest)
+		delete(t.seen, oldest.Value.(string))
+	}
+	return nil
+}
+
+// reset forgets all the pairs.
+func (t *nonceTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.order.Init()
+	clear(t.seen)
+}
+
+// trackNonce records the (key, nonce) pair if the detection is enabled.
+func trackNonce(keyID [32]byte, nonce []byte) error {
+	if !nonceReuseDetection.Load() {
+		return nil
+	}
+	return defaultNonceTracker.track(keyID, nonce)
+}
@@ -0,0 +1,74 @@
+package simplecipher
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEnableNonceReuseDetection(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	EnableNonceReuseDetection(true)
+	defer EnableNonceReuseDetection(false)
+
+	key := Bytes([]byte("key0key1key2key3"))
+
+	c := NewGCM(key, NewNonce("fixed"))
+	if _, err := c.Encrypt("first"); err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if _, err := c.Encrypt("second"); !errors.Is(err, ErrNonceReused) {
+		t.Errorf("Encrypt(reused nonce) error = %v, want %v", err, ErrNonceReused)
+	}
+	// another instance with the same key and nonce
+	if _, err := NewGCM(key, NewNonce("fixed")).(AppendCipher).EncryptAppend(nil, []byte("third")); !errors.Is(err, ErrNonceReused) {
+		t.Errorf("EncryptAppend(reused nonce) error = %v, want %v", err, ErrNonceReused)
+	}
+
+	// decryption is not affected
+	EnableNonceReuseDetection(false)
+	ciphertext, _ := c.Encrypt("plaintext")
+	EnableNonceReuseDetection(true)
+	if plaintext, err := c.Decrypt(ciphertext); err != nil || plaintext != "plaintext" {
+		t.Errorf("Decrypt() = %q, %v, want %q", plaintext, err, "plaintext")
+	}
+
+	// other keys, other nonces and random nonces are fine
+	if _, err := NewGCM(Bytes([]byte("key0key1key2key4")), NewNonce("fixed")).Encrypt("first"); err != nil {
+		t.Errorf("Encrypt(another key) error = %v", err)
+	}
+	if _, err := NewGCM(key, NewNonce("another")).Encrypt("first"); err != nil {
+		t.Errorf("Encrypt(another nonce) error = %v", err)
+	}
+	random := NewGCMWithConfig(GCMConfig{Key: key, RandomNonce: true})
+	for i := 0; i < 100; i++ {
+		if _, err := random.Encrypt("plaintext"); err != nil {
+			t.Fatalf("Encrypt(random nonce) error = %v", err)
+		}
+	}
+
+	// disabling clears the records
+	EnableNonceReuseDetection(false)
+	EnableNonceReuseDetection(true)
+	if _, err := NewGCM(key, NewNonce("fixed")).Encrypt("first"); err != nil {
+		t.Errorf("Encrypt() after reset error = %v", err)
+	}
+}
+
+func Test_nonceTracker_evict(t *testing.T) {
+	tracker := newNonceTracker(2)
+	var keyID [32]byte
+
+	for _, nonce := range []string{"a", "b", "c"} {
+		if err := tracker.track(keyID, []byte(nonce)); err != nil {
+			t.Fatalf("track(%s) error = %v", nonce, err)
+		}
+	}
+	// "a" is evicted, "c" is remembered
+	if err := tracker.track(keyID, []byte("a")); err != nil {
+		t.Errorf("track(evicted) error = %v, want nil", err)
+	}
+	if err := tracker.track(keyID, []byte("c")); !errors.Is(err, ErrNonceReused) {
+		t.Errorf("track(remembered) error = %v, want %v", err, ErrNonceReused)
+	}
+}
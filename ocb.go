@@ -0,0 +1,191 @@
+package simplecipher
+
+import (
+	"crypto/cipher"
+	"fmt"
+	"github.com/ProtonMail/go-crypto/ocb"
+)
+
+// This file implements the AES-OCB mode (RFC 7253), a single-pass AEAD
+// faster than GCM without hardware support for it, over the OCB
+// implementation of github.com/ProtonMail/go-crypto (used by OpenPGP).
+// OCB is free of patents since 2021.
+//
+// It also provides the fixed nonce [Cipher] shared by the AEAD modes
+// other than GCM.
+
+// aeadCipher is a [Cipher] over a [cipher.AEAD] of AES with a fixed nonce,
+// for the AEAD modes other than GCM.
+type aeadCipher struct {
+	key   Key
+	nonce Key
+	mode  ModeID
+	// minNonceSize and maxNonceSize bound the length of the nonce.
+	minNonceSize, maxNonceSize int
+	// newAEAD creates the AEAD of the mode from the AES block,
+	// for nonces of nonceSize bytes.
+	newAEAD func(block cipher.Block, nonceSize int) (cipher.AEAD, error)
+}
+
+var _ AADCipher = (*aeadCipher)(nil)
+
+// AADCipher is a [Cipher] that authenticates additional data given per
+// call (e.g., a record ID binding the ciphertext to its row), without
+// storing it in the ciphertext:
+//
+//	ac := simplecipher.NewOCB(key, nonce).(simplecipher.AADCipher)
+//	cipherText, err := ac.EncryptWithAAD([]byte(recordID), "plaintext")
+//	plainText, err := ac.DecryptWithAAD([]byte(recordID), cipherText)
+//
+// Decrypt fails with an error wrapping [ErrAuthenticationFailed] if the
+// additional data does not match. Encrypt and Decrypt use no additional
+// data. The ciphers created by [NewOCB] and [NewEAX] implement AADCipher.
+type AADCipher interface {
+	Cipher
+	// EncryptWithAAD encrypts the plaintext, authenticating the additional data.
+	EncryptWithAAD(additionalData []byte, plainText string) (cipherText string, err error)
+	// DecryptWithAAD decrypts the ciphertext, authenticating the additional data.
+	DecryptWithAAD(additionalData []byte, cipherText string) (plainText string, err error)
+}
+
+// ocbMaxNonceSize is the maximum nonce size of OCB, less than the block size.
+const ocbMaxNonceSize = 15
+
+// NewOCB creates a new AES-OCB cipher with the given key and nonce.
+//
+// The ciphertext is followed by a 16-byte tag, like GCM, and the
+// additional data is supported via [AADCipher].
+//
+// It's caller's responsibility to ensure the following:
+//
+//   - The key must be 16, 24, or 32 bytes long to select AES-128,
+//     AES-192, or AES-256.
+//   - The nonce must be 1 to 15 bytes long (12 recommended),
+//     otherwise an error wrapping [ErrNonceSize] is returned.
+//   - The nonce must never be reused with the same key for different
+//     plaintexts, which breaks OCB as it breaks GCM.
+//
+// See also: [NewGCM].
+func NewOCB(key, nonce Key) Cipher {
+	return &aeadCipher{
+		key:          keyOrEmpty(key),
+		nonce:        keyOrEmpty(nonce),
+		mode:         ModeOCB,
+		minNonceSize: 1,
+		maxNonceSize: ocbMaxNonceSize,
+		newAEAD: func(block cipher.Block, nonceSize int) (cipher.AEAD, error) {
+			return ocb.NewOCBWithNonceAndTagSize(block, nonceSize, aeadTagSize)
+		},
+	}
+}
+
+// aeadTagSize is the size of the tag of the AEAD modes other than GCM.
+const aeadTagSize = 16
+
+// Mode returns the AEAD mode of the cipher.
+func (c *aeadCipher) Mode() ModeID {
+	return c.mode
+}
+
+// Describe describes the cipher, e.g., AES-256-OCB.
+func (c *aeadCipher) Describe() CipherInfo {
+	return CipherInfo{Algorithm: algorithmAES, Mode: c.mode, KeyBits: keyBits(c.key), Codec: defaultCodecName()}
+}
+
+// Validate checks the lengths of the key and the nonce.
+func (c *aeadCipher) Validate() (err error) {
+	defer recoverFromPanic(&err)
+
+	if err := validateAesKey(c.key); err != nil {
+		return err
+	}
+	return c.validateNonce(c.nonce.Bytes())
+}
+
+// validateNonce checks the length of the nonce.
+func (c *aeadCipher) validateNonce(nonce []byte) error {
+	if n := len(nonce); n < c.minNonceSize || n > c.maxNonceSize {
+		return fmt.Errorf("%w: got %d bytes, want %d to %d", ErrNonceSize, n, c.minNonceSize, c.maxNonceSize)
+	}
+	return nil
+}
+
+// aead returns the AEAD and the nonce.
+func (c *aeadCipher) aead() (cipher.AEAD, []byte, error) {
+	nonce := c.nonce.Bytes()
+	if err := c.validateNonce(nonce); err != nil {
+		return nil, nil, err
+	}
+
+	block, err := newAesBlock(c.key.Bytes())
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrNewAesCipher, err)
+	}
+
+	aead, err := c.newAEAD(block, len(nonce))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return aead, nonce, nil
+}
+
+// Encrypt encrypts the given plaintext.
+// The ciphertext is returned with [DefaultStringCodec] encoding.
+func (c *aeadCipher) Encrypt(plainText string) (cipherText string, err error) {
+	return c.EncryptWithAAD(nil, plainText)
+}
+
+// Decrypt decrypts the given ciphertext.
+// The ciphertext must be a [DefaultStringCodec] string.
+//
+// If the ciphertext has been tampered with (or the key/nonce mismatch),
+// an error wrapping [ErrAuthenticationFailed] is returned.
+func (c *aeadCipher) Decrypt(cipherText string) (plainText string, err error) {
+	return c.DecryptWithAAD(nil, cipherText)
+}
+
+// EncryptWithAAD encrypts the given plaintext, authenticating the
+// additional data. The ciphertext is returned with [DefaultStringCodec]
+// encoding.
+func (c *aeadCipher) EncryptWithAAD(additionalData []byte, plainText string) (cipherText string, err error) {
+	defer wrapOpError(&err, encrypt, c.mode)
+	defer recoverFromPanic(&err)
+
+	aead, nonce, err := c.aead()
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext := aead.Seal(nil, nonce, []byte(plainText), additionalData)
+
+	return DefaultStringCodec.EncodeToString(ciphertext), nil
+}
+
+// DecryptWithAAD decrypts the given ciphertext, authenticating the
+// additional data. The ciphertext must be a [DefaultStringCodec] string.
+func (c *aeadCipher) DecryptWithAAD(additionalData []byte, cipherText string) (plainText string, err error) {
+	defer wrapOpError(&err, decrypt, c.mode)
+	defer recoverFromPanic(&err)
+
+	ciphertext, err := decodeCipherText(cipherText)
+	if err != nil {
+		return "", err
+	}
+
+	aead, nonce, err := c.aead()
+	if err != nil {
+		return "", err
+	}
+
+	if len(ciphertext) < aead.Overhead() {
+		return "", ErrCipherTextTooShort
+	}
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrAuthenticationFailed, err)
+	}
+
+	return string(plaintext), nil
+}
@@ -0,0 +1,106 @@
+package simplecipher
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewOCB(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	key := String("key0key1key2key3key4key5key6key7")
+
+	for _, plaintext := range []string{"", "plaintext", strings.Repeat("plaintext", 100)} {
+		testCipher("", t, func() Cipher { return NewOCB(key, String("nonce0nonce1")) }, plaintext)
+	}
+
+	for _, nonce := range []string{"", "nonce0nonce1nonce"} {
+		if _, err := NewOCB(key, String(nonce)).Encrypt("plaintext"); !errors.Is(err, ErrNonceSize) {
+			t.Errorf("Encrypt(%d bytes nonce) error = %v, want %v", len(nonce), err, ErrNonceSize)
+		}
+	}
+	if err := ValidateCipher(NewOCB(String("short key"), String("nonce"))); !errors.Is(err, ErrKeySize) {
+		t.Errorf("ValidateCipher(short key) error = %v, want %v", err, ErrKeySize)
+	}
+
+	c := NewOCB(key, String("nonce0nonce1")).(AADCipher)
+
+	ciphertext, err := c.EncryptWithAAD([]byte("record 1"), "plaintext")
+	if err != nil {
+		t.Fatalf("EncryptWithAAD error: %v", err)
+	}
+	if plaintext, err := c.DecryptWithAAD([]byte("record 1"), ciphertext); err != nil || plaintext != "plaintext" {
+		t.Errorf("DecryptWithAAD() = %q, %v, want %q", plaintext, err, "plaintext")
+	}
+	if _, err := c.DecryptWithAAD([]byte("record 2"), ciphertext); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("DecryptWithAAD(wrong AAD) error = %v, want %v", err, ErrAuthenticationFailed)
+	}
+
+	// tampered ciphertext or tag
+	raw, _ := DefaultStringCodec.DecodeString(ciphertext)
+	for _, i := range []int{0, len(raw) - 1} {
+		tampered := append([]byte(nil), raw...)
+		tampered[i] ^= 1
+		if _, err := c.DecryptWithAAD([]byte("record 1"), DefaultStringCodec.EncodeToString(tampered)); !errors.Is(err, ErrAuthenticationFailed) {
+			t.Errorf("DecryptWithAAD(tampered at %d) error = %v, want %v", i, err, ErrAuthenticationFailed)
+		}
+	}
+
+	if _, err := c.Decrypt("00"); !errors.Is(err, ErrCipherTextTooShort) {
+		t.Errorf("Decrypt(short) error = %v, want %v", err, ErrCipherTextTooShort)
+	}
+}
+
+// TestNewOCB_RFC7253 checks the sample results of RFC 7253, Appendix A.
+func TestNewOCB_RFC7253(t *testing.T) {
+	key, _ := hex.DecodeString("000102030405060708090A0B0C0D0E0F")
+
+	vectors := []struct {
+		nonce, aad, plaintext, ciphertext string
+	}{
+		{
+			nonce:      "BBAA99887766554433221100",
+			ciphertext: "785407BFFFC8AD9EDCC5520AC9111EE6",
+		},
+		{
+			nonce:      "BBAA99887766554433221101",
+			aad:        "0001020304050607",
+			plaintext:  "0001020304050607",
+			ciphertext: "6820B3657B6F615A5725BDA0D3B4EB3A257C9AF1F8F03009",
+		},
+		{
+			nonce:      "BBAA99887766554433221103",
+			plaintext:  "0001020304050607",
+			ciphertext: "45DD69F8F5AAE72414054CD1F35D82760B2CD00D2F99BFA9",
+		},
+		{
+			nonce:      "BBAA99887766554433221104",
+			aad:        "000102030405060708090A0B0C0D0E0F",
+			plaintext:  "000102030405060708090A0B0C0D0E0F",
+			ciphertext: "571D535B60B277188BE5147170A9A22C3AD7A4FF3835B8C5701C1CCEC8FC3358",
+		},
+	}
+
+	for _, v := range vectors {
+		nonce, _ := hex.DecodeString(v.nonce)
+		aad, _ := hex.DecodeString(v.aad)
+		plaintext, _ := hex.DecodeString(v.plaintext)
+
+		c := NewOCB(Bytes(key), Bytes(nonce)).(AADCipher)
+
+		ciphertext, err := c.EncryptWithAAD(aad, string(plaintext))
+		if err != nil {
+			t.Fatalf("EncryptWithAAD(%s) error: %v", v.nonce, err)
+		}
+		if !strings.EqualFold(ciphertext, v.ciphertext) {
+			t.Errorf("EncryptWithAAD(%s) = %s, want %s", v.nonce, ciphertext, v.ciphertext)
+		}
+
+		decrypted, err := c.DecryptWithAAD(aad, strings.ToLower(v.ciphertext))
+		if err != nil || decrypted != string(plaintext) {
+			t.Errorf("DecryptWithAAD(%s) = %x, %v, want %s", v.nonce, decrypted, err, v.plaintext)
+		}
+	}
+}
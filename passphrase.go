@@ -0,0 +1,61 @@
+package simplecipher
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/term"
+	"io"
+	"os"
+	"strings"
+)
+
+// This file provides a helper to read passphrases from the terminal.
+
+// ReadPassphrase prints the prompt to stderr, and reads a passphrase from
+// stdin without echoing it, e.g., for the Simple* constructors in a CLI:
+//
+//	passphrase, err := simplecipher.ReadPassphrase("Passphrase: ")
+//	c := simplecipher.SimpleGCM(passphrase, "nonce")
+//
+// If stdin is not a terminal (e.g., a pipe in scripts), a line is read
+// instead. The trailing newline (\n or \r\n) is not included.
+// An error wrapping [io.EOF] is returned if stdin is closed before
+// anything is entered.
+func ReadPassphrase(prompt string) (string, error) {
+	return readPassphrase(os.Stdin, os.Stderr, prompt)
+}
+
+func readPassphrase(in *os.File, out io.Writer, prompt string) (string, error) {
+	fmt.Fprint(out, prompt)
+
+	if fd := int(in.Fd()); term.IsTerminal(fd) {
+		passphrase, err := term.ReadPassword(fd)
+		fmt.Fprintln(out) // the newline entered is not echoed either
+		if err != nil {
+			return "", fmt.Errorf("read passphrase: %w", err)
+		}
+		return string(passphrase), nil
+	}
+
+	// Read byte by byte up to the newline: a buffered reader could
+	// consume the input following the passphrase.
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := in.Read(b)
+		if n > 0 {
+			if b[0] == '\n' {
+				break
+			}
+			line = append(line, b[0])
+		}
+		if errors.Is(err, io.EOF) && len(line) > 0 {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("read passphrase: %w", err)
+		}
+	}
+
+	return strings.TrimSuffix(string(line), "\r"), nil
+}
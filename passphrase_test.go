@@ -0,0 +1,40 @@
+package simplecipher
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestReadPassphrase(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe error: %v", err)
+	}
+	defer r.Close()
+
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+
+	go func() {
+		w.WriteString("pass phrase\r\nsecond\nlast")
+		w.Close()
+	}()
+
+	// not a terminal: one line at a time, without the newline
+	for _, want := range []string{"pass phrase", "second", "last"} {
+		passphrase, err := ReadPassphrase("")
+		if err != nil {
+			t.Fatalf("ReadPassphrase error: %v", err)
+		}
+		if passphrase != want {
+			t.Errorf("ReadPassphrase() = %q, want %q", passphrase, want)
+		}
+	}
+
+	if _, err := ReadPassphrase(""); !errors.Is(err, io.EOF) {
+		t.Errorf("ReadPassphrase(closed) error = %v, want %v", err, io.EOF)
+	}
+}
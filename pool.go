@@ -0,0 +1,57 @@
+package simplecipher
+
+// This file implements a pool of ciphers, to reuse the ciphers that are
+// expensive to construct across goroutines.
+
+// CipherPool is a bounded pool of [Cipher] instances created by a factory,
+// for servers handling requests concurrently:
+//
+//	pool := simplecipher.NewCipherPool(func() simplecipher.Cipher {
+//		return simplecipher.SimpleGCM(passphrase, nonce)
+//	}, runtime.GOMAXPROCS(0))
+//
+//	c := pool.Get()
+//	defer pool.Put(c)
+//
+// The ciphers of this package are stateless, except for the keys and AEADs
+// they derive on first use and cache, so a cipher put back can be reused by
+// any goroutine, saving the key derivation (e.g., scrypt) of a new one.
+// Do not pool ciphers whose state must be unique, e.g., [NewGCMCounter]
+// (two instances with the same counters reuse nonces) or [NewReplayGuard].
+//
+// A CipherPool is safe for concurrent use.
+type CipherPool struct {
+	factory func() Cipher
+	idle    chan Cipher
+}
+
+// NewCipherPool creates a new [CipherPool] keeping up to size idle ciphers
+// created by factory. A size <= 0 keeps none, i.e., Get always creates a
+// new cipher.
+func NewCipherPool(factory func() Cipher, size int) *CipherPool {
+	return &CipherPool{factory: factory, idle: make(chan Cipher, max(size, 0))}
+}
+
+// Get returns an idle cipher from the pool, or a new one from the factory
+// if there is none. The cipher is for the exclusive use of the caller until
+// it is put back with Put.
+func (p *CipherPool) Get() Cipher {
+	select {
+	case c := <-p.idle:
+		return c
+	default:
+		return p.factory()
+	}
+}
+
+// Put puts the cipher back into the pool for reuse.
+// It is dropped if the pool is full, or if it is nil.
+func (p *CipherPool) Put(c Cipher) {
+	if c == nil {
+		return
+	}
+	select {
+	case p.idle <- c:
+	default:
+	}
+}
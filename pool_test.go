@@ -0,0 +1,75 @@
+package simplecipher
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// TestCipherPool exercises concurrent Get and Put, run it with -race.
+func TestCipherPool(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	var created atomic.Int64
+	pool := NewCipherPool(func() Cipher {
+		created.Add(1)
+		return SimpleGCM("key", "nonce")
+	}, 4)
+
+	want, err := SimpleGCM("key", "nonce").Encrypt("plaintext")
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+
+	const goroutines, iterations = 16, 50
+
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				c := pool.Get()
+				ciphertext, err := c.Encrypt("plaintext")
+				if err == nil && ciphertext != want {
+					err = fmt.Errorf("Encrypt() = %s, want %s", ciphertext, want)
+				}
+				if err == nil {
+					_, err = c.Decrypt(ciphertext)
+				}
+				pool.Put(c)
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+
+	// the idle ciphers are reused without the factory
+	before := created.Load()
+	for i := 0; i < 4; i++ {
+		pool.Put(pool.Get())
+	}
+	if n := created.Load(); n != before {
+		t.Errorf("Get() with idle ciphers created %d new ones, want 0", n-before)
+	}
+
+	// a pool of size 0 keeps none
+	empty := NewCipherPool(func() Cipher { created.Add(1); return SimpleGCM("key", "nonce") }, 0)
+	before = created.Load()
+	empty.Put(empty.Get())
+	empty.Put(nil)
+	empty.Get()
+	if n := created.Load() - before; n != 2 {
+		t.Errorf("Get() from an empty pool created %d ciphers, want 2", n)
+	}
+}
@@ -0,0 +1,100 @@
+package simplecipher
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// This file implements a Cipher decorator that prepends random bytes to the
+// plaintext, so that identical plaintexts encrypt differently.
+
+// randomPrefixLenSize is the size of the big-endian length of the random
+// prefix recorded by [WithRandomPrefix].
+const randomPrefixLenSize = 2
+
+// randomPrefixCipher is a [Cipher] decorator that prepends n random bytes
+// (after their length) to the plaintext before passing it to the inner
+// Cipher.
+type randomPrefixCipher struct {
+	inner Cipher
+	n     int
+}
+
+var _ ModeCipher = (*randomPrefixCipher)(nil)
+
+// WithRandomPrefix wraps the inner [Cipher] to prepend n random bytes to the
+// plaintext before encryption, and strip them after decryption:
+//
+//	len(prefix) (2 bytes, big-endian) | random prefix (n bytes) | plaintext
+//
+// With a chaining mode (e.g., [NewCBC] or [NewCFB] with a fixed iv), the
+// random first block changes every following block, so identical (or
+// identically prefixed) plaintexts no longer produce identical ciphertexts,
+// and the predictable start of a message is not aligned with a known
+// keystream. Use n >= 16 (the AES block size) for that.
+//
+// Caveat: it does not help modes XORing the plaintext with a keystream
+// fixed by the key and iv or nonce (CTR, OFB, or GCM with a fixed nonce):
+// the keystream is still reused. Use a random iv or nonce there instead.
+//
+// The length is recorded, so Decrypt does not need n. An n out of
+// [1, 65535] fails with an error wrapping [ErrInvalidConfig].
+func WithRandomPrefix(inner Cipher, n int) Cipher {
+	return &randomPrefixCipher{inner: inner, n: n}
+}
+
+// Describe describes the inner Cipher.
+func (c *randomPrefixCipher) Describe() CipherInfo {
+	return DescribeCipher(c.inner)
+}
+
+// Mode returns the mode of the inner Cipher, or "" if unknown.
+func (c *randomPrefixCipher) Mode() ModeID {
+	return modeOf(c.inner)
+}
+
+// Encrypt prepends the random prefix to the plaintext and encrypts it
+// with the inner Cipher.
+func (c *randomPrefixCipher) Encrypt(plainText string) (cipherText string, err error) {
+	defer wrapOpError(&err, encrypt, modeOf(c.inner))
+	defer recoverFromPanic(&err)
+
+	if c.n < 1 || c.n > math.MaxUint16 {
+		return "", fmt.Errorf("%w: random prefix size %d not in [1, %d]", ErrInvalidConfig, c.n, math.MaxUint16)
+	}
+
+	prefix, err := randomBytes(c.n)
+	if err != nil {
+		return "", err
+	}
+
+	prefixed := make([]byte, 0, randomPrefixLenSize+c.n+len(plainText))
+	prefixed = binary.BigEndian.AppendUint16(prefixed, uint16(c.n))
+	prefixed = append(prefixed, prefix...)
+	prefixed = append(prefixed, plainText...)
+
+	return c.inner.Encrypt(string(prefixed))
+}
+
+// Decrypt decrypts the ciphertext with the inner Cipher and strips the
+// random prefix.
+func (c *randomPrefixCipher) Decrypt(cipherText string) (plainText string, err error) {
+	defer wrapOpError(&err, decrypt, modeOf(c.inner))
+	defer recoverFromPanic(&err)
+
+	prefixed, err := c.inner.Decrypt(cipherText)
+	if err != nil {
+		return "", err
+	}
+
+	if len(prefixed) < randomPrefixLenSize {
+		return "", fmt.Errorf("%w: missing the random prefix length", ErrMalformedCiphertext)
+	}
+	n := int(binary.BigEndian.Uint16([]byte(prefixed[:randomPrefixLenSize])))
+	if n > len(prefixed)-randomPrefixLenSize {
+		return "", fmt.Errorf("%w: random prefix of %d out of %d bytes", ErrMalformedCiphertext, n, len(prefixed)-randomPrefixLenSize)
+	}
+
+	return prefixed[randomPrefixLenSize+n:], nil
+}
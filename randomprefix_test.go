@@ -0,0 +1,64 @@
+package simplecipher
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWithRandomPrefix(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	key := String("key0key1key2key3key4key5key6key7")
+	iv := String("iv00iv01iv02iv03")
+
+	for name, plaintext := range map[string]string{
+		"empty": "",
+		"short": "plaintext",
+		"long":  strings.Repeat("plaintext", 100),
+	} {
+		testCipher(name, t, func() Cipher { return WithRandomPrefix(NewCFB(key, iv), 16) }, plaintext)
+	}
+
+	// the inner cipher is deterministic
+	inner := NewCFB(key, iv)
+	same1, _ := inner.Encrypt("GET /index.html")
+	same2, _ := inner.Encrypt("GET /index.html")
+	if same1 != same2 {
+		t.Fatalf("inner Encrypt(same plaintext) = %s and %s, want identical", same1, same2)
+	}
+
+	c := WithRandomPrefix(inner, 16)
+	ciphertext1, err := c.Encrypt("GET /index.html")
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+	ciphertext2, err := c.Encrypt("GET /index.html")
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+	if ciphertext1 == ciphertext2 {
+		t.Errorf("Encrypt(same plaintext) = %s twice, want different ciphertexts", ciphertext1)
+	}
+
+	// the prefix length is recorded: another n decrypts as well
+	decrypted, err := WithRandomPrefix(inner, 1).Decrypt(ciphertext1)
+	if err != nil || decrypted != "GET /index.html" {
+		t.Errorf("Decrypt() = %q, %v, want %q", decrypted, err, "GET /index.html")
+	}
+
+	for _, n := range []int{0, -1, 1 << 16} {
+		if _, err := WithRandomPrefix(inner, n).Encrypt("plaintext"); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("Encrypt(n=%d) error = %v, want %v", n, err, ErrInvalidConfig)
+		}
+	}
+
+	// a ciphertext without the random prefix
+	unprefixed, err := inner.Encrypt("\xff\xffplaintext")
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+	if _, err := c.Decrypt(unprefixed); !errors.Is(err, ErrMalformedCiphertext) {
+		t.Errorf("Decrypt(unprefixed) error = %v, want %v", err, ErrMalformedCiphertext)
+	}
+}
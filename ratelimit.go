@@ -0,0 +1,115 @@
+package simplecipher
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// This file implements a Cipher decorator that rate-limits decryption,
+// to slow down brute-force attacks against (passphrase-derived) keys.
+
+// rateLimitCipher is a [Cipher] decorator that throttles the Decrypt calls
+// of the inner Cipher with a token bucket.
+type rateLimitCipher struct {
+	inner Cipher
+
+	mu        sync.Mutex
+	perSecond float64   // refill rate of the bucket
+	burst     float64   // capacity of the bucket
+	tokens    float64   // available tokens
+	last      time.Time // last refill
+	now       func() time.Time
+}
+
+var _ ModeCipher = (*rateLimitCipher)(nil)
+
+// WithDecryptRateLimit wraps the inner [Cipher] to allow at most perSecond
+// Decrypt calls per second on average, e.g., for APIs exposing decryption
+// to untrusted callers.
+//
+// It is a token bucket holding up to max(perSecond, 1) tokens (so short
+// bursts are allowed), initially full. Each Decrypt call takes a token,
+// or fails immediately with an error wrapping [ErrRateLimited] if the
+// bucket is empty. Encrypt is not limited.
+//
+// A perSecond <= 0 (or NaN) fails both Encrypt and Decrypt with an error
+// wrapping [ErrInvalidConfig].
+//
+// The returned Cipher is safe for concurrent use if the inner one is.
+// The limit is per returned Cipher: share it among the callers to limit.
+func WithDecryptRateLimit(inner Cipher, perSecond float64) Cipher {
+	return newRateLimitCipher(inner, perSecond, time.Now)
+}
+
+func newRateLimitCipher(inner Cipher, perSecond float64, now func() time.Time) *rateLimitCipher {
+	burst := math.Max(perSecond, 1)
+	return &rateLimitCipher{
+		inner:     inner,
+		perSecond: perSecond,
+		burst:     burst,
+		tokens:    burst,
+		last:      now(),
+		now:       now,
+	}
+}
+
+// Describe describes the inner Cipher.
+func (c *rateLimitCipher) Describe() CipherInfo {
+	return DescribeCipher(c.inner)
+}
+
+// Mode returns the mode of the inner Cipher, or "" if unknown.
+func (c *rateLimitCipher) Mode() ModeID {
+	return modeOf(c.inner)
+}
+
+// Encrypt encrypts the plaintext with the inner Cipher, without limit.
+func (c *rateLimitCipher) Encrypt(plainText string) (cipherText string, err error) {
+	defer wrapOpError(&err, encrypt, modeOf(c.inner))
+
+	if err := c.validate(); err != nil {
+		return "", err
+	}
+	return c.inner.Encrypt(plainText)
+}
+
+// Decrypt takes a token and decrypts the ciphertext with the inner Cipher.
+func (c *rateLimitCipher) Decrypt(cipherText string) (plainText string, err error) {
+	defer wrapOpError(&err, decrypt, modeOf(c.inner))
+
+	if err := c.validate(); err != nil {
+		return "", err
+	}
+	if !c.allow() {
+		return "", fmt.Errorf("%w: more than %v decryptions per second", ErrRateLimited, c.perSecond)
+	}
+	return c.inner.Decrypt(cipherText)
+}
+
+// validate checks the rate: the bucket would never refill otherwise.
+func (c *rateLimitCipher) validate() error {
+	if !(c.perSecond > 0) { // NaN too
+		return fmt.Errorf("%w: rate limit %v per second", ErrInvalidConfig, c.perSecond)
+	}
+	return nil
+}
+
+// allow refills the bucket and takes a token if available.
+func (c *rateLimitCipher) allow() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if elapsed := now.Sub(c.last).Seconds(); elapsed > 0 {
+		c.tokens = math.Min(c.burst, c.tokens+elapsed*c.perSecond)
+	}
+	c.last = now
+
+	if c.tokens < 1 {
+		return false
+	}
+	c.tokens--
+	return true
+}
@@ -0,0 +1,105 @@
+package simplecipher
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithDecryptRateLimit(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	inner := NewGCMWithConfig(GCMConfig{Key: Bytes([]byte("key0key1key2key3")), RandomNonce: true})
+	ciphertext, err := inner.Encrypt("plaintext")
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+
+	now := time.Unix(0, 0)
+	c := newRateLimitCipher(inner, 2, func() time.Time { return now })
+
+	// the initial burst of 2 is allowed, the rapid 3rd decrypt is not
+	for i := 0; i < 2; i++ {
+		if _, err := c.Decrypt(ciphertext); err != nil {
+			t.Fatalf("Decrypt #%d error: %v", i, err)
+		}
+	}
+	if _, err := c.Decrypt(ciphertext); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("rapid Decrypt error = %v, want %v", err, ErrRateLimited)
+	}
+
+	// spaced out decrypts succeed
+	for i := 0; i < 5; i++ {
+		now = now.Add(500 * time.Millisecond)
+		if _, err := c.Decrypt(ciphertext); err != nil {
+			t.Errorf("spaced Decrypt #%d error: %v", i, err)
+		}
+	}
+
+	// Encrypt is not limited
+	for i := 0; i < 5; i++ {
+		if _, err := c.Encrypt("plaintext"); err != nil {
+			t.Errorf("Encrypt #%d error: %v", i, err)
+		}
+	}
+}
+
+func TestWithDecryptRateLimit_invalid(t *testing.T) {
+	inner := NewGCMWithConfig(GCMConfig{Key: Bytes([]byte("key0key1key2key3")), RandomNonce: true})
+	ciphertext, err := inner.Encrypt("plaintext")
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+
+	for _, perSecond := range []float64{0, -1, math.NaN(), math.Inf(-1)} {
+		c := WithDecryptRateLimit(inner, perSecond)
+		if _, err := c.Decrypt(ciphertext); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("WithDecryptRateLimit(%v).Decrypt() error = %v, want %v", perSecond, err, ErrInvalidConfig)
+		}
+		if _, err := c.Encrypt("plaintext"); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("WithDecryptRateLimit(%v).Encrypt() error = %v, want %v", perSecond, err, ErrInvalidConfig)
+		}
+	}
+}
+
+func TestWithDecryptRateLimit_concurrent(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	inner := NewGCMWithConfig(GCMConfig{Key: Bytes([]byte("key0key1key2key3")), RandomNonce: true})
+	ciphertext, err := inner.Encrypt("plaintext")
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+
+	c := WithDecryptRateLimit(inner, 10)
+
+	var allowed, limited atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.Decrypt(ciphertext)
+			switch {
+			case err == nil:
+				allowed.Add(1)
+			case errors.Is(err, ErrRateLimited):
+				limited.Add(1)
+			default:
+				t.Errorf("Decrypt error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// a burst of 10, plus the few tokens refilled while running
+	if n := allowed.Load(); n < 10 || n > 15 {
+		t.Errorf("allowed %d of 50 concurrent decrypts, want about 10", n)
+	}
+	if limited.Load() == 0 {
+		t.Errorf("no concurrent decrypt was rate limited")
+	}
+}
@@ -0,0 +1,217 @@
+package simplecipher
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// This file implements an AEAD stream cipher with per-record framing,
+// for streaming over network connections.
+//
+// The stream is split into records of at most maxRecord plaintext bytes,
+// each sealed independently by AES-GCM. The stream output is laid out as:
+//
+//	base nonce (12 bytes)
+//	records: length (4 bytes, big-endian) | GCM sealed record
+//
+// The nonce of the i-th record is the base nonce XOR i (big-endian),
+// so reordered, replayed or dropped records fail the authentication.
+// The last record (possibly empty) is marked as final in the additional
+// data, so that a truncated stream is detected too.
+
+// DefaultMaxRecord is the default maximum plaintext size of a record.
+const DefaultMaxRecord = 16 * 1024
+
+// record additional data flags
+var (
+	recordAdditionalData      = []byte{0}
+	recordFinalAdditionalData = []byte{1}
+)
+
+// recordStream is the per-record AES-GCM implementation for the [Stream] interface.
+type recordStream struct {
+	key       Key
+	maxRecord int
+}
+
+var _ Stream = (*recordStream)(nil)
+
+// NewRecordStream creates a new AES-GCM stream cipher with per-record framing.
+//
+// Each read of up to maxRecord bytes from the plaintext becomes a
+// length-prefixed, independently authenticated record. DecryptStream
+// verifies each record before writing its plaintext, and returns an error
+// wrapping [ErrAuthenticationFailed] on a tampered, reordered, dropped or
+// truncated record. So no unauthenticated plaintext is ever written.
+//
+// However, the plaintext of the (verified) records before the failing one
+// has already been written: the error also wraps [ErrPartialPlaintext] in
+// that case. The stream as a whole is only authentic if DecryptStream
+// returns nil, so callers must treat a mid-stream error as "discard
+// everything written so far" (e.g., remove the partial output file or
+// roll back the transaction).
+//
+// The key must be 16, 24, or 32 bytes long to select AES-128, AES-192, or AES-256.
+// maxRecord <= 0 selects [DefaultMaxRecord].
+func NewRecordStream(key Key, maxRecord int) Stream {
+	if maxRecord <= 0 {
+		maxRecord = DefaultMaxRecord
+	}
+	return &recordStream{key: keyOrEmpty(key), maxRecord: maxRecord}
+}
+
+// Mode returns [ModeGCM].
+func (s *recordStream) Mode() ModeID {
+	return ModeGCM
+}
+
+// Describe describes the stream cipher, e.g., AES-256-GCM.
+func (s *recordStream) Describe() CipherInfo {
+	return CipherInfo{Algorithm: algorithmAES, Mode: ModeGCM, KeyBits: keyBits(s.key)}
+}
+
+// EncryptStream encrypts the plaintext from the reader into records.
+func (s *recordStream) EncryptStream(plainText io.Reader, cipherText io.Writer) (err error) {
+	defer wrapOpError(&err, encrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	aesgcm, err := s.aead()
+	if err != nil {
+		return err
+	}
+
+	baseNonce, err := randomBytes(aesgcm.NonceSize())
+	if err != nil {
+		return err
+	}
+	if _, err := cipherText.Write(baseNonce); err != nil {
+		return fmt.Errorf("%w: %w", ErrCopy, err)
+	}
+
+	buf := make([]byte, s.maxRecord)
+	out := make([]byte, 0, 4+s.maxRecord+aesgcm.Overhead())
+
+	for seq := uint64(0); ; {
+		n, readErr := plainText.Read(buf)
+		if readErr != nil && readErr != io.EOF {
+			return fmt.Errorf("%w: %w", ErrCopy, readErr)
+		}
+
+		final := readErr == io.EOF
+		if n == 0 && !final {
+			continue
+		}
+
+		additionalData := recordAdditionalData
+		if final {
+			additionalData = recordFinalAdditionalData
+		}
+
+		out = out[:4]
+		out = aesgcm.Seal(out, recordNonce(baseNonce, seq), buf[:n], additionalData)
+		binary.BigEndian.PutUint32(out, uint32(len(out)-4))
+		seq++
+
+		if _, err := cipherText.Write(out); err != nil {
+			return fmt.Errorf("%w: %w", ErrCopy, err)
+		}
+
+		if final {
+			return nil
+		}
+	}
+}
+
+// DecryptStream reads and verifies the records from the reader,
+// and writes the plaintext of each record to the writer.
+func (s *recordStream) DecryptStream(cipherText io.Reader, plainText io.Writer) (err error) {
+	defer wrapOpError(&err, decrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	aesgcm, err := s.aead()
+	if err != nil {
+		return err
+	}
+
+	baseNonce := make([]byte, aesgcm.NonceSize())
+	if _, err := io.ReadFull(cipherText, baseNonce); err != nil {
+		return fmt.Errorf("%w: %w", ErrCopy, err)
+	}
+
+	header := make([]byte, 4)
+	buf := make([]byte, s.maxRecord+aesgcm.Overhead())
+	// Open zeroes its output on failure, so it must not work in-place here
+	plainBuf := make([]byte, 0, s.maxRecord)
+
+	// written is the plaintext size written, to flag a partial output
+	var written int64
+	defer func() {
+		if err != nil && written > 0 {
+			err = fmt.Errorf("%w (%d bytes): %w", ErrPartialPlaintext, written, err)
+		}
+	}()
+
+	for seq := uint64(0); ; seq++ {
+		if _, err := io.ReadFull(cipherText, header); err != nil {
+			if err == io.EOF {
+				return fmt.Errorf("%w: truncated stream, no final record", ErrAuthenticationFailed)
+			}
+			return fmt.Errorf("%w: %w", ErrCopy, err)
+		}
+
+		length := binary.BigEndian.Uint32(header)
+		if length > uint32(len(buf)) {
+			return fmt.Errorf("%w: record of %d bytes exceeds the maximum %d", ErrAuthenticationFailed, length, len(buf))
+		}
+
+		record := buf[:length]
+		if _, err := io.ReadFull(cipherText, record); err != nil {
+			return fmt.Errorf("%w: %w", ErrCopy, err)
+		}
+
+		nonce := recordNonce(baseNonce, seq)
+
+		final := false
+		plaintext, err := aesgcm.Open(plainBuf, nonce, record, recordAdditionalData)
+		if err != nil {
+			plaintext, err = aesgcm.Open(plainBuf, nonce, record, recordFinalAdditionalData)
+			final = true
+		}
+		if err != nil {
+			return fmt.Errorf("%w: record %d: %w", ErrAuthenticationFailed, seq, err)
+		}
+
+		n, err := plainText.Write(plaintext)
+		written += int64(n)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrCopy, err)
+		}
+
+		if final {
+			return nil
+		}
+	}
+}
+
+// aead creates the AES-GCM AEAD from the key.
+func (s *recordStream) aead() (cipher.AEAD, error) {
+	block, err := newAesBlock(s.key.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNewAesCipher, err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// recordNonce returns the nonce of the seq-th record:
+// the base nonce XOR seq (big-endian, in the last 8 bytes).
+func recordNonce(baseNonce []byte, seq uint64) []byte {
+	nonce := make([]byte, len(baseNonce))
+	copy(nonce, baseNonce)
+
+	tail := nonce[len(nonce)-8:]
+	binary.BigEndian.PutUint64(tail, binary.BigEndian.Uint64(tail)^seq)
+
+	return nonce
+}
@@ -0,0 +1,110 @@
+package simplecipher
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// splitRecords splits the output of a record stream into
+// the base nonce and the length-prefixed records.
+func splitRecords(t *testing.T, ciphertext []byte) (baseNonce []byte, records [][]byte) {
+	baseNonce, ciphertext = ciphertext[:NonceSize], ciphertext[NonceSize:]
+	for len(ciphertext) > 0 {
+		n := 4 + int(binary.BigEndian.Uint32(ciphertext))
+		if n > len(ciphertext) {
+			t.Fatalf("bad record length %d", n)
+		}
+		records = append(records, ciphertext[:n])
+		ciphertext = ciphertext[n:]
+	}
+	return baseNonce, records
+}
+
+func TestRecordStream(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	stream := NewRecordStream(NewAesKey("key"), 16)
+	plaintext := strings.Repeat("0123456789", 10) // 100 bytes
+
+	testStream("", t, func() Stream { return stream }, plaintext)
+	testStream("empty", t, func() Stream { return stream }, "")
+
+	ciphertext := new(bytes.Buffer)
+	if err := stream.EncryptStream(strings.NewReader(plaintext), ciphertext); err != nil {
+		t.Fatalf("EncryptStream error: %v", err)
+	}
+
+	baseNonce, records := splitRecords(t, ciphertext.Bytes())
+	if len(records) != 8 { // 7 full or partial records + the final one
+		t.Fatalf("got %d records, want 8", len(records))
+	}
+
+	join := func(records ...[]byte) []byte {
+		return bytes.Join(append([][]byte{baseNonce}, records...), nil)
+	}
+
+	tampered := bytes.Clone(records[2])
+	tampered[10] ^= 1
+
+	tests := map[string][]byte{
+		"tampered":  join(records[0], records[1], tampered, records[3], records[4], records[5], records[6], records[7]),
+		"dropped":   join(records[0], records[1], records[3], records[4], records[5], records[6], records[7]),
+		"reordered": join(records[0], records[2], records[1], records[3], records[4], records[5], records[6], records[7]),
+		"truncated": join(records[0], records[1], records[2], records[3], records[4], records[5], records[6]),
+	}
+	for name, bad := range tests {
+		t.Run(name, func(t *testing.T) {
+			decrypted := new(bytes.Buffer)
+			err := stream.DecryptStream(bytes.NewReader(bad), decrypted)
+			if !errors.Is(err, ErrAuthenticationFailed) {
+				t.Errorf("DecryptStream(%s) error = %v, want %v", name, err, ErrAuthenticationFailed)
+			}
+		})
+	}
+}
+
+func TestRecordStream_verifyBeforeRelease(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	stream := NewRecordStream(NewAesKey("key"), 16)
+	plaintext := strings.Repeat("0123456789", 10) // 100 bytes
+
+	ciphertext := new(bytes.Buffer)
+	if err := stream.EncryptStream(strings.NewReader(plaintext), ciphertext); err != nil {
+		t.Fatalf("EncryptStream error: %v", err)
+	}
+
+	baseNonce, records := splitRecords(t, ciphertext.Bytes())
+
+	corrupt := func(i int) []byte {
+		corrupted := make([][]byte, len(records))
+		copy(corrupted, records)
+		corrupted[i] = bytes.Clone(records[i])
+		corrupted[i][len(corrupted[i])-1] ^= 1
+		return bytes.Join(append([][]byte{baseNonce}, corrupted...), nil)
+	}
+
+	// a late record is corrupted: the verified records before it are
+	// written, and the error flags the partial output.
+	decrypted := new(bytes.Buffer)
+	err := stream.DecryptStream(bytes.NewReader(corrupt(5)), decrypted)
+	if !errors.Is(err, ErrAuthenticationFailed) || !errors.Is(err, ErrPartialPlaintext) {
+		t.Errorf("DecryptStream(late corrupted) error = %v, want %v and %v", err, ErrAuthenticationFailed, ErrPartialPlaintext)
+	}
+	if want := plaintext[:5*16]; decrypted.String() != want {
+		t.Errorf("DecryptStream(late corrupted) wrote %q, want only the verified %q", decrypted.String(), want)
+	}
+
+	// the first record is corrupted: nothing is written.
+	decrypted.Reset()
+	err = stream.DecryptStream(bytes.NewReader(corrupt(0)), decrypted)
+	if !errors.Is(err, ErrAuthenticationFailed) || errors.Is(err, ErrPartialPlaintext) {
+		t.Errorf("DecryptStream(first corrupted) error = %v, want %v only", err, ErrAuthenticationFailed)
+	}
+	if decrypted.Len() != 0 {
+		t.Errorf("DecryptStream(first corrupted) wrote %d bytes, want 0", decrypted.Len())
+	}
+}
@@ -0,0 +1,86 @@
+package simplecipher
+
+import (
+	"errors"
+	"io"
+)
+
+// This file provides helpers to re-encrypt ciphertexts under another
+// cipher (e.g., a new key), for key rotation pipelines.
+
+// Reencrypt decrypts the ciphertext with the old cipher, and encrypts the
+// plaintext with the new one, so that the plaintext never reaches the
+// caller:
+//
+//	for _, row := range rows {
+//		row.Secret, err = simplecipher.Reencrypt(oldCipher, newCipher, row.Secret)
+//	}
+//
+// The transient plaintext is zeroed before returning if both ciphers
+// implement [AppendCipher] (e.g., the GCM and the CBC ciphers), which work
+// on byte slices. Otherwise, it passes through Go strings, which can not be
+// zeroed: it stays in memory until the garbage collector reuses it.
+//
+// Both ciphers must output [DefaultStringCodec] ciphertexts. On error,
+// the error of the decryption or the encryption is returned as is.
+func Reencrypt(old, new Cipher, cipherText string) (string, error) {
+	oldAppend, ok1 := old.(AppendCipher)
+	newAppend, ok2 := new.(AppendCipher)
+	if !ok1 || !ok2 {
+		plainText, err := old.Decrypt(cipherText)
+		if err != nil {
+			return "", err
+		}
+		return new.Encrypt(plainText)
+	}
+
+	ciphertext, err := decodeCipherText(cipherText)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := oldAppend.DecryptAppend(nil, ciphertext)
+	defer clear(plaintext)
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err = newAppend.EncryptAppend(nil, plaintext)
+	if err != nil {
+		return "", err
+	}
+
+	return DefaultStringCodec.EncodeToString(ciphertext), nil
+}
+
+// ReencryptStream decrypts the src stream with the old stream cipher, and
+// encrypts the plaintext with the new one into dst.
+//
+// The decryption is piped into the encryption through an [io.Pipe], so
+// large files are re-encrypted without buffering them in memory.
+// If either side fails, the other side is stopped, and the error of the
+// side that failed first is returned. The output written to dst is then
+// incomplete and should be discarded.
+func ReencryptStream(old, new Stream, src io.Reader, dst io.Writer) error {
+	pr, pw := io.Pipe()
+
+	decrypted := make(chan error, 1)
+	go func() {
+		err := old.DecryptStream(src, pw)
+		// a nil error closes the pipe normally, i.e., an io.EOF to the reader
+		pw.CloseWithError(err)
+		decrypted <- err
+	}()
+
+	encErr := new.EncryptStream(pr, dst)
+	// unblock the decryption if the encryption stopped reading early:
+	// its writes fail with encErr, or io.ErrClosedPipe if encErr is nil
+	pr.CloseWithError(encErr)
+	decErr := <-decrypted
+
+	if decErr != nil && (encErr == nil || errors.Is(encErr, decErr)) {
+		// the decryption failed first, and the encryption read its error
+		return decErr
+	}
+	return encErr
+}
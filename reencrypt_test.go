@@ -0,0 +1,173 @@
+package simplecipher
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	mathrand "math/rand"
+	"testing"
+)
+
+func TestReencrypt(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	oldKey := NewAesKey("old key")
+	newKey := NewAesKey("new key")
+
+	ciphers := map[string]struct{ old, new Cipher }{
+		"gcm": {
+			NewGCMWithConfig(GCMConfig{Key: oldKey, RandomNonce: true}),
+			NewGCMWithConfig(GCMConfig{Key: newKey, RandomNonce: true}),
+		},
+		"keyring": {NewKeyring(oldKey), NewKeyring(newKey)},
+		"gcmToCBC": {
+			NewGCMWithConfig(GCMConfig{Key: oldKey, RandomNonce: true}),
+			SimpleCBC("new key"),
+		},
+	}
+	for name, tt := range ciphers {
+		t.Run(name, func(t *testing.T) {
+			oldCiphertext, err := tt.old.Encrypt("plaintext")
+			if err != nil {
+				t.Fatalf("Encrypt error: %v", err)
+			}
+
+			newCiphertext, err := Reencrypt(tt.old, tt.new, oldCiphertext)
+			if err != nil {
+				t.Fatalf("Reencrypt error: %v", err)
+			}
+
+			plaintext, err := tt.new.Decrypt(newCiphertext)
+			if err != nil || plaintext != "plaintext" {
+				t.Errorf("Decrypt(Reencrypt()) with the new cipher = %q, %v, want %q", plaintext, err, "plaintext")
+			}
+
+			// only the new key decrypts it
+			if plaintext, err := tt.old.Decrypt(newCiphertext); err == nil && plaintext == "plaintext" {
+				t.Errorf("Decrypt(Reencrypt()) with the old cipher = %q, want an error", plaintext)
+			}
+		})
+	}
+
+	// the error of the old cipher is returned
+	gcm := NewGCMWithConfig(GCMConfig{Key: oldKey, RandomNonce: true})
+	ciphertext, _ := gcm.Encrypt("plaintext")
+	other := NewGCMWithConfig(GCMConfig{Key: newKey, RandomNonce: true})
+	if _, err := Reencrypt(other, gcm, ciphertext); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("Reencrypt(wrong old key) error = %v, want %v", err, ErrAuthenticationFailed)
+	}
+	if _, err := Reencrypt(gcm, other, "not hex"); !errors.Is(err, ErrMalformedCiphertext) {
+		t.Errorf("Reencrypt(malformed) error = %v, want %v", err, ErrMalformedCiphertext)
+	}
+}
+
+// clearCheckingCipher is an [AppendCipher] keeping the plaintexts returned
+// by DecryptAppend, to check that they are zeroed after use.
+type clearCheckingCipher struct {
+	AppendCipher
+	plaintexts [][]byte
+}
+
+func (c *clearCheckingCipher) DecryptAppend(dst, cipherText []byte) ([]byte, error) {
+	plaintext, err := c.AppendCipher.DecryptAppend(dst, cipherText)
+	c.plaintexts = append(c.plaintexts, plaintext)
+	return plaintext, err
+}
+
+func TestReencrypt_zeroed(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	ciphers := map[string]struct{ old, new Cipher }{
+		"gcmToGCM": {
+			NewGCMWithConfig(GCMConfig{Key: NewAesKey("old key"), RandomNonce: true}),
+			NewGCMWithConfig(GCMConfig{Key: NewAesKey("new key"), RandomNonce: true}),
+		},
+		"cbcToGCM": {
+			SimpleCBC("old key"),
+			NewGCMWithConfig(GCMConfig{Key: NewAesKey("new key"), RandomNonce: true}),
+		},
+	}
+	for name, tt := range ciphers {
+		t.Run(name, func(t *testing.T) {
+			oldCiphertext, err := tt.old.Encrypt("plaintext")
+			if err != nil {
+				t.Fatalf("Encrypt error: %v", err)
+			}
+
+			old := &clearCheckingCipher{AppendCipher: tt.old.(AppendCipher)}
+			if _, err := Reencrypt(old, tt.new, oldCiphertext); err != nil {
+				t.Fatalf("Reencrypt error: %v", err)
+			}
+
+			if len(old.plaintexts) != 1 {
+				t.Fatalf("DecryptAppend called %d times, want 1", len(old.plaintexts))
+			}
+			if plaintext := old.plaintexts[0]; !bytes.Equal(plaintext, make([]byte, len("plaintext"))) {
+				t.Errorf("plaintext after Reencrypt = %q, want zeroed", plaintext)
+			}
+		})
+	}
+}
+
+// errWriter is an io.Writer always failing with err.
+type errWriter struct{ err error }
+
+func (w errWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
+
+func TestReencryptStream(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	oldKey := NewAesKey("old key")
+	newKey := NewAesKey("new key")
+
+	// a large file of 8 MiB, more than any internal buffer
+	plaintext := make([]byte, 8<<20)
+	if _, err := mathrand.New(mathrand.NewSource(1)).Read(plaintext); err != nil {
+		t.Fatal(err)
+	}
+
+	oldStream := NewAuthenticatedCTRStream(oldKey)
+	newStream := NewAuthenticatedCTRStream(newKey)
+
+	var oldCiphertext bytes.Buffer
+	if err := oldStream.EncryptStream(bytes.NewReader(plaintext), &oldCiphertext); err != nil {
+		t.Fatalf("EncryptStream error: %v", err)
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		var newCiphertext bytes.Buffer
+		if err := ReencryptStream(oldStream, newStream, bytes.NewReader(oldCiphertext.Bytes()), &newCiphertext); err != nil {
+			t.Fatalf("ReencryptStream error: %v", err)
+		}
+
+		var got bytes.Buffer
+		if err := newStream.DecryptStream(bytes.NewReader(newCiphertext.Bytes()), &got); err != nil {
+			t.Fatalf("DecryptStream with the new stream error: %v", err)
+		}
+		if !bytes.Equal(got.Bytes(), plaintext) {
+			t.Errorf("DecryptStream(ReencryptStream()) != plaintext")
+		}
+
+		if err := oldStream.DecryptStream(bytes.NewReader(newCiphertext.Bytes()), io.Discard); !errors.Is(err, ErrAuthenticationFailed) {
+			t.Errorf("DecryptStream with the old stream error = %v, want %v", err, ErrAuthenticationFailed)
+		}
+	})
+
+	t.Run("decryptError", func(t *testing.T) {
+		wrongStream := NewAuthenticatedCTRStream(NewAesKey("wrong key"))
+		err := ReencryptStream(wrongStream, newStream, bytes.NewReader(oldCiphertext.Bytes()), io.Discard)
+		if !errors.Is(err, ErrAuthenticationFailed) {
+			t.Errorf("ReencryptStream(wrong old key) error = %v, want %v", err, ErrAuthenticationFailed)
+		}
+	})
+
+	t.Run("encryptError", func(t *testing.T) {
+		errWrite := errors.New("write error")
+		err := ReencryptStream(oldStream, newStream, bytes.NewReader(oldCiphertext.Bytes()), errWriter{errWrite})
+		if !errors.Is(err, errWrite) {
+			t.Errorf("ReencryptStream(failing dst) error = %v, want %v", err, errWrite)
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package simplecipher
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+)
+
+// This file implements an AES-GCM cipher with a sequence number based
+// replay guard, for message-based protocols.
+//
+// The ciphertext is laid out as:
+//
+//	sequence number (8 bytes, big-endian) | nonce (12 bytes) | ciphertext | tag
+//
+// The sequence number is authenticated as the additional data.
+
+// replayGuardSeqSize is the size of the sequence number.
+const replayGuardSeqSize = 8
+
+// replayGuard is the AES-GCM cipher rejecting replayed messages,
+// implementing the [Cipher] interface.
+type replayGuard struct {
+	gcm *gcm
+
+	mu       sync.Mutex
+	sent     uint64 // sequence number of the last encrypted message
+	received uint64 // sequence number of the last decrypted message
+}
+
+var _ ModeCipher = (*replayGuard)(nil)
+
+// NewReplayGuard creates a new GCM cipher with the given key and a random
+// nonce per message, guarding against replayed messages.
+//
+// Encrypt embeds a monotonically increasing sequence number (from 1) into
+// each message, authenticated as the additional data. Decrypt rejects a
+// message whose sequence number is not greater than the last one it
+// accepted with an error wrapping [ErrReplayDetected], so messages must be
+// decrypted in order: use it over an ordered transport (e.g., TCP, or a
+// queue with ordering), one instance to send and one to receive.
+// Dropped messages are tolerated.
+//
+// The state is per instance, in memory, and safe for concurrent use.
+// A new instance (e.g., after a restart) starts over, and would accept
+// the old messages again: rotate the key along with it.
+func NewReplayGuard(key Key) Cipher {
+	return &replayGuard{gcm: &gcm{cfg: GCMConfig{Key: keyOrEmpty(key), RandomNonce: true}}}
+}
+
+// Mode returns [ModeGCM].
+func (g *replayGuard) Mode() ModeID {
+	return ModeGCM
+}
+
+// Describe describes the cipher, e.g., AES-256-GCM.
+func (g *replayGuard) Describe() CipherInfo {
+	return g.gcm.Describe()
+}
+
+// Validate checks the length of the key.
+func (g *replayGuard) Validate() error {
+	return g.gcm.Validate()
+}
+
+// Encrypt encrypts the given plaintext using GCM, with the next sequence
+// number. The ciphertext is returned with [DefaultStringCodec] encoding.
+func (g *replayGuard) Encrypt(plainText string) (cipherText string, err error) {
+	defer wrapOpError(&err, encrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	aesgcm, prefix, nonce, err := g.gcm.sealNonce()
+	if err != nil {
+		return "", err
+	}
+
+	g.mu.Lock()
+	g.sent++
+	seq := g.sent
+	g.mu.Unlock()
+
+	out := binary.BigEndian.AppendUint64(nil, seq)
+	additionalData := out[:replayGuardSeqSize]
+	out = append(out, prefix...)
+	out = aesgcm.Seal(out, nonce, []byte(plainText), additionalData)
+
+	return DefaultStringCodec.EncodeToString(out), nil
+}
+
+// Decrypt decrypts the given ciphertext using GCM, and rejects it if its
+// sequence number is not greater than the last accepted one.
+// The ciphertext must be a [DefaultStringCodec] string.
+func (g *replayGuard) Decrypt(cipherText string) (plainText string, err error) {
+	defer wrapOpError(&err, decrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	ciphertext, err := decodeCipherText(cipherText)
+	if err != nil {
+		return "", err
+	}
+
+	if len(ciphertext) < replayGuardSeqSize {
+		return "", ErrCipherTextTooShort
+	}
+	additionalData, ciphertext := ciphertext[:replayGuardSeqSize], ciphertext[replayGuardSeqSize:]
+	seq := binary.BigEndian.Uint64(additionalData)
+
+	aesgcm, nonce, ciphertext, err := g.gcm.openNonce(ciphertext)
+	if err != nil {
+		return "", err
+	}
+
+	// authenticate before updating the state,
+	// so that a forged sequence number can not advance it
+	plaintext, err := aesgcm.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrAuthenticationFailed, err)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if seq <= g.received {
+		return "", fmt.Errorf("%w: sequence number %d, last seen %d", ErrReplayDetected, seq, g.received)
+	}
+	g.received = seq
+
+	return string(plaintext), nil
+}
@@ -0,0 +1,86 @@
+package simplecipher
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestNewReplayGuard(t *testing.T) {
+	key := Bytes([]byte("key0key1key2key3"))
+	sender := NewReplayGuard(key)
+	receiver := NewReplayGuard(key)
+
+	var messages []string
+	for _, plaintext := range []string{"first", "second", "third", "fourth"} {
+		ciphertext, err := sender.Encrypt(plaintext)
+		if err != nil {
+			t.Fatalf("Encrypt() error = %v", err)
+		}
+		messages = append(messages, ciphertext)
+	}
+
+	// in order, with a dropped message
+	for _, i := range []int{0, 1, 3} {
+		plaintext, err := receiver.Decrypt(messages[i])
+		if err != nil {
+			t.Errorf("Decrypt(message %d) error = %v", i, err)
+		}
+		if want := []string{"first", "second", "third", "fourth"}[i]; plaintext != want {
+			t.Errorf("Decrypt(message %d) = %q, want %q", i, plaintext, want)
+		}
+	}
+
+	// replayed and older messages
+	for _, i := range []int{3, 0, 2} {
+		if _, err := receiver.Decrypt(messages[i]); !errors.Is(err, ErrReplayDetected) {
+			t.Errorf("Decrypt(message %d again) error = %v, want %v", i, err, ErrReplayDetected)
+		}
+	}
+
+	// a forged sequence number fails the authentication
+	// and does not advance the state
+	raw, _ := DefaultStringCodec.DecodeString(messages[3])
+	raw[replayGuardSeqSize-1] = 0xff
+	if _, err := NewReplayGuard(key).Decrypt(DefaultStringCodec.EncodeToString(raw)); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("Decrypt(forged sequence number) error = %v, want %v", err, ErrAuthenticationFailed)
+	}
+	fresh := NewReplayGuard(key)
+	_, _ = fresh.Decrypt(DefaultStringCodec.EncodeToString(raw))
+	if _, err := fresh.Decrypt(messages[0]); err != nil {
+		t.Errorf("Decrypt() after a forged message error = %v", err)
+	}
+}
+
+func TestNewReplayGuard_concurrent(t *testing.T) {
+	key := Bytes([]byte("key0key1key2key3"))
+	sender := NewReplayGuard(key)
+
+	ciphertext, _ := sender.Encrypt("plaintext")
+
+	// the same message decrypted concurrently is accepted only once
+	receiver := NewReplayGuard(key)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := receiver.Decrypt(ciphertext)
+			switch {
+			case err == nil:
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			case !errors.Is(err, ErrReplayDetected):
+				t.Errorf("Decrypt() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if accepted != 1 {
+		t.Errorf("Decrypt() accepted %d times, want 1", accepted)
+	}
+}
@@ -0,0 +1,104 @@
+package simplecipher
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+)
+
+// This file implements hybrid encryption to an RSA public key.
+//
+// A random data encryption key (DEK) is generated for each message.
+// The payload is encrypted with AES-256-GCM under the DEK,
+// and the DEK is wrapped with RSA-OAEP (SHA-256) under the public key.
+//
+// The sealed message is laid out as follows, and then encoded with
+// [DefaultStringCodec]:
+//
+//	wrapped DEK (the size of the RSA modulus, e.g., 256 bytes for RSA-2048)
+//	payload nonce (12 bytes)
+//	payload ciphertext (with GCM tag)
+
+// SealRSA encrypts the plaintext so that only the holder of the private key
+// of pub can decrypt it via [OpenRSA]:
+//
+//	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+//	sealed, err := simplecipher.SealRSA(&priv.PublicKey, "secret")
+//	plain, err := simplecipher.OpenRSA(priv, sealed)
+//
+// The payload can be of any size: only the random AES key is encrypted with
+// RSA. Use RSA keys of 2048 bits or more.
+//
+// The sealed message is returned with [DefaultStringCodec] encoding.
+func SealRSA(pub *rsa.PublicKey, plain string) (sealed string, err error) {
+	defer wrapOpError(&err, encrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	if pub == nil {
+		return "", fmt.Errorf("%w: nil RSA public key", ErrInvalidConfig)
+	}
+
+	dek, err := randomBytes(envelopeDekSize)
+	if err != nil {
+		return "", err
+	}
+
+	wrappedDek, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, dek, nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrKeySize, err)
+	}
+
+	nonce, err := randomBytes(envelopeNonceSize)
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := sealGCM(dek, nonce, []byte(plain))
+	if err != nil {
+		return "", err
+	}
+
+	out := append(wrappedDek, nonce...)
+	out = append(out, ciphertext...)
+
+	return DefaultStringCodec.EncodeToString(out), nil
+}
+
+// OpenRSA decrypts a message sealed by [SealRSA] with the private key.
+//
+// If the message was not sealed to the public key of priv, an error
+// wrapping [ErrNoRecipient] is returned.
+func OpenRSA(priv *rsa.PrivateKey, sealed string) (plain string, err error) {
+	defer wrapOpError(&err, decrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	if priv == nil {
+		return "", fmt.Errorf("%w: nil RSA private key", ErrInvalidConfig)
+	}
+
+	ciphertext, err := decodeCipherText(sealed)
+	if err != nil {
+		return "", err
+	}
+
+	wrappedDekSize := priv.Size()
+	if len(ciphertext) < wrappedDekSize+envelopeNonceSize+gcmTagSize {
+		return "", ErrCipherTextTooShort
+	}
+
+	wrappedDek, ciphertext := ciphertext[:wrappedDekSize], ciphertext[wrappedDekSize:]
+	nonce, ciphertext := ciphertext[:envelopeNonceSize], ciphertext[envelopeNonceSize:]
+
+	dek, err := rsa.DecryptOAEP(sha256.New(), nil, priv, wrappedDek, nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrNoRecipient, err)
+	}
+
+	plaintext, err := openGCM(dek, nonce, ciphertext)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
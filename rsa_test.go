@@ -0,0 +1,58 @@
+package simplecipher
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSealRSA(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, plaintext := range []string{"", "secret", strings.Repeat("a large payload ", 1024)} {
+		sealed, err := SealRSA(&priv.PublicKey, plaintext)
+		if err != nil {
+			t.Fatalf("SealRSA error: %v", err)
+		}
+
+		got, err := OpenRSA(priv, sealed)
+		if err != nil || got != plaintext {
+			t.Errorf("OpenRSA(SealRSA(%.16q)) = %.16q, %v", plaintext, got, err)
+		}
+
+		if _, err := OpenRSA(other, sealed); !errors.Is(err, ErrNoRecipient) {
+			t.Errorf("OpenRSA(wrong key) error = %v, want %v", err, ErrNoRecipient)
+		}
+	}
+
+	// randomized
+	sealed1, _ := SealRSA(&priv.PublicKey, "secret")
+	sealed2, _ := SealRSA(&priv.PublicKey, "secret")
+	if sealed1 == sealed2 {
+		t.Errorf("SealRSA is deterministic")
+	}
+
+	// tampered payload
+	ciphertext, _ := decodeCipherText(sealed1)
+	ciphertext[len(ciphertext)-1] ^= 1
+	if _, err := OpenRSA(priv, DefaultStringCodec.EncodeToString(ciphertext)); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("OpenRSA(tampered) error = %v, want %v", err, ErrAuthenticationFailed)
+	}
+
+	if _, err := OpenRSA(priv, DefaultStringCodec.EncodeToString(ciphertext[:100])); !errors.Is(err, ErrCipherTextTooShort) {
+		t.Errorf("OpenRSA(truncated) error = %v, want %v", err, ErrCipherTextTooShort)
+	}
+
+	if _, err := SealRSA(nil, "secret"); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("SealRSA(nil) error = %v, want %v", err, ErrInvalidConfig)
+	}
+}
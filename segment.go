@@ -0,0 +1,297 @@
+package simplecipher
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// This file implements the encryption of large inputs into independently
+// encrypted segments of a fixed size, for random-access and parallel
+// decryption.
+//
+// The output is laid out as:
+//
+//	header: version (1 byte) | segment size (4 bytes, big-endian) | file id (16 bytes)
+//	segments: GCM sealed segment (segment size + 16 bytes, the last one may be shorter)
+//
+// The i-th segment is sealed with a subkey derived from the key, the random
+// file id and i via HKDF, and a zero nonce (every subkey is unique).
+// The header and a final flag are authenticated as the additional data, so
+// that reordered, dropped or truncated segments fail the authentication.
+
+const (
+	// segmentVersion1 is the current version of the segments format.
+	segmentVersion1 byte = 1
+	// segmentFileIDSize is the size of the random file id.
+	segmentFileIDSize = 16
+	// segmentHeaderSize is the size of the header.
+	segmentHeaderSize = 1 + 4 + segmentFileIDSize
+	// hkdfInfoSegment is the HKDF info label prefix of the segment subkeys.
+	hkdfInfoSegment = "simplecipher segment"
+)
+
+// MaxSegmentSize is the maximum segment size of [EncryptSegments].
+//
+// The segment size is read from the (not yet authenticated) header to
+// allocate the buffers of the decryption, so it is bounded to keep a forged
+// header from exhausting the memory.
+const MaxSegmentSize = 64 << 20
+
+// segmentHeader is the parsed header of the segments format.
+type segmentHeader struct {
+	raw         []byte
+	segmentSize int
+	fileID      []byte
+}
+
+// parseSegmentHeader parses and checks the header.
+func parseSegmentHeader(raw []byte) (*segmentHeader, error) {
+	if len(raw) < segmentHeaderSize {
+		return nil, ErrCipherTextTooShort
+	}
+	if raw[0] != segmentVersion1 {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, raw[0])
+	}
+	segmentSize := int(binary.BigEndian.Uint32(raw[1:5]))
+	if segmentSize <= 0 || segmentSize > MaxSegmentSize {
+		return nil, fmt.Errorf("%w: segment size %d not in [1, %d]", ErrMalformedCiphertext, segmentSize, MaxSegmentSize)
+	}
+	return &segmentHeader{
+		raw:         raw[:segmentHeaderSize],
+		segmentSize: segmentSize,
+		fileID:      raw[5:segmentHeaderSize],
+	}, nil
+}
+
+// segmentSealer seals and opens the segments of a file.
+type segmentSealer struct {
+	key    Key // the materialized key
+	header *segmentHeader
+}
+
+// seal encrypts the i-th segment, appending it to dst.
+func (s *segmentSealer) seal(dst []byte, i uint64, plaintext []byte, final bool) ([]byte, error) {
+	aesgcm, err := s.aead(i)
+	if err != nil {
+		return nil, err
+	}
+	return aesgcm.Seal(dst, make([]byte, aesgcm.NonceSize()), plaintext, s.additionalData(final)), nil
+}
+
+// open decrypts the i-th segment.
+func (s *segmentSealer) open(i uint64, ciphertext []byte, final bool) ([]byte, error) {
+	aesgcm, err := s.aead(i)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aesgcm.Open(nil, make([]byte, aesgcm.NonceSize()), ciphertext, s.additionalData(final))
+	if err != nil {
+		return nil, fmt.Errorf("%w: segment %d: %w", ErrAuthenticationFailed, i, err)
+	}
+	return plaintext, nil
+}
+
+// aead returns the AES-GCM of the i-th segment, with a subkey derived from
+// the key, the file id and i.
+func (s *segmentSealer) aead(i uint64) (cipher.AEAD, error) {
+	info := append([]byte(hkdfInfoSegment), s.header.fileID...)
+	info = binary.BigEndian.AppendUint64(info, i)
+	subkey := hkdfKey{Secret: s.key, Info: string(info), Len: Aes256}.Bytes()
+
+	block, err := aes.NewCipher(subkey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNewAesCipher, err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// additionalData returns the header followed by the final flag.
+func (s *segmentSealer) additionalData(final bool) []byte {
+	ad := append([]byte(nil), s.header.raw...)
+	if final {
+		return append(ad, 1)
+	}
+	return append(ad, 0)
+}
+
+// EncryptSegments reads the plaintext from src, and writes it to dst
+// split into segments of segmentSize bytes, each encrypted independently
+// with AES-GCM under a subkey derived from the key:
+//
+//	err := simplecipher.EncryptSegments(key, file, 1<<20, out) // 1 MiB segments
+//
+// The segmentSize must be in [1, [MaxSegmentSize]] (64 MiB).
+// As the ciphertext segments have a fixed size (segmentSize + 16 bytes, but
+// the last one), any segment can be located and decrypted alone, e.g., by
+// concurrent [DecryptSegmentAt] calls. Use [DecryptSegments] to decrypt all
+// of them sequentially.
+//
+// The key (any length, e.g., from [NewAesKey] or [KeyFromEnv]) is read
+// only once.
+func EncryptSegments(key Key, src io.Reader, segmentSize int, dst io.Writer) (err error) {
+	defer wrapOpError(&err, encrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	if segmentSize <= 0 || segmentSize > MaxSegmentSize {
+		return fmt.Errorf("%w: segment size %d not in [1, %d]", ErrInvalidConfig, segmentSize, MaxSegmentSize)
+	}
+
+	fileID, err := randomBytes(segmentFileIDSize)
+	if err != nil {
+		return err
+	}
+
+	raw := []byte{segmentVersion1}
+	raw = binary.BigEndian.AppendUint32(raw, uint32(segmentSize))
+	raw = append(raw, fileID...)
+	header, err := parseSegmentHeader(raw)
+	if err != nil {
+		return err
+	}
+
+	if _, err := dst.Write(raw); err != nil {
+		return fmt.Errorf("%w: %w", ErrCopy, err)
+	}
+
+	sealer := &segmentSealer{key: MaterializeKey(key), header: header}
+
+	// read one segment ahead to tell the final one
+	cur := make([]byte, segmentSize)
+	next := make([]byte, segmentSize)
+	n, err := readSegment(src, cur)
+	if err != nil {
+		return err
+	}
+
+	var out []byte
+	for i := uint64(0); ; i++ {
+		m := 0
+		if n == segmentSize {
+			if m, err = readSegment(src, next); err != nil {
+				return err
+			}
+		}
+		final := m == 0
+
+		if out, err = sealer.seal(out[:0], i, cur[:n], final); err != nil {
+			return err
+		}
+		if _, err := dst.Write(out); err != nil {
+			return fmt.Errorf("%w: %w", ErrCopy, err)
+		}
+
+		if final {
+			return nil
+		}
+		cur, next, n = next, cur, m
+	}
+}
+
+// readSegment reads a full segment, or what is left, into buf.
+func readSegment(src io.Reader, buf []byte) (int, error) {
+	n, err := io.ReadFull(src, buf)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return n, fmt.Errorf("%w: %w", ErrCopy, err)
+	}
+	return n, nil
+}
+
+// DecryptSegments reads the segments written by [EncryptSegments] from src,
+// and writes the plaintext of each segment to dst after verifying it.
+//
+// A tampered, reordered, dropped or truncated segment fails with an error
+// wrapping [ErrAuthenticationFailed], naming the segment. As with
+// [NewRecordStream], the plaintext of the segments before it has already
+// been written then, and the error also wraps [ErrPartialPlaintext].
+func DecryptSegments(key Key, src io.Reader, dst io.Writer) (err error) {
+	defer wrapOpError(&err, decrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	raw := make([]byte, segmentHeaderSize)
+	if _, err := io.ReadFull(src, raw); err != nil {
+		return fmt.Errorf("%w: %w", ErrCipherTextTooShort, err)
+	}
+	header, err := parseSegmentHeader(raw)
+	if err != nil {
+		return err
+	}
+
+	sealer := &segmentSealer{key: MaterializeKey(key), header: header}
+
+	r := bufio.NewReader(src)
+	buf := make([]byte, header.segmentSize+gcmTagSize)
+	written := 0
+
+	for i := uint64(0); ; i++ {
+		n, err := readSegment(r, buf)
+		if err != nil {
+			return err
+		}
+		final := n < len(buf)
+		if !final {
+			_, err := r.Peek(1)
+			final = errors.Is(err, io.EOF)
+		}
+
+		plaintext, err := sealer.open(i, buf[:n], final)
+		if err != nil {
+			if written > 0 {
+				return fmt.Errorf("%w (%d bytes): %w", ErrPartialPlaintext, written, err)
+			}
+			return err
+		}
+
+		if _, err := dst.Write(plaintext); err != nil {
+			return fmt.Errorf("%w: %w", ErrCopy, err)
+		}
+		written += len(plaintext)
+
+		if final {
+			return nil
+		}
+	}
+}
+
+// DecryptSegmentAt decrypts the index-th segment (from 0) of the output of
+// [EncryptSegments] of size bytes, read from src, e.g., an [os.File].
+//
+// Segments are verified independently, so any segment can be decrypted
+// without reading the others, and concurrently. A tampered segment fails
+// with an error wrapping [ErrAuthenticationFailed], and an index out of
+// range with an error wrapping [ErrInvalidConfig].
+func DecryptSegmentAt(key Key, src io.ReaderAt, size int64, index int) (plaintext []byte, err error) {
+	defer wrapOpError(&err, decrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	raw := make([]byte, segmentHeaderSize)
+	if _, err := src.ReadAt(raw, 0); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCipherTextTooShort, err)
+	}
+	header, err := parseSegmentHeader(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	sealedSize := int64(header.segmentSize + gcmTagSize)
+	count := (size - segmentHeaderSize + sealedSize - 1) / sealedSize
+	if count < 1 {
+		return nil, ErrCipherTextTooShort
+	}
+	if index < 0 || int64(index) >= count {
+		return nil, fmt.Errorf("%w: segment %d out of %d", ErrInvalidConfig, index, count)
+	}
+
+	offset := segmentHeaderSize + int64(index)*sealedSize
+	buf := make([]byte, min(sealedSize, size-offset))
+	if _, err := src.ReadAt(buf, offset); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: %w", ErrCopy, err)
+	}
+
+	sealer := &segmentSealer{key: MaterializeKey(key), header: header}
+	return sealer.open(uint64(index), buf, int64(index) == count-1)
+}
@@ -0,0 +1,157 @@
+package simplecipher
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestEncryptSegments(t *testing.T) {
+	key := Bytes([]byte("key0key1key2key3"))
+
+	plaintext := make([]byte, 1000)
+	_, _ = rand.Read(plaintext)
+
+	tests := []struct {
+		name        string
+		size        int
+		segmentSize int
+		segments    int
+	}{
+		{"empty", 0, 64, 1},
+		{"single", 10, 64, 1},
+		{"exact", 256, 64, 4},
+		{"multiple", 1000, 64, 16},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ciphertext bytes.Buffer
+			if err := EncryptSegments(key, bytes.NewReader(plaintext[:tt.size]), tt.segmentSize, &ciphertext); err != nil {
+				t.Fatalf("EncryptSegments() error = %v", err)
+			}
+			if want := segmentHeaderSize + tt.size + tt.segments*gcmTagSize; ciphertext.Len() != want {
+				t.Errorf("EncryptSegments() wrote %d bytes, want %d", ciphertext.Len(), want)
+			}
+
+			var decrypted bytes.Buffer
+			if err := DecryptSegments(key, bytes.NewReader(ciphertext.Bytes()), &decrypted); err != nil {
+				t.Fatalf("DecryptSegments() error = %v", err)
+			}
+			if !bytes.Equal(decrypted.Bytes(), plaintext[:tt.size]) {
+				t.Errorf("DecryptSegments() = %x, want %x", decrypted.Bytes(), plaintext[:tt.size])
+			}
+
+			// random access, in reverse order
+			src := bytes.NewReader(ciphertext.Bytes())
+			var joined []byte
+			for i := tt.segments - 1; i >= 0; i-- {
+				segment, err := DecryptSegmentAt(key, src, src.Size(), i)
+				if err != nil {
+					t.Fatalf("DecryptSegmentAt(%d) error = %v", i, err)
+				}
+				joined = append(segment, joined...)
+			}
+			if !bytes.Equal(joined, plaintext[:tt.size]) {
+				t.Errorf("DecryptSegmentAt() = %x, want %x", joined, plaintext[:tt.size])
+			}
+
+			if _, err := DecryptSegmentAt(key, src, src.Size(), tt.segments); !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("DecryptSegmentAt(out of range) error = %v, want %v", err, ErrInvalidConfig)
+			}
+		})
+	}
+}
+
+func TestEncryptSegments_corrupted(t *testing.T) {
+	key := Bytes([]byte("key0key1key2key3"))
+	plaintext := bytes.Repeat([]byte("0123456789"), 100)
+
+	var buf bytes.Buffer
+	if err := EncryptSegments(key, bytes.NewReader(plaintext), 100, &buf); err != nil {
+		t.Fatalf("EncryptSegments() error = %v", err)
+	}
+	ciphertext := buf.Bytes()
+
+	// corrupt the 3rd segment
+	corrupted := bytes.Clone(ciphertext)
+	corrupted[segmentHeaderSize+2*(100+gcmTagSize)+5] ^= 1
+
+	src := bytes.NewReader(corrupted)
+	for i := 0; i < 10; i++ {
+		segment, err := DecryptSegmentAt(key, src, src.Size(), i)
+		if i == 2 {
+			if !errors.Is(err, ErrAuthenticationFailed) {
+				t.Errorf("DecryptSegmentAt(corrupted) error = %v, want %v", err, ErrAuthenticationFailed)
+			}
+			continue
+		}
+		if err != nil || !bytes.Equal(segment, plaintext[i*100:(i+1)*100]) {
+			t.Errorf("DecryptSegmentAt(%d) = %q, %v, want intact", i, segment, err)
+		}
+	}
+
+	var decrypted bytes.Buffer
+	err := DecryptSegments(key, bytes.NewReader(corrupted), &decrypted)
+	if !errors.Is(err, ErrAuthenticationFailed) || !errors.Is(err, ErrPartialPlaintext) {
+		t.Errorf("DecryptSegments(corrupted) error = %v, want %v and %v", err, ErrAuthenticationFailed, ErrPartialPlaintext)
+	}
+	if decrypted.Len() != 200 {
+		t.Errorf("DecryptSegments(corrupted) wrote %d bytes, want the 2 segments before", decrypted.Len())
+	}
+
+	// reordered, truncated and wrong key
+	swapped := bytes.Clone(ciphertext)
+	first := segmentHeaderSize
+	second := segmentHeaderSize + 100 + gcmTagSize
+	copy(swapped[first:second], ciphertext[second:second+100+gcmTagSize])
+	copy(swapped[second:second+100+gcmTagSize], ciphertext[first:second])
+
+	invalid := map[string]struct {
+		key        Key
+		ciphertext []byte
+	}{
+		"reordered":        {key, swapped},
+		"truncated":        {key, ciphertext[:second]},
+		"truncatedPartial": {key, ciphertext[:second-1]},
+		"headerOnly":       {key, ciphertext[:segmentHeaderSize]},
+		"wrongKey":         {Bytes([]byte("key0key1key2key4")), ciphertext},
+	}
+	for name, tt := range invalid {
+		err := DecryptSegments(tt.key, bytes.NewReader(tt.ciphertext), &bytes.Buffer{})
+		if !errors.Is(err, ErrAuthenticationFailed) {
+			t.Errorf("DecryptSegments(%s) error = %v, want %v", name, err, ErrAuthenticationFailed)
+		}
+	}
+
+	bumped := bytes.Clone(ciphertext)
+	bumped[0]++
+	if err := DecryptSegments(key, bytes.NewReader(bumped), &bytes.Buffer{}); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("DecryptSegments(version 2) error = %v, want %v", err, ErrUnsupportedVersion)
+	}
+}
+
+func TestEncryptSegments_segmentSize(t *testing.T) {
+	key := Bytes([]byte("key0key1key2key3"))
+
+	for _, size := range []int{0, -1, MaxSegmentSize + 1} {
+		if err := EncryptSegments(key, bytes.NewReader([]byte("plaintext")), size, &bytes.Buffer{}); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("EncryptSegments(segment size %d) error = %v, want %v", size, err, ErrInvalidConfig)
+		}
+	}
+
+	// a forged header claiming 4 GiB segments must not be allocated
+	forged := make([]byte, segmentHeaderSize)
+	forged[0] = segmentVersion1
+	binary.BigEndian.PutUint32(forged[1:5], math.MaxUint32)
+
+	if err := DecryptSegments(key, bytes.NewReader(forged), &bytes.Buffer{}); !errors.Is(err, ErrMalformedCiphertext) {
+		t.Errorf("DecryptSegments(forged header) error = %v, want %v", err, ErrMalformedCiphertext)
+	}
+	src := bytes.NewReader(forged)
+	if _, err := DecryptSegmentAt(key, src, src.Size(), 0); !errors.Is(err, ErrMalformedCiphertext) {
+		t.Errorf("DecryptSegmentAt(forged header) error = %v, want %v", err, ErrMalformedCiphertext)
+	}
+}
@@ -0,0 +1,122 @@
+package simplecipher
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// This file implements the encryption with an AEAD chosen by the size of
+// the plaintext.
+//
+// The output is laid out as (before the encoding):
+//
+//	algorithm (1 byte) | ciphertext of the algorithm
+//
+// where the algorithm is one of:
+//
+//	1: GCM, with a random nonce: nonce (12 bytes) | ciphertext | tag
+//	2: per-record GCM stream of [NewRecordStream] with DefaultMaxRecord
+const (
+	smartGCM     byte = 1
+	smartRecords byte = 2
+)
+
+// DefaultSmartThreshold is the plaintext size in bytes up to which
+// [SmartEncrypt] uses GCM by default. See [WithSmartThreshold].
+const DefaultSmartThreshold = 1 << 20 // 1 MiB
+
+// SmartOption is a functional option of [SmartEncrypt].
+type SmartOption func(opts *smartOptions)
+
+// smartOptions are the options of [SmartEncrypt].
+type smartOptions struct {
+	threshold int
+}
+
+// WithSmartThreshold sets the plaintext size in bytes up to which
+// [SmartEncrypt] uses GCM, instead of [DefaultSmartThreshold]. Larger
+// plaintexts are encrypted by the per-record GCM stream of [NewRecordStream].
+//
+// [SmartDecrypt] needs no option: it reads the algorithm from the
+// ciphertext, so the threshold can differ from one call to another.
+func WithSmartThreshold(n int) SmartOption {
+	return func(opts *smartOptions) {
+		opts.threshold = n
+	}
+}
+
+// SmartEncrypt encrypts the plaintext with an AEAD chosen by its size:
+// GCM for plaintexts up to [DefaultSmartThreshold] bytes (see
+// [WithSmartThreshold]), and the per-record GCM stream of [NewRecordStream]
+// for larger ones, whose records are authenticated one by one, within the
+// limits of GCM.
+//
+// The choice is recorded in the first byte of the ciphertext, so that
+// [SmartDecrypt] handles both. The ciphertext is returned with
+// [DefaultStringCodec] encoding.
+//
+// The key must be 16, 24, or 32 bytes long to select AES-128, AES-192, or AES-256.
+func SmartEncrypt(key Key, plain string, options ...SmartOption) (cipherText string, err error) {
+	defer wrapOpError(&err, encrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	opts := smartOptions{threshold: DefaultSmartThreshold}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	key = MaterializeKey(key)
+
+	if len(plain) <= opts.threshold {
+		gcm := NewGCMWithConfig(GCMConfig{Key: key, RandomNonce: true}).(AppendCipher)
+		ciphertext, err := gcm.EncryptAppend([]byte{smartGCM}, []byte(plain))
+		if err != nil {
+			return "", err
+		}
+		return DefaultStringCodec.EncodeToString(ciphertext), nil
+	}
+
+	buf := bytes.NewBuffer([]byte{smartRecords})
+	if err := NewRecordStream(key, DefaultMaxRecord).EncryptStream(strings.NewReader(plain), buf); err != nil {
+		return "", err
+	}
+	return DefaultStringCodec.EncodeToString(buf.Bytes()), nil
+}
+
+// SmartDecrypt decrypts the ciphertext of [SmartEncrypt], with the
+// algorithm recorded in it.
+//
+// An unknown algorithm fails with an error wrapping [ErrUnsupportedVersion].
+func SmartDecrypt(key Key, cipherText string) (plain string, err error) {
+	defer wrapOpError(&err, decrypt, ModeGCM)
+	defer recoverFromPanic(&err)
+
+	ciphertext, err := decodeCipherText(cipherText)
+	if err != nil {
+		return "", err
+	}
+	if len(ciphertext) < 1 {
+		return "", ErrCipherTextTooShort
+	}
+
+	key = MaterializeKey(key)
+
+	switch algorithm, ciphertext := ciphertext[0], ciphertext[1:]; algorithm {
+	case smartGCM:
+		gcm := NewGCMWithConfig(GCMConfig{Key: key, RandomNonce: true}).(AppendCipher)
+		plaintext, err := gcm.DecryptAppend(nil, ciphertext)
+		if err != nil {
+			return "", err
+		}
+		return string(plaintext), nil
+	case smartRecords:
+		buf := new(strings.Builder)
+		if err := NewRecordStream(key, DefaultMaxRecord).DecryptStream(bytes.NewReader(ciphertext), buf); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	default:
+		return "", fmt.Errorf("%w: smart algorithm %d", ErrUnsupportedVersion, algorithm)
+	}
+}
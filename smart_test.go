@@ -0,0 +1,63 @@
+package simplecipher
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSmartEncrypt(t *testing.T) {
+	key := Bytes([]byte("key0key1key2key3key4key5key6key7"))
+
+	plaintexts := []struct {
+		name      string
+		plaintext string
+		algorithm byte
+	}{
+		{"empty", "", smartGCM},
+		{"small", "plaintext", smartGCM},
+		{"threshold", strings.Repeat("p", 1<<10), smartGCM},
+		{"large", strings.Repeat("p", 1<<10+1), smartRecords},
+		{"multiRecord", strings.Repeat("plaintext", DefaultMaxRecord), smartRecords},
+	}
+
+	for _, tt := range plaintexts {
+		t.Run(tt.name, func(t *testing.T) {
+			ciphertext, err := SmartEncrypt(key, tt.plaintext, WithSmartThreshold(1<<10))
+			if err != nil {
+				t.Fatalf("SmartEncrypt error: %v", err)
+			}
+
+			raw, _ := DefaultStringCodec.DecodeString(ciphertext)
+			if raw[0] != tt.algorithm {
+				t.Errorf("algorithm = %d, want %d", raw[0], tt.algorithm)
+			}
+
+			decrypted, err := SmartDecrypt(key, ciphertext)
+			if err != nil {
+				t.Fatalf("SmartDecrypt error: %v", err)
+			}
+			if decrypted != tt.plaintext {
+				t.Errorf("SmartDecrypt() = %d bytes, want %d bytes", len(decrypted), len(tt.plaintext))
+			}
+
+			raw[len(raw)-1] ^= 1
+			if _, err := SmartDecrypt(key, DefaultStringCodec.EncodeToString(raw)); !errors.Is(err, ErrAuthenticationFailed) {
+				t.Errorf("SmartDecrypt(tampered) error = %v, want %v", err, ErrAuthenticationFailed)
+			}
+		})
+	}
+
+	// the default threshold
+	ciphertext, err := SmartEncrypt(key, strings.Repeat("p", 1<<10+1))
+	if err != nil {
+		t.Fatalf("SmartEncrypt error: %v", err)
+	}
+	if raw, _ := DefaultStringCodec.DecodeString(ciphertext); raw[0] != smartGCM {
+		t.Errorf("default algorithm = %d, want %d", raw[0], smartGCM)
+	}
+
+	if _, err := SmartDecrypt(key, DefaultStringCodec.EncodeToString([]byte{9, 0, 0})); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("SmartDecrypt(unknown algorithm) error = %v, want %v", err, ErrUnsupportedVersion)
+	}
+}
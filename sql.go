@@ -0,0 +1,84 @@
+package simplecipher
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+)
+
+// This file provides a string type that is encrypted when written to
+// a SQL database, and decrypted when read from it.
+
+// EncryptedString is a nullable string stored encrypted in a SQL database.
+// It implements [driver.Valuer] to encrypt the String on write, and
+// [sql.Scanner] to decrypt the column on read, with the Cipher:
+//
+//	email := simplecipher.NewEncryptedString(cipher, "alice@example.com")
+//	db.Exec("INSERT INTO users (email) VALUES (?)", email)
+//
+//	got := simplecipher.EncryptedString{Cipher: cipher}
+//	db.QueryRow("SELECT email FROM users").Scan(&got)
+//
+// Like [sql.NullString], Valid is false for a NULL, which is stored as is.
+// Set the Cipher before scanning, otherwise Scan fails.
+//
+// The column must be a text (or binary) column large enough for the
+// [DefaultStringCodec] encoded ciphertext.
+type EncryptedString struct {
+	// Cipher encrypts and decrypts the String.
+	Cipher Cipher
+	// String is the plaintext.
+	String string
+	// Valid is true if String is not NULL.
+	Valid bool
+}
+
+var (
+	_ driver.Valuer = EncryptedString{}
+	_ sql.Scanner   = (*EncryptedString)(nil)
+)
+
+// NewEncryptedString returns a valid (not NULL) EncryptedString of the
+// plaintext s, encrypted with the cipher.
+func NewEncryptedString(c Cipher, s string) EncryptedString {
+	return EncryptedString{Cipher: c, String: s, Valid: true}
+}
+
+// Value encrypts the String, or returns nil for a NULL.
+func (s EncryptedString) Value() (driver.Value, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	if s.Cipher == nil {
+		return nil, fmt.Errorf("%w: EncryptedString without a Cipher", ErrInvalidConfig)
+	}
+	return s.Cipher.Encrypt(s.String)
+}
+
+// Scan decrypts the value of a text or binary column into the String,
+// or sets Valid to false for a NULL.
+func (s *EncryptedString) Scan(src any) error {
+	var cipherText string
+	switch v := src.(type) {
+	case nil:
+		s.String, s.Valid = "", false
+		return nil
+	case string:
+		cipherText = v
+	case []byte:
+		cipherText = string(v)
+	default:
+		return fmt.Errorf("%w: can not scan %T into an EncryptedString", ErrUnsupportedType, src)
+	}
+
+	if s.Cipher == nil {
+		return fmt.Errorf("%w: EncryptedString without a Cipher", ErrInvalidConfig)
+	}
+
+	plainText, err := s.Cipher.Decrypt(cipherText)
+	if err != nil {
+		return err
+	}
+	s.String, s.Valid = plainText, true
+	return nil
+}
@@ -0,0 +1,56 @@
+package simplecipher
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+func TestEncryptedString(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	c := SimpleGCM("key", "nonce")
+
+	value, err := NewEncryptedString(c, "alice@example.com").Value()
+	if err != nil {
+		t.Fatalf("Value error: %v", err)
+	}
+	stored, ok := value.(string)
+	if !ok || stored == "alice@example.com" {
+		t.Fatalf("Value() = %#v, want an encrypted string", value)
+	}
+	if !driver.IsValue(value) {
+		t.Errorf("Value() = %T, want a driver.Value", value)
+	}
+
+	// drivers may return text columns as string or []byte
+	for _, src := range []any{stored, []byte(stored)} {
+		got := EncryptedString{Cipher: c}
+		if err := got.Scan(src); err != nil {
+			t.Fatalf("Scan(%T) error: %v", src, err)
+		}
+		if !got.Valid || got.String != "alice@example.com" {
+			t.Errorf("Scan(%T) = %+v, want the plaintext", src, got)
+		}
+	}
+
+	// NULL
+	value, err = EncryptedString{Cipher: c}.Value()
+	if err != nil || value != nil {
+		t.Errorf("Value() of NULL = %#v, %v, want nil", value, err)
+	}
+	got := NewEncryptedString(c, "previous")
+	if err := got.Scan(nil); err != nil || got.Valid || got.String != "" {
+		t.Errorf("Scan(nil) = %+v, %v, want NULL", got, err)
+	}
+
+	if err := got.Scan(42); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("Scan(42) error = %v, want %v", err, ErrUnsupportedType)
+	}
+	if err := (&EncryptedString{}).Scan(stored); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Scan() without a Cipher error = %v, want %v", err, ErrInvalidConfig)
+	}
+	if err := (&EncryptedString{Cipher: c}).Scan("tampered"); err == nil {
+		t.Errorf("Scan(tampered) error = nil, want an error")
+	}
+}
@@ -1,8 +1,9 @@
 package simplecipher
 
 import (
-	"crypto/aes"
+	"bytes"
 	"crypto/cipher"
+	"crypto/sha256"
 	"fmt"
 	"io"
 )
@@ -27,6 +28,27 @@ type steam struct {
 	key          Key
 	iv           Key
 	cipherStream cipherStreamBuilder
+	mode         ModeID
+	// newBlock creates the underlying block cipher, nil for AES.
+	newBlock blockBuilder
+	// algorithm is the name of the block cipher, "" for AES.
+	algorithm string
+	// rand is the source of a random iv per encryption set by WithRand,
+	// nil for using iv.
+	rand io.Reader
+	// nilRand tells WithRand is applied with a nil reader.
+	nilRand bool
+}
+
+// block creates the underlying block cipher from the key.
+func (s *steam) block(key []byte) (cipher.Block, error) {
+	if len(key) == 0 {
+		return nil, ErrEmptyKey
+	}
+	if s.newBlock == nil {
+		return newAesBlock(key)
+	}
+	return s.newBlock(key)
 }
 
 var _ Stream = (*steam)(nil)
@@ -34,12 +56,72 @@ var _ Stream = (*steam)(nil)
 // EncryptStream encrypts the given plaintext using CFB.
 // The ciphertext is written to the given writer without encoding.
 func (s *steam) EncryptStream(plainText io.Reader, cipherText io.Writer) (err error) {
+	defer wrapOpError(&err, encrypt, s.mode)
+	defer recoverFromPanic(&err)
+
+	if err := s.validateRand(); err != nil {
+		return err
+	}
+	if s.rand != nil {
+		return s.encryptStream(nil, plainText, cipherText)
+	}
+
+	return s.encryptStream(s.iv.Bytes(), plainText, cipherText)
+}
+
+// IVStream is a [Stream] that can encrypt with an iv supplied per call,
+// e.g., for resumable uploads where a retry must reproduce the same
+// ciphertext.
+//
+// The CFB, OFB and CTR streams created by this package implement IVStream:
+//
+//	s := simplecipher.SimpleCTRStream("key").(simplecipher.IVStream)
+//	err := s.EncryptStreamWithIV(iv, plaintext, ciphertext)
+type IVStream interface {
+	Stream
+	// EncryptStreamWithIV encrypts the plaintext like EncryptStream,
+	// but with the given iv instead of the configured one.
+	// The iv is prepended to the ciphertext as usual, so the output can
+	// be decrypted by DecryptStream.
+	//
+	// Never reuse an iv with the same key for different plaintexts.
+	EncryptStreamWithIV(iv Key, plainText io.Reader, cipherText io.Writer) error
+}
+
+var _ IVStream = (*steam)(nil)
+
+// EncryptStreamWithIV encrypts the given plaintext with the given iv.
+// It returns an error wrapping [ErrIvSize] if the iv is not
+// [aes.BlockSize] bytes long.
+func (s *steam) EncryptStreamWithIV(iv Key, plainText io.Reader, cipherText io.Writer) (err error) {
+	defer wrapOpError(&err, encrypt, s.mode)
 	defer recoverFromPanic(&err)
 
+	if err := validateIv(iv); err != nil {
+		return err
+	}
+
+	return s.encryptStream(iv.Bytes(), plainText, cipherText)
+}
+
+// encryptStream encrypts the plaintext with the iv, and writes the iv
+// followed by the ciphertext. A nil iv is read from rand.
+func (s *steam) encryptStream(iv []byte, plainText io.Reader, cipherText io.Writer) error {
 	key := s.key.Bytes()
-	iv := s.iv.Bytes()
 
-	stream, err := s.cipherStream(key, iv, encrypt)
+	block, err := s.block(key)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrNewAesCipher, err)
+	}
+
+	if iv == nil {
+		iv, err = randomBytesFrom(s.rand, block.BlockSize())
+		if err != nil {
+			return err
+		}
+	}
+
+	stream, err := s.cipherStream(block, iv, encrypt)
 	if err != nil {
 		return fmt.Errorf("%w: %w", ErrNewAesCipher, err)
 	}
@@ -60,16 +142,22 @@ func (s *steam) EncryptStream(plainText io.Reader, cipherText io.Writer) (err er
 // DecryptStream decrypts the given ciphertext using CFB.
 // The ciphertext read from the given reader should not be encoded.
 func (s *steam) DecryptStream(cipherText io.Reader, plainText io.Writer) (err error) {
+	defer wrapOpError(&err, decrypt, s.mode)
 	defer recoverFromPanic(&err)
 
 	key := s.key.Bytes()
 
-	iv := make([]byte, aes.BlockSize)
+	block, err := s.block(key)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrNewAesCipher, err)
+	}
+
+	iv := make([]byte, block.BlockSize())
 	if _, err := io.ReadFull(cipherText, iv); err != nil {
 		return fmt.Errorf("%w: %w", ErrCopy, err)
 	}
 
-	stream, err := s.cipherStream(key, iv, decrypt)
+	stream, err := s.cipherStream(block, iv, decrypt)
 	if err != nil {
 		return fmt.Errorf("%w: %w", ErrNewAesCipher, err)
 	}
@@ -82,18 +170,51 @@ func (s *steam) DecryptStream(cipherText io.Reader, plainText io.Writer) (err er
 	return nil
 }
 
-//////// CFB, OFB, CTR ////////
+// Mode returns the cipher mode of the stream: CFB, OFB, or CTR.
+func (s *steam) Mode() ModeID {
+	return s.mode
+}
 
-// cipherStreamBuilder is a function that creates a new [cipher.Stream].
-// Available implementations are cfbStreamBuilder, ofbStreamBuilder, and ctrStreamBuilder.
-type cipherStreamBuilder func(key []byte, iv []byte, encryptOrDecrypt encryptOrDecrypt) (cipher.Stream, error)
+// Describe describes the stream cipher, e.g., AES-256-CTR.
+func (s *steam) Describe() CipherInfo {
+	algorithm := s.algorithm
+	if algorithm == "" {
+		algorithm = algorithmAES
+	}
+	return CipherInfo{Algorithm: algorithm, Mode: s.mode, KeyBits: keyBits(s.key)}
+}
 
-func cfbStreamBuilder(key []byte, iv []byte, encryptOrDecrypt encryptOrDecrypt) (cipher.Stream, error) {
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, err
+// Validate checks the lengths of the key and the iv,
+// and the reader of [WithRand].
+func (s *steam) Validate() error {
+	if err := s.validateRand(); err != nil {
+		return err
+	}
+	if s.newBlock != nil {
+		return validateBlockKeyIv(s.newBlock, s.key, s.iv)
+	}
+	if err := validateAesKey(s.key); err != nil {
+		return err
+	}
+	return validateIv(s.iv)
+}
+
+// validateRand checks that the reader of [WithRand] is not nil.
+func (s *steam) validateRand() error {
+	if s.nilRand {
+		return fmt.Errorf("%w: nil rand reader", ErrInvalidConfig)
 	}
+	return nil
+}
+
+//////// CFB, OFB, CTR ////////
 
+// cipherStreamBuilder is a function that creates a new [cipher.Stream]
+// from the block cipher.
+// Available implementations are cfbStreamBuilder, ofbStreamBuilder, and ctrStreamBuilder.
+type cipherStreamBuilder func(block cipher.Block, iv []byte, encryptOrDecrypt encryptOrDecrypt) (cipher.Stream, error)
+
+func cfbStreamBuilder(block cipher.Block, iv []byte, encryptOrDecrypt encryptOrDecrypt) (cipher.Stream, error) {
 	switch encryptOrDecrypt {
 	case encrypt:
 		return cipher.NewCFBEncrypter(block, iv), nil
@@ -104,31 +225,96 @@ func cfbStreamBuilder(key []byte, iv []byte, encryptOrDecrypt encryptOrDecrypt)
 	}
 }
 
-func ofbStreamBuilder(key []byte, iv []byte, _ encryptOrDecrypt) (cipher.Stream, error) {
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, err
-	}
+func ofbStreamBuilder(block cipher.Block, iv []byte, _ encryptOrDecrypt) (cipher.Stream, error) {
 	return cipher.NewOFB(block, iv), nil
 }
 
-func ctrStreamBuilder(key []byte, iv []byte, _ encryptOrDecrypt) (cipher.Stream, error) {
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, err
-	}
+func ctrStreamBuilder(block cipher.Block, iv []byte, _ encryptOrDecrypt) (cipher.Stream, error) {
 	return cipher.NewCTR(block, iv), nil
 }
 
 // encryptOrDecrypt is an enum to indicate the operation of the cipher.
-// For CFB only, which uses different [cipher.Stream] implementations for encryption and decryption.
+// For CFB, which uses different [cipher.Stream] implementations for encryption and decryption,
+// and for the context of the returned errors (see wrapOpError).
 type encryptOrDecrypt int
 
 const (
 	encrypt encryptOrDecrypt = iota
 	decrypt
+	// loadKey is the operation of loading a key, e.g., by [KeyFromEnv],
+	// for the context of the returned errors only.
+	loadKey
 )
 
+func (op encryptOrDecrypt) String() string {
+	switch op {
+	case decrypt:
+		return "decrypt"
+	case loadKey:
+		return "load key"
+	}
+	return "encrypt"
+}
+
+//////// Options ////////
+
+// StreamOption is a functional option to customize the Simple*Stream ciphers.
+type StreamOption func(s *steam)
+
+// WithIV pins the iv of the stream cipher, instead of a random one.
+//
+// Use it for reproducible output or interop with other tools.
+// Notice that reusing an iv with the same key is insecure for all the
+// stream modes: never encrypt different plaintexts with a pinned iv.
+//
+// The iv must be [aes.BlockSize] bytes long.
+func WithIV(iv Key) StreamOption {
+	return func(s *steam) {
+		s.iv = iv
+	}
+}
+
+// WithRand sets the source of the ivs of the stream cipher,
+// instead of [crypto/rand.Reader]: a new iv is read from r for each
+// EncryptStream, e.g., a deterministic reader to reproduce the
+// ciphertexts in tests. It only affects this stream, not the others.
+//
+// Only the Simple*Stream ciphers and the GCM ciphers (see [GCMConfig].Rand)
+// take a source of randomness. All the other random values always read
+// [crypto/rand.Reader]: [NewRandomIv] (hence the iv of [SimpleCBC]), the
+// salts of [SimpleGCMAutoSalt] and [NewSaltedKey], the keys and nonces of
+// [NewKeyring], [SealMultiRecipient], [SealRSA], [SealBox],
+// [NewRecordStream] and [EncryptSegments], and the prefix of
+// [WithRandomPrefix].
+//
+// Never use a predictable r in production: it repeats the ivs.
+// A nil r makes the stream fail with an error wrapping [ErrInvalidConfig].
+func WithRand(r io.Reader) StreamOption {
+	return func(s *steam) {
+		s.rand = r
+		s.nilRand = r == nil
+	}
+}
+
+// WithKey sets the key of the stream cipher,
+// instead of the one derived from the passphrase.
+//
+// The key must be 16, 24, or 32 bytes long to select AES-128, AES-192, or AES-256.
+func WithKey(key Key) StreamOption {
+	return func(s *steam) {
+		s.key = keyOrEmpty(key)
+	}
+}
+
+// newSimpleStream applies the options to the stream.
+func newSimpleStream(stream Stream, options []StreamOption) Stream {
+	s := stream.(*steam)
+	for _, opt := range options {
+		opt(s)
+	}
+	return s
+}
+
 //////// Exported Constructors ////////
 
 // NewCFBStream creates a new CFB stream cipher with the given key and iv.
@@ -143,7 +329,7 @@ const (
 // Use [SimpleCFBStream] if you are not familiar with these.
 // See also: [cipher.NewCFBDecrypter], [cipher.NewCFBEncrypter] for low-level usage.
 func NewCFBStream(key, iv Key) Stream {
-	return &steam{key: key, iv: iv, cipherStream: cfbStreamBuilder}
+	return &steam{key: keyOrEmpty(key), iv: iv, cipherStream: cfbStreamBuilder, mode: ModeCFB}
 }
 
 // SimpleCFBStream creates a new AES-256-CFB stream cipher from the given key and iv.
@@ -151,11 +337,12 @@ func NewCFBStream(key, iv Key) Stream {
 // An [Aes256] key for encryption/decryption will be derived from the
 // arbitrary keyPassphrase string via scrypt.
 //
-// The iv will be a random value.
+// The iv will be a random value by default.
+// Use [WithIV] to pin the iv, or [WithKey] to use a key instead of the passphrase.
 //
 // See also: [NewCFBStream] for more control.
-func SimpleCFBStream(keyPassphrase string) Stream {
-	return NewCFBStream(NewAesKey(keyPassphrase), NewRandomIv())
+func SimpleCFBStream(keyPassphrase string, options ...StreamOption) Stream {
+	return newSimpleStream(NewCFBStream(NewAesKey(keyPassphrase), NewRandomIv()), options)
 }
 
 // NewOFBStream creates a new OFB stream cipher with the given key and iv.
@@ -170,7 +357,7 @@ func SimpleCFBStream(keyPassphrase string) Stream {
 // Use [SimpleOFBStream] if you are not familiar with these.
 // See also: [cipher.NewOFB] for low-level usage.
 func NewOFBStream(key, iv Key) Stream {
-	return &steam{key: key, iv: iv, cipherStream: ofbStreamBuilder}
+	return &steam{key: keyOrEmpty(key), iv: iv, cipherStream: ofbStreamBuilder, mode: ModeOFB}
 }
 
 // SimpleOFBStream creates a new AES-256-OFB stream cipher from the given key and iv.
@@ -178,11 +365,12 @@ func NewOFBStream(key, iv Key) Stream {
 // An [Aes256] key for encryption/decryption will be derived from the
 // arbitrary keyPassphrase string via scrypt.
 //
-// The iv will be a random value.
+// The iv will be a random value by default.
+// Use [WithIV] to pin the iv, or [WithKey] to use a key instead of the passphrase.
 //
 // See also: [NewOFBStream] for more control.
-func SimpleOFBStream(keyPassphrase string) Stream {
-	return NewOFBStream(NewAesKey(keyPassphrase), NewRandomIv())
+func SimpleOFBStream(keyPassphrase string, options ...StreamOption) Stream {
+	return newSimpleStream(NewOFBStream(NewAesKey(keyPassphrase), NewRandomIv()), options)
 }
 
 // NewCTRStream creates a new CTR stream cipher with the given key and iv.
@@ -197,7 +385,7 @@ func SimpleOFBStream(keyPassphrase string) Stream {
 // Use [SimpleCTRStream] if you are not familiar with these.
 // See also: [cipher.NewCTR] for low-level usage.
 func NewCTRStream(key, iv Key) Stream {
-	return &steam{key: key, iv: iv, cipherStream: ctrStreamBuilder}
+	return &steam{key: keyOrEmpty(key), iv: iv, cipherStream: ctrStreamBuilder, mode: ModeCTR}
 }
 
 // SimpleCTRStream creates a new AES-256-CTR stream cipher from the given key and iv.
@@ -205,9 +393,411 @@ func NewCTRStream(key, iv Key) Stream {
 // An [Aes256] key for encryption/decryption will be derived from the
 // arbitrary keyPassphrase string via scrypt.
 //
-// The iv will be a random value.
+// The iv will be a random value by default.
+// Use [WithIV] to pin the iv, or [WithKey] to use a key instead of the passphrase.
 //
 // See also: [NewCTRStream] for more control.
-func SimpleCTRStream(keyPassphrase string) Stream {
-	return NewCTRStream(NewAesKey(keyPassphrase), NewRandomIv())
+func SimpleCTRStream(keyPassphrase string, options ...StreamOption) Stream {
+	return newSimpleStream(NewCTRStream(NewAesKey(keyPassphrase), NewRandomIv()), options)
+}
+
+//////// CTR with random access ////////
+
+// CTRSeeker is a CTR [Stream] that supports decrypting a sub-range of
+// the ciphertext without processing the preceding bytes.
+//
+// CTR mode supports random access because the keystream of each block
+// only depends on the key, the iv and the index of the block.
+type CTRSeeker interface {
+	Stream
+	// DecryptRange decrypts length bytes of plaintext starting at the
+	// plaintext offset, reading the ciphertext (output of EncryptStream,
+	// with the iv prepended) from src, and writes the plaintext to dst.
+	DecryptRange(src io.ReaderAt, offset, length int64, dst io.Writer) error
+}
+
+// ctrSeeker is the implementation of [CTRSeeker].
+type ctrSeeker struct {
+	steam
+}
+
+var _ CTRSeeker = (*ctrSeeker)(nil)
+
+// NewCTRSeekable creates a new CTR stream cipher with the given key and iv,
+// that supports random access decryption via DecryptRange.
+//
+// The ciphertext format is the same as [NewCTRStream],
+// so the two are interchangeable.
+//
+// It's caller's responsibility to ensure the following:
+//
+//   - The key must be 16, 24, or 32 bytes long to select AES-128, AES-192, or AES-256.
+//   - The IV must be [aes.BlockSize] bytes long.
+func NewCTRSeekable(key, iv Key) CTRSeeker {
+	return &ctrSeeker{steam: steam{key: keyOrEmpty(key), iv: iv, cipherStream: ctrStreamBuilder, mode: ModeCTR}}
+}
+
+// DecryptRange decrypts the plaintext in [offset, offset+length).
+//
+// The iv is read from the first block of src, like DecryptStream does.
+// An error wrapping [ErrCopy] is returned if src is shorter than the range.
+func (c *ctrSeeker) DecryptRange(src io.ReaderAt, offset, length int64, dst io.Writer) (err error) {
+	defer wrapOpError(&err, decrypt, ModeCTR)
+	defer recoverFromPanic(&err)
+
+	if offset < 0 || length < 0 {
+		return fmt.Errorf("invalid range: offset=%d, length=%d", offset, length)
+	}
+
+	key := c.key.Bytes()
+
+	block, err := c.block(key)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrNewAesCipher, err)
+	}
+
+	iv := make([]byte, block.BlockSize())
+	if _, err := src.ReadAt(iv, 0); err != nil {
+		return fmt.Errorf("%w: %w", ErrCopy, err)
+	}
+
+	stream := ctrStreamAt(block, iv, offset)
+
+	reader := &cipher.StreamReader{S: stream, R: io.NewSectionReader(src, int64(len(iv))+offset, length)}
+	if _, err := io.CopyN(dst, reader, length); err != nil {
+		return fmt.Errorf("%w: %w", ErrCopy, err)
+	}
+
+	return nil
+}
+
+// ctrStreamAt creates a CTR [cipher.Stream] whose keystream starts at
+// the given byte offset, by advancing the counter block (the iv) and
+// discarding the keystream bytes before offset inside the block.
+func ctrStreamAt(block cipher.Block, iv []byte, offset int64) cipher.Stream {
+	blockSize := int64(block.BlockSize())
+
+	counter := make([]byte, len(iv))
+	copy(counter, iv)
+
+	// add offset / blockSize to the big-endian counter
+	blocks := uint64(offset / blockSize)
+	for i := len(counter) - 1; i >= 0 && blocks > 0; i-- {
+		sum := uint64(counter[i]) + blocks&0xff
+		counter[i] = byte(sum)
+		blocks = blocks>>8 + sum>>8
+	}
+
+	stream := cipher.NewCTR(block, counter)
+
+	skip := make([]byte, offset%blockSize)
+	stream.XORKeyStream(skip, skip)
+
+	return stream
+}
+
+// ctrReadSeeker is the [io.ReadSeeker] returned by [NewCTRReadSeeker].
+type ctrReadSeeker struct {
+	block  cipher.Block
+	iv     []byte
+	src    io.ReadSeeker
+	base   int64         // offset of the ciphertext in src: the iv prefix length
+	pos    int64         // the current plaintext offset
+	stream cipher.Stream // the keystream at pos, nil after a Seek
+}
+
+// NewCTRReadSeeker returns an [io.ReadSeeker] of the plaintext decrypted
+// on demand from src, a CTR ciphertext. Seek recomputes the counter from
+// the offset, so reads can start anywhere without decrypting what's before:
+//
+//	r, err := simplecipher.NewCTRReadSeeker(key, nil, file)
+//	r.Seek(1<<20, io.SeekStart)
+//	io.ReadFull(r, buf) // plaintext from 1 MiB
+//
+// If iv is nil, src is the output of EncryptStream of [NewCTRStream] (or
+// [NewCTRSeekable]): the iv is read from its prefix, and the offsets
+// are plaintext offsets, i.e., after the prefix. Otherwise, src is the bare
+// ciphertext encrypted with iv, without any prefix.
+//
+// The key must be 16, 24, or 32 bytes long to select AES-128, AES-192, or AES-256.
+// An iv of another length than the block size fails with an error wrapping
+// [ErrIvSize]. Notice that the plaintext is not authenticated.
+func NewCTRReadSeeker(key, iv Key, src io.ReadSeeker) (r io.ReadSeeker, err error) {
+	defer wrapOpError(&err, decrypt, ModeCTR)
+	defer recoverFromPanic(&err)
+
+	block, err := newAesBlock(keyOrEmpty(key).Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNewAesCipher, err)
+	}
+
+	rs := &ctrReadSeeker{block: block, src: src}
+
+	if iv != nil {
+		rs.iv = iv.Bytes()
+		if len(rs.iv) != block.BlockSize() {
+			return nil, fmt.Errorf("%w: got %d bytes, want %d", ErrIvSize, len(rs.iv), block.BlockSize())
+		}
+	} else {
+		rs.iv = make([]byte, block.BlockSize())
+		if _, err := src.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrCopy, err)
+		}
+		if _, err := io.ReadFull(src, rs.iv); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrCopy, err)
+		}
+		rs.base = int64(len(rs.iv))
+	}
+
+	return rs, nil
+}
+
+// Read reads and decrypts the ciphertext at the current offset.
+//
+// io.EOF is returned as it is, as the [io.Reader] contract requires.
+func (r *ctrReadSeeker) Read(p []byte) (n int, err error) {
+	defer func() {
+		if err != io.EOF {
+			wrapOpError(&err, decrypt, ModeCTR)
+		}
+	}()
+
+	if r.stream == nil {
+		if _, err := r.src.Seek(r.base+r.pos, io.SeekStart); err != nil {
+			return 0, fmt.Errorf("%w: %w", ErrCopy, err)
+		}
+		r.stream = ctrStreamAt(r.block, r.iv, r.pos)
+	}
+
+	n, err = r.src.Read(p)
+	r.stream.XORKeyStream(p[:n], p[:n])
+	r.pos += int64(n)
+	return n, err
+}
+
+// Seek sets the plaintext offset of the next Read.
+func (r *ctrReadSeeker) Seek(offset int64, whence int) (pos int64, err error) {
+	defer wrapOpError(&err, decrypt, ModeCTR)
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.pos
+	case io.SeekEnd:
+		end, err := r.src.Seek(0, io.SeekEnd)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %w", ErrCopy, err)
+		}
+		offset += end - r.base
+	default:
+		return 0, fmt.Errorf("invalid whence: %d", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("negative position: %d", offset)
+	}
+
+	r.pos = offset
+	r.stream = nil
+	return offset, nil
+}
+
+//////// Reader ////////
+
+// streamBuilders maps the stream modes to their cipherStreamBuilders.
+var streamBuilders = map[ModeID]cipherStreamBuilder{
+	ModeCFB: cfbStreamBuilder,
+	ModeOFB: ofbStreamBuilder,
+	ModeCTR: ctrStreamBuilder,
+}
+
+// NewDecryptReader returns a reader yielding the plaintext decrypted on
+// demand from src, the ciphertext output by the CFB, OFB, or CTR [Stream]
+// of this package (not encoded).
+//
+// The iv prefix is read and consumed from src before returning,
+// so an error wrapping [ErrCopy] is returned if src is too short.
+// It is the read-side counterpart of EncryptStream, for APIs expecting an
+// [io.Reader] of plaintext:
+//
+//	r, err := simplecipher.NewDecryptReader(key, simplecipher.ModeCTR, file)
+//	gz, err := gzip.NewReader(r)
+//
+// The key must be 16, 24, or 32 bytes long to select AES-128, AES-192, or AES-256.
+// An error wrapping [ErrInvalidConfig] is returned for other modes.
+// Notice that the plaintext is not authenticated.
+func NewDecryptReader(key Key, mode ModeID, src io.Reader) (r io.Reader, err error) {
+	defer wrapOpError(&err, decrypt, mode)
+	defer recoverFromPanic(&err)
+
+	builder, ok := streamBuilders[mode]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported mode %q for a decrypt reader", ErrInvalidConfig, mode)
+	}
+
+	block, err := newAesBlock(keyOrEmpty(key).Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNewAesCipher, err)
+	}
+
+	iv := make([]byte, block.BlockSize())
+	if _, err := io.ReadFull(src, iv); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCopy, err)
+	}
+
+	stream, err := builder(block, iv, decrypt)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNewAesCipher, err)
+	}
+
+	return &cipher.StreamReader{S: stream, R: src}, nil
+}
+
+//////// Encoded streams ////////
+
+// EncryptStreamEncoded encrypts the plaintext from the reader with the
+// [Stream], and writes the ciphertext encoded with [DefaultStringCodec]
+// to the writer.
+//
+// Unlike a [Cipher], the ciphertext is encoded incrementally,
+// so the memory usage is bounded regardless of the size of the input.
+// [DefaultStringCodec] must implement [StreamCodec] (all the codecs
+// provided by this package do), otherwise an error is returned.
+func EncryptStreamEncoded(s Stream, plainText io.Reader, cipherText io.Writer) error {
+	codec, ok := DefaultStringCodec.(StreamCodec)
+	if !ok {
+		return fmt.Errorf("DefaultStringCodec %T is not a StreamCodec", DefaultStringCodec)
+	}
+
+	encoder := codec.NewEncoder(cipherText)
+
+	if err := s.EncryptStream(plainText, encoder); err != nil {
+		return err
+	}
+
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("%w: %w", ErrCopy, err)
+	}
+
+	return nil
+}
+
+// DecryptStreamEncoded decrypts the ciphertext encoded with
+// [DefaultStringCodec] from the reader with the [Stream],
+// and writes the plaintext to the writer.
+//
+// It is the counterpart of [EncryptStreamEncoded].
+func DecryptStreamEncoded(s Stream, cipherText io.Reader, plainText io.Writer) error {
+	codec, ok := DefaultStringCodec.(StreamCodec)
+	if !ok {
+		return fmt.Errorf("DefaultStringCodec %T is not a StreamCodec", DefaultStringCodec)
+	}
+
+	return s.DecryptStream(codec.NewDecoder(cipherText), plainText)
+}
+
+// EncryptReaderToString encrypts the plaintext from the reader with the
+// [Stream], and returns the ciphertext encoded with [DefaultStringCodec],
+// e.g., to store an encrypted file in a database string column:
+//
+//	f, _ := os.Open("report.pdf")
+//	cipherText, err := simplecipher.EncryptReaderToString(stream, f)
+//
+// The whole ciphertext is held in memory. Use [EncryptStreamEncoded] to
+// write it to an [io.Writer] instead.
+func EncryptReaderToString(s Stream, r io.Reader) (string, error) {
+	buf := new(bytes.Buffer)
+	if err := s.EncryptStream(r, buf); err != nil {
+		return "", err
+	}
+	return DefaultStringCodec.EncodeToString(buf.Bytes()), nil
+}
+
+// DecryptStringToWriter decrypts the [DefaultStringCodec] encoded
+// ciphertext with the [Stream], and writes the plaintext to the writer.
+//
+// It is the counterpart of [EncryptReaderToString].
+func DecryptStringToWriter(s Stream, cipherText string, w io.Writer) error {
+	ciphertext, err := decodeCipherText(cipherText)
+	if err != nil {
+		return err
+	}
+	return s.DecryptStream(bytes.NewReader(ciphertext), w)
+}
+
+//////// Flushing ////////
+
+// Flusher is an [io.Writer] buffering its output until Flush is called,
+// e.g., [bufio.Writer].
+type Flusher interface {
+	io.Writer
+	Flush() error
+}
+
+// EncryptStreamFlush encrypts the plaintext from the reader with the
+// [Stream] like EncryptStream, and then flushes the writer if it is a
+// [Flusher] (e.g., a [bufio.Writer]).
+//
+// EncryptStream does not flush: with a buffered writer, the tail of the
+// ciphertext stays in the buffer until the caller flushes it, and is lost
+// if they forget to. Use EncryptStreamFlush to have the whole ciphertext
+// written through when it returns nil. Nothing is flushed on error.
+func EncryptStreamFlush(s Stream, plainText io.Reader, cipherText io.Writer) error {
+	if err := s.EncryptStream(plainText, cipherText); err != nil {
+		return err
+	}
+	return flush(cipherText)
+}
+
+// DecryptStreamFlush decrypts the ciphertext from the reader with the
+// [Stream] like DecryptStream, and then flushes the writer if it is a
+// [Flusher].
+//
+// It is the counterpart of [EncryptStreamFlush].
+func DecryptStreamFlush(s Stream, cipherText io.Reader, plainText io.Writer) error {
+	if err := s.DecryptStream(cipherText, plainText); err != nil {
+		return err
+	}
+	return flush(plainText)
+}
+
+// flush flushes the writer if it is a [Flusher].
+func flush(w io.Writer) error {
+	f, ok := w.(Flusher)
+	if !ok {
+		return nil
+	}
+	if err := f.Flush(); err != nil {
+		return fmt.Errorf("%w: flush: %w", ErrCopy, err)
+	}
+	return nil
+}
+
+//////// Digests ////////
+
+// EncryptStreamWithDigest encrypts the plaintext from the reader with the
+// [Stream] like EncryptStream, and returns the SHA-256 digest of the
+// plaintext, computed on the fly, e.g., for integrity auditing.
+//
+// Store the digest to verify the output of [DecryptStreamWithDigest] later.
+// Notice that the digest of a plaintext reveals whether it equals a guess.
+func EncryptStreamWithDigest(s Stream, plainText io.Reader, cipherText io.Writer) (digest []byte, err error) {
+	h := sha256.New()
+	if err := s.EncryptStream(io.TeeReader(plainText, h), cipherText); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// DecryptStreamWithDigest decrypts the ciphertext from the reader with the
+// [Stream] like DecryptStream, and returns the SHA-256 digest of the
+// decrypted plaintext, to compare with the one of [EncryptStreamWithDigest]
+// (e.g., with [crypto/hmac.Equal]).
+//
+// No digest is returned on error.
+func DecryptStreamWithDigest(s Stream, cipherText io.Reader, plainText io.Writer) (digest []byte, err error) {
+	h := sha256.New()
+	if err := s.DecryptStream(cipherText, io.MultiWriter(plainText, h)); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
 }
@@ -1,10 +1,19 @@
 package simplecipher
 
 import (
+	"archive/tar"
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"crypto/aes"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"io"
+	mathrand "math/rand"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func testErrorStream(name string, t *testing.T, newStream func() Stream, plaintext string) {
@@ -199,3 +208,656 @@ func ExampleSimpleCTRStream() {
 
 	// Output: Hello, World!
 }
+
+func TestCTRSeeker_DecryptRange(t *testing.T) {
+	key := Bytes([]byte("key0key1key2key3key4key5key6key7"))
+	ivs := map[string]Key{
+		"common":   Bytes([]byte("iv00iv01iv02iv03")),
+		"overflow": Bytes(bytes.Repeat([]byte{0xff}, aes.BlockSize)),
+	}
+
+	plaintext := make([]byte, 1000)
+	for i := range plaintext {
+		plaintext[i] = byte(i * 7)
+	}
+
+	ranges := []struct {
+		offset, length int64
+	}{
+		{0, 0},
+		{0, 1000},
+		{5, 10},
+		{16, 16},
+		{17, 300},
+		{999, 1},
+	}
+
+	for name, iv := range ivs {
+		seeker := NewCTRSeekable(key, iv)
+
+		ciphertext := new(bytes.Buffer)
+		if err := seeker.EncryptStream(bytes.NewReader(plaintext), ciphertext); err != nil {
+			t.Fatalf("%v: EncryptStream error: %v", name, err)
+		}
+
+		for _, r := range ranges {
+			t.Run(fmt.Sprintf("%s-%d-%d", name, r.offset, r.length), func(t *testing.T) {
+				decrypted := new(bytes.Buffer)
+				err := seeker.DecryptRange(bytes.NewReader(ciphertext.Bytes()), r.offset, r.length, decrypted)
+				if err != nil {
+					t.Fatalf("DecryptRange error: %v", err)
+				}
+
+				want := plaintext[r.offset : r.offset+r.length]
+				if !bytes.Equal(decrypted.Bytes(), want) {
+					t.Errorf("DecryptRange() = %x, want %x", decrypted.Bytes(), want)
+				}
+			})
+		}
+
+		err := seeker.DecryptRange(bytes.NewReader(ciphertext.Bytes()), 990, 20, new(bytes.Buffer))
+		if !errors.Is(err, ErrCopy) {
+			t.Errorf("%v: DecryptRange(out of range) error = %v, want %v", name, err, ErrCopy)
+		}
+	}
+}
+
+// countingWriter counts the bytes written to it,
+// and records the size of the largest single write.
+type countingWriter struct {
+	w        io.Writer
+	n        int
+	maxWrite int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += len(p)
+	c.maxWrite = max(c.maxWrite, len(p))
+	return c.w.Write(p)
+}
+
+func TestStreamEncoded(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	codecs := map[string]StringCodec{
+		"HexCodec":                HexCodec,
+		"Base64StdCodec":          Base64StdCodec,
+		"Base64URLNoPaddingCodec": Base64URLNoPaddingCodec,
+		"Base32HexCodec":          Base32HexCodec,
+	}
+
+	plaintext := bytes.Repeat([]byte("0123456789abcdef"), 1<<18) // 4 MiB
+
+	for name, codec := range codecs {
+		t.Run(name, func(t *testing.T) {
+			DefaultStringCodec = codec
+			defer func() { DefaultStringCodec = HexCodec }()
+
+			stream := SimpleCTRStream("key")
+
+			ciphertext := new(bytes.Buffer)
+			counter := &countingWriter{w: ciphertext}
+
+			err := EncryptStreamEncoded(stream, bytes.NewReader(plaintext), counter)
+			if err != nil {
+				t.Fatalf("EncryptStreamEncoded error: %v", err)
+			}
+
+			wantLen := len(codec.EncodeToString(make([]byte, aes.BlockSize+len(plaintext))))
+			if counter.n != wantLen {
+				t.Errorf("encoded length = %v, want %v", counter.n, wantLen)
+			}
+			// the ciphertext is written in chunks, instead of as a whole
+			if counter.maxWrite > 1<<16 {
+				t.Errorf("largest write = %v bytes, want bounded", counter.maxWrite)
+			}
+
+			decrypted := new(bytes.Buffer)
+			err = DecryptStreamEncoded(stream, ciphertext, decrypted)
+			if err != nil {
+				t.Fatalf("DecryptStreamEncoded error: %v", err)
+			}
+			if !bytes.Equal(decrypted.Bytes(), plaintext) {
+				t.Errorf("decrypted != plaintext")
+			}
+		})
+	}
+}
+
+func TestEncryptReaderToString(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	codecs := map[string]StringCodec{
+		"HexCodec":       HexCodec,
+		"Base64StdCodec": Base64StdCodec,
+	}
+
+	plaintext := bytes.Repeat([]byte("0123456789abcdef"), 1<<16) // 1 MiB
+
+	for name, codec := range codecs {
+		t.Run(name, func(t *testing.T) {
+			DefaultStringCodec = codec
+			defer func() { DefaultStringCodec = HexCodec }()
+
+			stream := SimpleCTRStream("key")
+
+			ciphertext, err := EncryptReaderToString(stream, bytes.NewReader(plaintext))
+			if err != nil {
+				t.Fatalf("EncryptReaderToString error: %v", err)
+			}
+			if _, err := codec.DecodeString(ciphertext); err != nil {
+				t.Errorf("ciphertext is not encoded with %v: %v", name, err)
+			}
+
+			decrypted := new(bytes.Buffer)
+			if err := DecryptStringToWriter(stream, ciphertext, decrypted); err != nil {
+				t.Fatalf("DecryptStringToWriter error: %v", err)
+			}
+			if !bytes.Equal(decrypted.Bytes(), plaintext) {
+				t.Errorf("decrypted != plaintext")
+			}
+		})
+	}
+
+	err := DecryptStringToWriter(SimpleCTRStream("key"), "not encoded", new(bytes.Buffer))
+	if !errors.Is(err, ErrMalformedCiphertext) {
+		t.Errorf("DecryptStringToWriter(malformed) error = %v, want %v", err, ErrMalformedCiphertext)
+	}
+}
+
+func TestSimpleStream_options(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	simpleStreams := map[string]func(keyPassphrase string, options ...StreamOption) Stream{
+		"SimpleCFBStream": SimpleCFBStream,
+		"SimpleOFBStream": SimpleOFBStream,
+		"SimpleCTRStream": SimpleCTRStream,
+	}
+
+	iv := String("iv00iv01iv02iv03")
+	key := String("key0key1key2key3")
+
+	encrypt := func(stream Stream) string {
+		ciphertext := new(bytes.Buffer)
+		if err := stream.EncryptStream(bytes.NewReader([]byte("plaintext")), ciphertext); err != nil {
+			t.Fatalf("EncryptStream error: %v", err)
+		}
+		return ciphertext.String()
+	}
+
+	for name, newStream := range simpleStreams {
+		t.Run(name, func(t *testing.T) {
+			pinned1 := encrypt(newStream("key", WithIV(iv)))
+			pinned2 := encrypt(newStream("key", WithIV(iv)))
+			if pinned1 != pinned2 {
+				t.Errorf("pinned iv: ciphertexts differ: %x != %x", pinned1, pinned2)
+			}
+
+			random1 := encrypt(newStream("key"))
+			random2 := encrypt(newStream("key"))
+			if random1 == random2 {
+				t.Errorf("random iv: ciphertexts are identical: %x", random1)
+			}
+
+			// WithKey overrides the passphrase
+			withKey1 := encrypt(newStream("key", WithKey(key), WithIV(iv)))
+			withKey2 := encrypt(newStream("another key", WithKey(key), WithIV(iv)))
+			if withKey1 != withKey2 {
+				t.Errorf("WithKey: ciphertexts differ: %x != %x", withKey1, withKey2)
+			}
+		})
+	}
+}
+
+func TestIVStream_EncryptStreamWithIV(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	streams := map[string]Stream{
+		"SimpleCFBStream": SimpleCFBStream("key"),
+		"SimpleOFBStream": SimpleOFBStream("key"),
+		"SimpleCTRStream": SimpleCTRStream("key"),
+	}
+
+	iv := String("iv00iv01iv02iv03")
+	plaintext := strings.Repeat("plaintext", 1000)
+
+	for name, stream := range streams {
+		t.Run(name, func(t *testing.T) {
+			s := stream.(IVStream)
+
+			encrypt := func() []byte {
+				ciphertext := new(bytes.Buffer)
+				if err := s.EncryptStreamWithIV(iv, strings.NewReader(plaintext), ciphertext); err != nil {
+					t.Fatalf("EncryptStreamWithIV error: %v", err)
+				}
+				return ciphertext.Bytes()
+			}
+
+			run1, run2 := encrypt(), encrypt()
+			if !bytes.Equal(run1, run2) {
+				t.Errorf("EncryptStreamWithIV(same iv) ciphertexts differ")
+			}
+
+			decrypted := new(bytes.Buffer)
+			if err := s.DecryptStream(bytes.NewReader(run1), decrypted); err != nil {
+				t.Fatalf("DecryptStream error: %v", err)
+			}
+			if decrypted.String() != plaintext {
+				t.Errorf("DecryptStream = %q..., want %q...", decrypted.String()[:9], plaintext[:9])
+			}
+
+			err := s.EncryptStreamWithIV(String("short"), strings.NewReader(plaintext), new(bytes.Buffer))
+			if !errors.Is(err, ErrIvSize) {
+				t.Errorf("EncryptStreamWithIV(short iv) error = %v, want %v", err, ErrIvSize)
+			}
+		})
+	}
+}
+
+func TestStream_blockSize(t *testing.T) {
+	key := String("mockkey0")
+	iv := String("iv00iv01")
+
+	builders := map[string]cipherStreamBuilder{
+		"CFB": cfbStreamBuilder,
+		"OFB": ofbStreamBuilder,
+		"CTR": ctrStreamBuilder,
+	}
+
+	plaintext := strings.Repeat("plaintext", 100)
+
+	for name, builder := range builders {
+		createStream := func() Stream {
+			return &steam{key: key, iv: iv, cipherStream: builder, newBlock: newMockBlock}
+		}
+
+		testStream(name, t, createStream, plaintext)
+
+		ciphertext := new(bytes.Buffer)
+		if err := createStream().EncryptStream(strings.NewReader(plaintext), ciphertext); err != nil {
+			t.Fatalf("%s: EncryptStream error: %v", name, err)
+		}
+		if ciphertext.Len() != mockBlockSize+len(plaintext) {
+			t.Errorf("%s: len(ciphertext) = %v, want %v", name, ciphertext.Len(), mockBlockSize+len(plaintext))
+		}
+	}
+
+	seeker := &ctrSeeker{steam: steam{key: key, iv: iv, cipherStream: ctrStreamBuilder, newBlock: newMockBlock}}
+
+	ciphertext := new(bytes.Buffer)
+	if err := seeker.EncryptStream(strings.NewReader(plaintext), ciphertext); err != nil {
+		t.Fatalf("EncryptStream error: %v", err)
+	}
+
+	decrypted := new(bytes.Buffer)
+	if err := seeker.DecryptRange(bytes.NewReader(ciphertext.Bytes()), 13, 100, decrypted); err != nil {
+		t.Fatalf("DecryptRange error: %v", err)
+	}
+	if decrypted.String() != plaintext[13:113] {
+		t.Errorf("DecryptRange = %q, want %q", decrypted.String(), plaintext[13:113])
+	}
+}
+
+func TestNewCTRReadSeeker(t *testing.T) {
+	key := Bytes([]byte("key0key1key2key3key4key5key6key7"))
+	iv := Bytes(bytes.Repeat([]byte{0xff}, aes.BlockSize))
+
+	plaintext := make([]byte, 1000)
+	for i := range plaintext {
+		plaintext[i] = byte(i * 7)
+	}
+
+	ciphertext := new(bytes.Buffer)
+	if err := NewCTRStream(key, iv).EncryptStream(bytes.NewReader(plaintext), ciphertext); err != nil {
+		t.Fatalf("EncryptStream error: %v", err)
+	}
+
+	// the full sequential decrypt
+	decrypted := new(bytes.Buffer)
+	if err := NewCTRStream(key, iv).DecryptStream(bytes.NewReader(ciphertext.Bytes()), decrypted); err != nil {
+		t.Fatalf("DecryptStream error: %v", err)
+	}
+	want := decrypted.Bytes()
+
+	sources := map[string]func() (io.ReadSeeker, error){
+		"prefixed": func() (io.ReadSeeker, error) {
+			return NewCTRReadSeeker(key, nil, bytes.NewReader(ciphertext.Bytes()))
+		},
+		"bare": func() (io.ReadSeeker, error) {
+			return NewCTRReadSeeker(key, iv, bytes.NewReader(ciphertext.Bytes()[aes.BlockSize:]))
+		},
+	}
+
+	seeks := []struct {
+		offset int64
+		whence int
+		pos    int64
+	}{
+		{0, io.SeekStart, 0},
+		{17, io.SeekStart, 17},
+		{16, io.SeekCurrent, 33 + 10},
+		{-1, io.SeekEnd, 999},
+		{500, io.SeekStart, 500},
+		{-100, io.SeekCurrent, 410},
+	}
+
+	for name, newReader := range sources {
+		t.Run(name, func(t *testing.T) {
+			r, err := newReader()
+			if err != nil {
+				t.Fatalf("NewCTRReadSeeker error: %v", err)
+			}
+
+			for _, s := range seeks {
+				pos, err := r.Seek(s.offset, s.whence)
+				if err != nil {
+					t.Fatalf("Seek(%d, %d) error: %v", s.offset, s.whence, err)
+				}
+				if pos != s.pos {
+					t.Fatalf("Seek(%d, %d) = %d, want %d", s.offset, s.whence, pos, s.pos)
+				}
+
+				got := make([]byte, 10)
+				n, err := io.ReadFull(r, got)
+				if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+					t.Fatalf("Read at %d error: %v", pos, err)
+				}
+				if !bytes.Equal(got[:n], want[pos:min(pos+10, int64(len(want)))]) {
+					t.Errorf("Read at %d = %x, want %x", pos, got[:n], want[pos:pos+int64(n)])
+				}
+			}
+
+			if _, err := r.Seek(0, io.SeekStart); err != nil {
+				t.Fatalf("Seek error: %v", err)
+			}
+			all, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll error: %v", err)
+			}
+			if !bytes.Equal(all, want) {
+				t.Errorf("ReadAll = %x, want %x", all, want)
+			}
+
+			if _, err := r.Seek(-1, io.SeekStart); err == nil {
+				t.Errorf("Seek(-1) error = nil, want an error")
+			}
+		})
+	}
+
+	if _, err := NewCTRReadSeeker(key, nil, bytes.NewReader([]byte("short"))); !errors.Is(err, ErrCopy) {
+		t.Errorf("NewCTRReadSeeker(short) error = %v, want %v", err, ErrCopy)
+	}
+	if _, err := NewCTRReadSeeker(key, Bytes([]byte("short iv")), bytes.NewReader(nil)); !errors.Is(err, ErrIvSize) {
+		t.Errorf("NewCTRReadSeeker(short iv) error = %v, want %v", err, ErrIvSize)
+	}
+}
+
+func TestNewDecryptReader(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	key := NewAesKey("key")
+
+	// a tar.gz archive as the plaintext
+	files := map[string]string{
+		"a.txt": strings.Repeat("hello, ", 1000),
+		"b.txt": "world",
+	}
+
+	archive := new(bytes.Buffer)
+	gz := gzip.NewWriter(archive)
+	tw := tar.NewWriter(gz)
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(files[name]))}); err != nil {
+			t.Fatalf("WriteHeader error: %v", err)
+		}
+		if _, err := tw.Write([]byte(files[name])); err != nil {
+			t.Fatalf("Write error: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("tar Close error: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("gzip Close error: %v", err)
+	}
+
+	streams := map[ModeID]Stream{
+		ModeCFB: NewCFBStream(key, NewRandomIv()),
+		ModeOFB: NewOFBStream(key, NewRandomIv()),
+		ModeCTR: NewCTRStream(key, NewRandomIv()),
+	}
+
+	for mode, stream := range streams {
+		t.Run(string(mode), func(t *testing.T) {
+			ciphertext := new(bytes.Buffer)
+			if err := stream.EncryptStream(bytes.NewReader(archive.Bytes()), ciphertext); err != nil {
+				t.Fatalf("EncryptStream error: %v", err)
+			}
+
+			// read in small chunks
+			r, err := NewDecryptReader(key, mode, iotest.OneByteReader(ciphertext))
+			if err != nil {
+				t.Fatalf("NewDecryptReader error: %v", err)
+			}
+
+			gr, err := gzip.NewReader(r)
+			if err != nil {
+				t.Fatalf("gzip.NewReader error: %v", err)
+			}
+			tr := tar.NewReader(gr)
+
+			got := map[string]string{}
+			for {
+				header, err := tr.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("tar Next error: %v", err)
+				}
+				content, err := io.ReadAll(tr)
+				if err != nil {
+					t.Fatalf("tar ReadAll error: %v", err)
+				}
+				got[header.Name] = string(content)
+			}
+
+			if len(got) != len(files) || got["a.txt"] != files["a.txt"] || got["b.txt"] != files["b.txt"] {
+				t.Errorf("decrypted archive = %v files, want %v", len(got), len(files))
+			}
+		})
+	}
+
+	if _, err := NewDecryptReader(key, ModeGCM, bytes.NewReader(nil)); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("NewDecryptReader(GCM) error = %v, want %v", err, ErrInvalidConfig)
+	}
+	if _, err := NewDecryptReader(key, ModeCTR, bytes.NewReader([]byte("short"))); !errors.Is(err, ErrCopy) {
+		t.Errorf("NewDecryptReader(short) error = %v, want %v", err, ErrCopy)
+	}
+}
+
+func TestStreamWithDigest(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	plaintext := strings.Repeat("plaintext", 1000)
+	want := sha256.Sum256([]byte(plaintext))
+
+	streams := map[string]Stream{
+		"ctr":    SimpleCTRStream("key"),
+		"record": NewRecordStream(NewAesKey("key"), 100),
+	}
+	for name, s := range streams {
+		t.Run(name, func(t *testing.T) {
+			var ciphertext bytes.Buffer
+			encDigest, err := EncryptStreamWithDigest(s, strings.NewReader(plaintext), &ciphertext)
+			if err != nil {
+				t.Fatalf("EncryptStreamWithDigest() error = %v", err)
+			}
+			if !bytes.Equal(encDigest, want[:]) {
+				t.Errorf("EncryptStreamWithDigest() = %x, want %x", encDigest, want)
+			}
+
+			var decrypted bytes.Buffer
+			decDigest, err := DecryptStreamWithDigest(s, bytes.NewReader(ciphertext.Bytes()), &decrypted)
+			if err != nil {
+				t.Fatalf("DecryptStreamWithDigest() error = %v", err)
+			}
+			if !bytes.Equal(decDigest, encDigest) || decrypted.String() != plaintext {
+				t.Errorf("DecryptStreamWithDigest() = %x, want %x", decDigest, encDigest)
+			}
+		})
+	}
+
+	// a different plaintext, e.g. decrypted with the wrong key, has another digest
+	var ciphertext bytes.Buffer
+	encDigest, _ := EncryptStreamWithDigest(SimpleCTRStream("key"), strings.NewReader(plaintext), &ciphertext)
+	decDigest, err := DecryptStreamWithDigest(SimpleCTRStream("another key"), &ciphertext, io.Discard)
+	if err != nil || bytes.Equal(decDigest, encDigest) {
+		t.Errorf("DecryptStreamWithDigest(wrong key) = %x, %v, want a different digest", decDigest, err)
+	}
+
+	// no digest on error
+	if digest, err := DecryptStreamWithDigest(NewRecordStream(NewAesKey("key"), 100), strings.NewReader("short"), io.Discard); err == nil || digest != nil {
+		t.Errorf("DecryptStreamWithDigest(invalid) = %x, %v, want nil digest and an error", digest, err)
+	}
+}
+
+func TestStreamFlush(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	s := SimpleCTRStream("key")
+	plaintext := "short plaintext"
+
+	// the default buffer of bufio.Writer is larger than the ciphertext,
+	// so nothing reaches the underlying buffer without a flush
+	var ciphertext bytes.Buffer
+	if err := EncryptStreamFlush(s, strings.NewReader(plaintext), bufio.NewWriter(&ciphertext)); err != nil {
+		t.Fatalf("EncryptStreamFlush() error = %v", err)
+	}
+	if want := aes.BlockSize + len(plaintext); ciphertext.Len() != want {
+		t.Fatalf("EncryptStreamFlush() wrote %d bytes, want %d", ciphertext.Len(), want)
+	}
+
+	var decrypted bytes.Buffer
+	if err := DecryptStreamFlush(s, &ciphertext, bufio.NewWriter(&decrypted)); err != nil {
+		t.Fatalf("DecryptStreamFlush() error = %v", err)
+	}
+	if decrypted.String() != plaintext {
+		t.Errorf("DecryptStreamFlush() = %q, want %q", decrypted.String(), plaintext)
+	}
+
+	// a flush error is reported
+	pr, pw := io.Pipe()
+	pr.Close()
+	w := bufio.NewWriter(pw)
+	if err := EncryptStreamFlush(s, strings.NewReader(plaintext), w); !errors.Is(err, ErrCopy) {
+		t.Errorf("EncryptStreamFlush(short writer) error = %v, want %v", err, ErrCopy)
+	}
+}
+
+func TestWithRand(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	key := NewAesKey("key")
+	encrypt := func(s Stream) string {
+		t.Helper()
+		var ciphertext bytes.Buffer
+		if err := s.EncryptStream(strings.NewReader("plaintext"), &ciphertext); err != nil {
+			t.Fatalf("EncryptStream error: %v", err)
+		}
+		return ciphertext.String()
+	}
+
+	// a deterministic reader per stream: reproducible, fresh ivs
+	seeded := func() io.Reader { return mathrand.New(mathrand.NewSource(1)) }
+	pinned1 := SimpleCTRStream("key", WithKey(key), WithRand(seeded()))
+	pinned2 := SimpleCTRStream("key", WithKey(key), WithRand(seeded()))
+
+	first := encrypt(pinned1)
+	if again := encrypt(pinned2); again != first {
+		t.Errorf("EncryptStream(same rand) = %x, want %x", again, first)
+	}
+	if next := encrypt(pinned1); next == first {
+		t.Errorf("EncryptStream(next) = %x, want a new iv", next)
+	}
+
+	wantIV := make([]byte, aes.BlockSize)
+	seeded().Read(wantIV)
+	if iv := first[:aes.BlockSize]; iv != string(wantIV) {
+		t.Errorf("iv = %x, want %x from the rand reader", iv, wantIV)
+	}
+
+	// the other streams are not affected
+	other := SimpleCTRStream("key", WithKey(key))
+	if iv := encrypt(other)[:aes.BlockSize]; iv == string(wantIV) {
+		t.Errorf("iv of another stream = %x, want not from the rand reader", iv)
+	}
+
+	var decrypted bytes.Buffer
+	if err := other.DecryptStream(strings.NewReader(first), &decrypted); err != nil || decrypted.String() != "plaintext" {
+		t.Errorf("DecryptStream() = %q, %v, want %q", decrypted.String(), err, "plaintext")
+	}
+
+	// the GCM counterpart
+	gcm := func() Cipher {
+		return NewGCMWithConfig(GCMConfig{Key: key, RandomNonce: true, Rand: seeded()})
+	}
+	ciphertext1, _ := gcm().Encrypt("plaintext")
+	ciphertext2, _ := gcm().Encrypt("plaintext")
+	if ciphertext1 != ciphertext2 {
+		t.Errorf("GCM Encrypt(same rand) = %s and %s, want identical", ciphertext1, ciphertext2)
+	}
+	if _, err := NewGCMWithConfig(GCMConfig{Key: key, Nonce: NewNonce("nonce"), Rand: seeded()}).Encrypt("plaintext"); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("GCM Encrypt(Rand without RandomNonce) error = %v, want %v", err, ErrInvalidConfig)
+	}
+
+	nilRand := SimpleCTRStream("key", WithRand(nil))
+	if err := nilRand.EncryptStream(strings.NewReader("plaintext"), io.Discard); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("EncryptStream(WithRand(nil)) error = %v, want %v", err, ErrInvalidConfig)
+	}
+	if err := ValidateStream(nilRand); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("ValidateStream(WithRand(nil)) error = %v, want %v", err, ErrInvalidConfig)
+	}
+}
+
+func TestStreamFromBlock(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	plaintext := strings.Repeat("plaintext", 100)
+
+	for name, c := range map[string]Cipher{
+		"gcm": SimpleGCM("key", "nonce"),
+		"cbc": SimpleCBC("key"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := StreamFromBlock(c)
+
+			var ciphertext bytes.Buffer
+			if err := s.EncryptStream(strings.NewReader(plaintext), &ciphertext); err != nil {
+				t.Fatalf("EncryptStream() error = %v", err)
+			}
+
+			// the raw bytes of the Cipher output
+			decrypted, err := c.Decrypt(DefaultStringCodec.EncodeToString(ciphertext.Bytes()))
+			if err != nil || decrypted != plaintext {
+				t.Errorf("Decrypt(EncryptStream()) = %q, %v, want the plaintext", decrypted, err)
+			}
+
+			var out bytes.Buffer
+			if err := s.DecryptStream(&ciphertext, &out); err != nil {
+				t.Fatalf("DecryptStream() error = %v", err)
+			}
+			if out.String() != plaintext {
+				t.Errorf("DecryptStream() = %q, want %q", out.String(), plaintext)
+			}
+		})
+	}
+
+	s := StreamFromBlock(SimpleGCM("key", "nonce"))
+	if err := s.DecryptStream(strings.NewReader("tampered ciphertext"), io.Discard); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("DecryptStream(tampered) error = %v, want %v", err, ErrAuthenticationFailed)
+	}
+	if info := DescribeStream(s); info.String() != "AES-256-GCM" {
+		t.Errorf("DescribeStream() = %v, want AES-256-GCM", info)
+	}
+}
@@ -0,0 +1,145 @@
+package simplecipher
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// This file provides helpers to encrypt and decrypt tagged struct fields
+// in place, e.g., before storing a record into a database.
+
+// structTagKey is the struct tag key recognized by [EncryptStruct].
+const structTagKey = "simplecipher"
+
+// struct tag options
+const (
+	structTagEncrypt   = "encrypt"
+	structTagOmitEmpty = "omitempty"
+)
+
+// EncryptStruct encrypts the tagged string fields of the struct pointed
+// to by v in place with the cipher.
+//
+// Fields are selected by the struct tag `simplecipher:"encrypt"`.
+// Tagged fields must be of type string or *string (a nil *string is
+// skipped). Add the omitempty option to leave empty strings as is,
+// e.g., for optional fields:
+//
+//	type User struct {
+//		Name  string
+//		Email string  `simplecipher:"encrypt"`
+//		Phone *string `simplecipher:"encrypt,omitempty"`
+//	}
+//
+//	err := simplecipher.EncryptStruct(cipher, &user)
+//
+// v must be a non-nil pointer to a struct. Nested structs are not
+// traversed. An error wrapping [ErrUnsupportedType] is returned for a
+// tagged field of another type. If any field fails to encrypt, the error
+// names it and the struct is left unmodified: the fields are only set once
+// all of them are encrypted.
+func EncryptStruct(c Cipher, v any) error {
+	return transformStruct(v, c.Encrypt)
+}
+
+// DecryptStruct decrypts the tagged string fields of the struct pointed
+// to by v in place with the cipher. It reverses [EncryptStruct].
+//
+// Like EncryptStruct, the struct is left unmodified on error, so that a
+// retry does not decrypt some fields twice.
+func DecryptStruct(c Cipher, v any) error {
+	return transformStruct(v, c.Decrypt)
+}
+
+// transformStruct applies the transform to the tagged fields of the
+// struct pointed to by v. All the outputs are computed before any field is
+// set, so that the struct is not partially transformed on error.
+func transformStruct(v any, transform func(string) (string, error)) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: %T, want a non-nil pointer to a struct", ErrUnsupportedType, v)
+	}
+
+	fields, err := taggedFields(rv.Elem())
+	if err != nil {
+		return err
+	}
+
+	type update struct {
+		value reflect.Value
+		out   string
+	}
+	updates := make([]update, 0, len(fields))
+
+	for _, f := range fields {
+		if f.value.Kind() == reflect.Pointer {
+			if f.value.IsNil() {
+				continue
+			}
+			f.value = f.value.Elem()
+		}
+
+		if f.omitEmpty && f.value.String() == "" {
+			continue
+		}
+
+		out, err := transform(f.value.String())
+		if err != nil {
+			return fmt.Errorf("field %s: %w", f.name, err)
+		}
+		updates = append(updates, update{f.value, out})
+	}
+
+	for _, u := range updates {
+		u.value.SetString(u.out)
+	}
+	return nil
+}
+
+// taggedField is a struct field tagged to be encrypted.
+type taggedField struct {
+	name      string
+	value     reflect.Value
+	omitEmpty bool
+}
+
+// taggedFields collects the tagged fields of the struct value,
+// and checks their types.
+func taggedFields(sv reflect.Value) ([]taggedField, error) {
+	var fields []taggedField
+
+	st := sv.Type()
+	for i := 0; i < st.NumField(); i++ {
+		sf := st.Field(i)
+
+		tag, ok := sf.Tag.Lookup(structTagKey)
+		if !ok {
+			continue
+		}
+
+		opts := strings.Split(tag, ",")
+		if opts[0] != structTagEncrypt {
+			continue
+		}
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if ft.Kind() != reflect.String || !sf.IsExported() {
+			return nil, fmt.Errorf("%w: field %s of type %s", ErrUnsupportedType, sf.Name, sf.Type)
+		}
+
+		field := taggedField{name: sf.Name, value: sv.Field(i)}
+		for _, opt := range opts[1:] {
+			if opt == structTagOmitEmpty {
+				field.omitEmpty = true
+			}
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
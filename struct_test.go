@@ -0,0 +1,114 @@
+package simplecipher
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testUser struct {
+	ID       int
+	Name     string
+	Email    string  `simplecipher:"encrypt"`
+	Phone    *string `simplecipher:"encrypt"`
+	Nickname string  `simplecipher:"encrypt,omitempty"`
+	Note     string  `json:"note"`
+}
+
+func TestEncryptStruct(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	cipher := NewGCMWithConfig(GCMConfig{Key: NewAesKey("key"), RandomNonce: true})
+
+	phone := "+1 555 0100"
+	user := testUser{ID: 1, Name: "Alice", Email: "alice@example.com", Phone: &phone, Note: "note"}
+	want := user
+	wantPhone := phone
+
+	if err := EncryptStruct(cipher, &user); err != nil {
+		t.Fatalf("EncryptStruct error: %v", err)
+	}
+
+	if user.ID != want.ID || user.Name != want.Name || user.Note != want.Note {
+		t.Errorf("EncryptStruct modified untagged fields: %+v", user)
+	}
+	if user.Email == want.Email || *user.Phone == wantPhone {
+		t.Errorf("EncryptStruct did not encrypt tagged fields: %+v", user)
+	}
+	if user.Nickname != "" {
+		t.Errorf("EncryptStruct encrypted an empty omitempty field: %q", user.Nickname)
+	}
+
+	if err := DecryptStruct(cipher, &user); err != nil {
+		t.Fatalf("DecryptStruct error: %v", err)
+	}
+	if user.Email != want.Email || *user.Phone != wantPhone || user.Nickname != "" {
+		t.Errorf("DecryptStruct = %+v, want %+v", user, want)
+	}
+
+	// nil pointer fields are skipped
+	noPhone := testUser{Email: "bob@example.com"}
+	if err := EncryptStruct(cipher, &noPhone); err != nil {
+		t.Errorf("EncryptStruct(nil *string) error: %v", err)
+	}
+}
+
+func TestEncryptStruct_unsupported(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	cipher := NewGCMWithConfig(GCMConfig{Key: NewAesKey("key"), RandomNonce: true})
+
+	type badField struct {
+		Name   string `simplecipher:"encrypt"`
+		Secret int    `simplecipher:"encrypt"`
+	}
+
+	bad := badField{Name: "name", Secret: 42}
+
+	tests := map[string]any{
+		"nonPointer": testUser{},
+		"nilPointer": (*testUser)(nil),
+		"nonStruct":  new(string),
+		"badField":   &bad,
+	}
+	for name, v := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := EncryptStruct(cipher, v)
+			if !errors.Is(err, ErrUnsupportedType) {
+				t.Errorf("EncryptStruct(%T) error = %v, want %v", v, err, ErrUnsupportedType)
+			}
+		})
+	}
+
+	if bad.Name != "name" {
+		t.Errorf("EncryptStruct modified a field before failing: %q", bad.Name)
+	}
+}
+
+func TestDecryptStruct_unmodifiedOnError(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	cipher := NewGCMWithConfig(GCMConfig{Key: NewAesKey("key"), RandomNonce: true})
+
+	phone := "555-0100"
+	user := testUser{Email: "alice@example.com", Phone: &phone}
+	if err := EncryptStruct(cipher, &user); err != nil {
+		t.Fatalf("EncryptStruct error: %v", err)
+	}
+
+	// the Email decrypts, but the Phone after it does not
+	last := "0"
+	if strings.HasSuffix(*user.Phone, last) {
+		last = "1"
+	}
+	*user.Phone = (*user.Phone)[:len(*user.Phone)-1] + last
+	encrypted := user
+	encryptedPhone := *user.Phone
+
+	if err := DecryptStruct(cipher, &user); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Fatalf("DecryptStruct(tampered) error = %v, want %v", err, ErrAuthenticationFailed)
+	}
+	if user.Email != encrypted.Email || *user.Phone != encryptedPhone {
+		t.Errorf("DecryptStruct(tampered) modified the struct: %+v", user)
+	}
+}
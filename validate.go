@@ -0,0 +1,108 @@
+package simplecipher
+
+import (
+	"crypto/aes"
+	"fmt"
+)
+
+// This file provides helpers to validate the configuration of ciphers.
+
+// ValidateCipher validates the configuration of the given [Cipher]
+// if it implements [Validator]. Otherwise, nil is returned.
+func ValidateCipher(c Cipher) error {
+	if v, ok := c.(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// ValidateStream validates the configuration of the given [Stream]
+// if it implements [Validator]. Otherwise, nil is returned.
+func ValidateStream(s Stream) error {
+	if v, ok := s.(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// interopProbe is the plaintext encrypted by [AreInteroperable].
+const interopProbe = "simplecipher interoperability probe"
+
+// AreInteroperable reports whether the ciphers can decrypt each other's
+// ciphertexts: a probe plaintext encrypted with a must decrypt to itself
+// with b, and vice versa.
+//
+// Use it to validate a migration or a configuration change, e.g., that
+// ciphers built from the new config can still decrypt the old data:
+//
+//	if !simplecipher.AreInteroperable(oldCipher, newCipher) {
+//		log.Fatal("the new config can not decrypt the existing data")
+//	}
+//
+// It is only meaningful for ciphers with fixed keys. And it is a check,
+// not a proof: some parameters, e.g., the codec, may only matter for
+// other plaintexts.
+func AreInteroperable(a, b Cipher) bool {
+	return decryptsFrom(a, b) && decryptsFrom(b, a)
+}
+
+// decryptsFrom reports whether dec decrypts the probe encrypted by enc.
+func decryptsFrom(enc, dec Cipher) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+
+	cipherText, err := enc.Encrypt(interopProbe)
+	if err != nil {
+		return false
+	}
+	plainText, err := dec.Decrypt(cipherText)
+	return err == nil && plainText == interopProbe
+}
+
+// validateAesKey checks that the key is 16, 24, or 32 bytes long.
+func validateAesKey(key Key) (err error) {
+	defer recoverFromPanic(&err)
+
+	n := KeyLen(len(keyOrEmpty(key).Bytes()))
+	if n == 0 {
+		return fmt.Errorf("%w: %w", ErrKeySize, ErrEmptyKey)
+	}
+	if n != Aes128 && n != Aes192 && n != Aes256 {
+		return fmt.Errorf("%w: got %d bytes, want 16, 24, or 32", ErrKeySize, n)
+	}
+	return nil
+}
+
+// validateIv checks that the iv is [aes.BlockSize] bytes long.
+func validateIv(iv Key) (err error) {
+	defer recoverFromPanic(&err)
+
+	if n := len(iv.Bytes()); n != aes.BlockSize {
+		return fmt.Errorf("%w: got %d bytes, want %d", ErrIvSize, n, aes.BlockSize)
+	}
+	return nil
+}
+
+// validateBlockKeyIv checks that the key is accepted by the block cipher,
+// and that the iv is as long as its block size.
+func validateBlockKeyIv(newBlock blockBuilder, key, iv Key) (err error) {
+	defer recoverFromPanic(&err)
+
+	b := keyOrEmpty(key).Bytes()
+	if len(b) == 0 {
+		return fmt.Errorf("%w: %w", ErrKeySize, ErrEmptyKey)
+	}
+
+	block, err := newBlock(b)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrKeySize, err)
+	}
+
+	if n := len(iv.Bytes()); n != block.BlockSize() {
+		return fmt.Errorf("%w: got %d bytes, want %d", ErrIvSize, n, block.BlockSize())
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package simplecipher
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateCipher(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	key := String("key0key1key2key3")
+	iv := String("iv00iv01iv02iv03")
+	nonce := String("nonce0nonce1")
+
+	tests := []struct {
+		name    string
+		cipher  Cipher
+		wantErr error
+	}{
+		{"cbc", NewCBC(key, iv), nil},
+		{"cbc_badKey", NewCBC(String("badkey"), iv), ErrKeySize},
+		{"cbc_badIv", NewCBC(key, String("badiv")), ErrIvSize},
+		{"simpleCBC", SimpleCBC("key"), nil},
+		{"ctr", NewCTR(key, iv), nil},
+		{"cfb_badKey", NewCFB(String("badkey"), iv), ErrKeySize},
+		{"ofb_badIv", NewOFB(key, String("badiv")), ErrIvSize},
+		{"simpleCTR", SimpleCTR("key"), nil},
+		{"gcm", NewGCM(key, nonce), nil},
+		{"simpleGCM", SimpleGCM("key", "nonce"), nil},
+		{"gcm_badKey", NewGCM(String("badkey"), nonce), ErrKeySize},
+		{"gcm_badNonce", NewGCM(key, String("badnonce")), ErrNonceSize},
+		{"gcm_nonceSize", NewGCMWithNonceSize(key, String("nonce-08"), 8), nil},
+		{"gcm_badNonceSize", NewGCMWithNonceSize(key, nonce, 8), ErrNonceSize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCipher(tt.cipher)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("ValidateCipher() = %v, want nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateCipher() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAreInteroperable(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	tests := []struct {
+		name string
+		a, b Cipher
+		want bool
+	}{
+		{"sameConfig", SimpleGCM("key", "nonce"), SimpleGCM("key", "nonce"), true},
+		{"sameSalt", NewCTR(NewAesKey("key", WithSalt("salt1")), NewIv("iv")), NewCTR(NewAesKey("key", WithSalt("salt1")), NewIv("iv")), true},
+		{"differentSalt", NewCTR(NewAesKey("key", WithSalt("salt1")), NewIv("iv")), NewCTR(NewAesKey("key", WithSalt("salt2")), NewIv("iv")), false},
+		{"differentMode", SimpleGCM("key", "nonce"), SimpleCTR("key"), false},
+		{"invalidKey", NewGCM(String("badkey"), NewNonce("nonce")), SimpleGCM("key", "nonce"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AreInteroperable(tt.a, tt.b); got != tt.want {
+				t.Errorf("AreInteroperable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmptyKey(t *testing.T) {
+	DefaultSalt = func() string { return "testsalt" }
+
+	iv := String("iv00iv01iv02iv03")
+	nonce := String("nonce0nonce1")
+
+	constructors := map[string]func(key Key) Cipher{
+		"NewCBC":                     func(key Key) Cipher { return NewCBC(key, iv) },
+		"NewCTR":                     func(key Key) Cipher { return NewCTR(key, iv) },
+		"NewGCM":                     func(key Key) Cipher { return NewGCM(key, nonce) },
+		"NewGCMVersioned":            NewGCMVersioned,
+		"NewGCMSynthNonce":           NewGCMSynthNonce,
+		"NewReplayGuard":             NewReplayGuard,
+		"NewKeyring":                 func(key Key) Cipher { return NewKeyring(key) },
+		"NewFF1":                     func(key Key) Cipher { return NewFF1(key, 10, nil) },
+		"NewBlowfishCBC":             func(key Key) Cipher { return NewBlowfishCBC(key, String("iv00iv01")) },
+		"NewAuthenticatedCTRStream":  func(key Key) Cipher { return BlockFromStream(NewAuthenticatedCTRStream(key)) },
+		"NewRecordStream":            func(key Key) Cipher { return BlockFromStream(NewRecordStream(key, 0)) },
+		"SimpleCTRStream(WithKey())": func(key Key) Cipher { return BlockFromStream(SimpleCTRStream("", WithKey(key))) },
+	}
+
+	keys := map[string]Key{
+		"nil":        nil,
+		"Bytes(nil)": Bytes(nil),
+		"String()":   String(""),
+	}
+
+	for name, newCipher := range constructors {
+		for keyName, key := range keys {
+			t.Run(name+"/"+keyName, func(t *testing.T) {
+				c := newCipher(key)
+
+				if _, err := c.Encrypt("1234567890123456"); !errors.Is(err, ErrEmptyKey) {
+					t.Errorf("Encrypt() error = %v, want %v", err, ErrEmptyKey)
+				}
+				if err := ValidateCipher(c); err != nil && !errors.Is(err, ErrEmptyKey) {
+					t.Errorf("ValidateCipher() error = %v, want %v", err, ErrEmptyKey)
+				}
+			})
+		}
+	}
+
+	if _, err := NewGCM(Bytes(nil), nonce).Decrypt("00112233445566778899aabbccddeeff"); !errors.Is(err, ErrEmptyKey) {
+		t.Errorf("Decrypt() error = %v, want %v", err, ErrEmptyKey)
+	}
+
+	// a passphrase-derived key is never empty, even for an empty passphrase
+	if err := ValidateCipher(NewGCM(NewAesKey(""), nonce)); err != nil {
+		t.Errorf("ValidateCipher(NewAesKey(\"\")) error = %v, want nil", err)
+	}
+	if _, err := SmartEncrypt(nil, "plaintext"); !errors.Is(err, ErrEmptyKey) {
+		t.Errorf("SmartEncrypt(nil) error = %v, want %v", err, ErrEmptyKey)
+	}
+}